@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/ampol-me/phi-DCN/internal/camera"
+)
+
+// คำสั่ง camera: เรียก preset ของกล้องหนึ่งครั้งเพื่อทดสอบที่อยู่และการตั้งค่าก่อนใช้กับ -director ของ proxy
+func runCamera(ctx context.Context, args []string) int {
+	fs := newFlagSet("camera", "[options] preset",
+		"ส่งคำสั่งเรียก preset (นับจาก 1) ไปยังกล้องหนึ่งตัวและรอจนกล้องตอบรับ\n"+
+			"ชนิดกล้อง: visca (VISCA-over-IP พอร์ต 52381) หรือ panasonic (AW series ผ่าน HTTP)")
+	cameraType := fs.String("type", camera.TYPE_VISCA, "ชนิดกล้อง: visca หรือ panasonic")
+	addr := fs.String("addr", "", "ที่อยู่ของกล้อง (visca: host[:port], panasonic: host[:port] หรือ URL)")
+	username := fs.String("username", "", "username ของกล้อง (panasonic)")
+	password := fs.String("password", "", "password ของกล้อง (panasonic)")
+	timeout := fs.Duration("timeout", camera.DEFAULT_TIMEOUT, "เวลารอจนกล้องทำคำสั่งเสร็จ")
+	logs := addLogFlags(fs, "camera")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() != 1 {
+		return usageError(fs, "ต้องระบุหมายเลข preset หนึ่งหมายเลข")
+	}
+	preset, err := strconv.Atoi(fs.Arg(0))
+	if err != nil || preset < 1 {
+		return usageError(fs, "preset %q ต้องเป็นตัวเลขตั้งแต่ 1", fs.Arg(0))
+	}
+	if *addr == "" {
+		return usageError(fs, "ต้องระบุ -addr")
+	}
+	if !logs.setup() {
+		return EXIT_USAGE
+	}
+
+	driver, err := camera.Open(camera.Config{
+		Type:     *cameraType,
+		Addr:     *addr,
+		Username: *username,
+		Password: *password,
+		Timeout:  *timeout,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return EXIT_FAILURE
+	}
+	defer driver.Close()
+
+	start := time.Now()
+	if err := driver.RecallPreset(ctx, preset); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return EXIT_FAILURE
+	}
+	fmt.Printf("preset %d OK (%s)\n", preset, time.Since(start).Round(time.Millisecond))
+	return EXIT_OK
+}
+
+// คำสั่ง mockcam: กล้องจำลองสำหรับทดสอบ director และคำสั่ง camera โดยไม่ต้องมีกล้องจริง
+func runMockCamera(ctx context.Context, args []string) int {
+	fs := newFlagSet("mockcam", "[options]",
+		"จำลองกล้อง PTZ ที่รับคำสั่งเรียก preset ผ่าน VISCA-over-IP และ/หรือ Panasonic aw_ptz\n"+
+			"และแสดงใน log ทุกครั้งที่ได้รับคำสั่ง จนกว่าจะหยุดด้วย Ctrl+C")
+	visca := fs.String("visca", fmt.Sprintf(":%d", camera.VISCA_PORT), "ที่อยู่ UDP ของ VISCA-over-IP (ว่าง = ไม่เปิด)")
+	httpAddr := fs.String("http", "", "ที่อยู่ HTTP ของ Panasonic aw_ptz เช่น :8080 (ว่าง = ไม่เปิด)")
+	moveTime := fs.Duration("move", 500*time.Millisecond, "เวลาที่ใช้เคลื่อนกล้องก่อนส่ง VISCA completion")
+	drop := fs.Float64("drop", 0, "สัดส่วนของ packet VISCA ที่ทิ้งโดยไม่ตอบ (0-1) สำหรับทดสอบการส่งซ้ำ")
+	username := fs.String("username", "", "บังคับใช้ basic authentication กับ HTTP")
+	password := fs.String("password", "", "password ของ basic authentication")
+	logs := addLogFlags(fs, "camera")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 0 {
+		return usageError(fs, "ไม่รับ argument %q", fs.Arg(0))
+	}
+	if *drop < 0 || *drop >= 1 {
+		return usageError(fs, "-drop ต้องอยู่ระหว่าง 0 ถึงน้อยกว่า 1")
+	}
+	if !logs.setup() {
+		return EXIT_USAGE
+	}
+
+	mock := camera.NewMock(camera.MockOptions{
+		VISCAAddr: *visca,
+		HTTPAddr:  *httpAddr,
+		MoveTime:  *moveTime,
+		Drop:      *drop,
+		Username:  *username,
+		Password:  *password,
+	})
+	if err := mock.Serve(ctx); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return EXIT_FAILURE
+	}
+	return EXIT_OK
+}
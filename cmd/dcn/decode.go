@@ -0,0 +1,516 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/ampol-me/phi-DCN/protocol"
+)
+
+// ที่นั่งหนึ่งที่นั่งใน frame ที่ถอดรหัสแล้ว
+type DecodedSeat struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	MicOn bool   `json:"micOn"`
+}
+
+// frame ที่สร้างขึ้นใหม่จาก log หรือ hex
+type DecodedFrame struct {
+	Time      string         `json:"time,omitempty"` // เวลาที่ได้รับ (เฉพาะคำสั่ง watch)
+	Source    string         `json:"source"`         // ไฟล์:บรรทัด ที่พบ frame หรือที่อยู่ที่รับ frame มา
+	Topic     uint32         `json:"topic"`
+	TopicName string         `json:"topicName"`
+	Length    uint32         `json:"length"`           // ความยาว payload ตาม header (หรือที่คำนวณได้ถ้าไม่มี header)
+	Encoding  string         `json:"encoding"`         // utf-16le, utf-8 หรือ unknown
+	Seat      *DecodedSeat   `json:"seat,omitempty"`   // SeatActivity
+	Active    *[]DecodedSeat `json:"active,omitempty"` // DiscussionActivity
+	XML       string         `json:"xml,omitempty"`
+	Error     string         `json:"error,omitempty"`
+}
+
+// frame ที่ยังรอ XML ตามหลังบรรทัด "📝 Raw data" (log มีเพียง 16 bytes แรกของ frame)
+type pendingFrame struct {
+	line       int
+	topic      uint32
+	length     uint32
+	hasHeader  bool
+	encoding   string
+	xml        strings.Builder
+	collecting bool
+}
+
+// ตัวแยก frame จาก log ทีละบรรทัด รองรับ
+//
+//	📝 Raw data: [05 00 00 00 ...] ตามด้วย XML ที่จัดรูปแบบแล้ว (log แบบเก่า)
+//	log แบบ text/json ของ slog (event=frame.raw hex="..." และ event=frame.xml xml="...")
+//	record ของไฟล์ capture และผลลัพธ์ JSON ของคำสั่ง import
+//	hex ล้วน (ติดกัน คั่นด้วยช่องว่าง จุลภาค หรือ 0x) ต่อกันได้หลายบรรทัด
+type frameScanner struct {
+	source  string
+	frames  []DecodedFrame
+	pending *pendingFrame
+	hexBuf  []byte
+	hexLine int
+}
+
+var (
+	rawDataPattern = regexp.MustCompile(`(?:📝 Raw data|raw data)\s*:?\s*\[([0-9a-fA-F\s]+)\]`)
+	hexAttrPattern = regexp.MustCompile(`\bhex="([0-9a-fA-F\s]+)"`)
+	topicPattern   = regexp.MustCompile(`\btopic=(\d+)`)
+	hexLinePattern = regexp.MustCompile(`^\[?\s*(?:(?:0x)?[0-9a-fA-F]{2}[\s,:]*)+\]?$`)
+	hexBytePattern = regexp.MustCompile(`(?:0x)?([0-9a-fA-F]{2})`)
+)
+
+// ประมวลผลหนึ่งบรรทัด
+func (s *frameScanner) line(n int, text string) {
+	trimmed := strings.TrimSpace(text)
+
+	// log แบบ JSON, record ของ capture หรือผลลัพธ์ของ import
+	if strings.HasPrefix(trimmed, "{") {
+		var fields map[string]any
+		if json.Unmarshal([]byte(trimmed), &fields) == nil {
+			s.flushHex()
+			s.jsonLine(n, fields)
+			return
+		}
+	}
+
+	// 📝 Raw data: [..] หรือ hex="..." ของ event frame.raw
+	if m := rawDataPattern.FindStringSubmatch(text); m != nil {
+		s.startFromHex(n, m[1])
+		return
+	}
+	if strings.Contains(text, "frame.raw") {
+		if m := hexAttrPattern.FindStringSubmatch(text); m != nil {
+			s.startFromHex(n, m[1])
+			return
+		}
+	}
+
+	// xml="..." ของ event frame.xml ในรูปแบบ text
+	if strings.Contains(text, "frame.xml") {
+		if i := strings.Index(text, ` xml="`); i >= 0 {
+			if quoted, err := strconv.QuotedPrefix(text[i+5:]); err == nil {
+				if value, err := strconv.Unquote(quoted); err == nil {
+					var topic uint32
+					if m := topicPattern.FindStringSubmatch(text); m != nil {
+						t, _ := strconv.ParseUint(m[1], 10, 32)
+						topic = uint32(t)
+					}
+					s.xmlOnly(n, topic, value)
+					return
+				}
+			}
+		}
+	}
+
+	// กำลังเก็บ XML ที่จัดรูปแบบไว้หลายบรรทัด
+	if s.pending != nil && s.pending.collecting {
+		if strings.HasPrefix(trimmed, "-----") {
+			s.finishPending()
+			return
+		}
+		s.pending.xml.WriteString(text)
+		s.pending.xml.WriteByte('\n')
+		if xmlComplete(s.pending.xml.String()) {
+			s.finishPending()
+		}
+		return
+	}
+
+	// จุดเริ่มต้นของ XML
+	if i := strings.Index(text, "<?xml"); i >= 0 {
+		s.flushHex()
+		if s.pending == nil {
+			s.pending = &pendingFrame{line: n}
+		}
+		s.pending.collecting = true
+		s.pending.xml.WriteString(text[i:])
+		s.pending.xml.WriteByte('\n')
+		if xmlComplete(s.pending.xml.String()) {
+			s.finishPending()
+		}
+		return
+	}
+
+	// hex ล้วน
+	if trimmed != "" && hexLinePattern.MatchString(trimmed) {
+		if len(s.hexBuf) == 0 {
+			s.hexLine = n
+		}
+		s.hexBuf = append(s.hexBuf, parseHexBytes(trimmed)...)
+		return
+	}
+
+	// บรรทัดอื่นจบ hex ที่ต่อกันหลายบรรทัด
+	s.flushHex()
+}
+
+// บรรทัดที่เป็น JSON
+func (s *frameScanner) jsonLine(n int, fields map[string]any) {
+	str := func(key string) string {
+		v, _ := fields[key].(string)
+		return v
+	}
+	topic := uint32(0)
+	if v, ok := fields["topic"].(float64); ok {
+		topic = uint32(v)
+	}
+
+	switch {
+	case str("header") != "" && fields["xml"] != nil:
+		// record ของไฟล์ capture
+		s.finishPending()
+		header := parseHexBytes(str("header"))
+		frame := DecodedFrame{Source: s.at(n), Topic: topic, Encoding: str("encoding")}
+		if len(header) >= 8 {
+			frame.Topic = binary.LittleEndian.Uint32(header[0:4])
+			frame.Length = binary.LittleEndian.Uint32(header[4:8])
+		}
+		s.add(frame, str("xml"))
+	case fields["xml"] != nil && fields["event"] == nil:
+		// ผลลัพธ์ JSON ของคำสั่ง import
+		s.finishPending()
+		frame := DecodedFrame{Source: s.at(n), Topic: topic, Encoding: "unknown"}
+		if v, ok := fields["length"].(float64); ok {
+			frame.Length = uint32(v)
+		}
+		s.add(frame, str("xml"))
+	case str("event") == "frame.raw":
+		s.startFromHex(n, str("hex"))
+	case str("event") == "frame.xml":
+		s.xmlOnly(n, topic, str("xml"))
+	}
+}
+
+// เริ่ม frame จาก hex ที่อยู่ใน log ถ้า hex ครบทั้ง frame จะได้ frame ทันที
+func (s *frameScanner) startFromHex(n int, text string) {
+	s.flushHex()
+	s.finishPending()
+
+	data := parseHexBytes(text)
+	if len(data) < 8 || !protocol.IsFrameTopic(data[0]) {
+		return
+	}
+	length := binary.LittleEndian.Uint32(data[4:8])
+	if uint64(len(data)) >= 8+uint64(length) {
+		s.hexLine = n
+		s.hexBuf = data
+		s.flushHex()
+		return
+	}
+
+	s.pending = &pendingFrame{
+		line:      n,
+		topic:     binary.LittleEndian.Uint32(data[0:4]),
+		length:    length,
+		hasHeader: true,
+		encoding:  detectEncoding(data[8:]),
+	}
+}
+
+// XML ที่มาจาก event frame.xml ถ้ามี frame.raw ก่อนหน้าจะรวมเป็น frame เดียวกัน
+func (s *frameScanner) xmlOnly(n int, topic uint32, text string) {
+	s.flushHex()
+	if s.pending == nil || s.pending.collecting {
+		s.finishPending()
+		s.pending = &pendingFrame{line: n, topic: topic}
+	}
+	s.pending.xml.WriteString(text)
+	s.finishPending()
+}
+
+// สร้าง frame จาก hex ที่สะสมไว้
+func (s *frameScanner) flushHex() {
+	data := s.hexBuf
+	s.hexBuf = nil
+	if len(data) == 0 {
+		return
+	}
+
+	if len(data) >= 8 && protocol.IsFrameTopic(data[0]) {
+		decoder := &protocol.FrameDecoder{}
+		for _, frame := range decoder.Feed(data) {
+			s.frames = append(s.frames, decodeFrame(s.at(s.hexLine), frame))
+		}
+		if decoder.Pending() > 0 {
+			s.frames = append(s.frames, DecodedFrame{
+				Source: s.at(s.hexLine),
+				Topic:  binary.LittleEndian.Uint32(data[0:4]),
+				Length: binary.LittleEndian.Uint32(data[4:8]),
+				Error:  fmt.Sprintf("frame ไม่ครบ: เหลือ %d bytes ที่ยังไม่ครบตาม header", decoder.Pending()),
+			})
+		}
+		return
+	}
+
+	// ไม่มี header ถือว่าเป็น payload อย่างเดียว
+	encoding := detectEncoding(data)
+	s.add(DecodedFrame{Source: s.at(s.hexLine), Length: uint32(len(data)), Encoding: encoding}, decodeText(data, encoding))
+}
+
+// ปิด frame ที่รออยู่ (ถ้ามี)
+func (s *frameScanner) finishPending() {
+	p := s.pending
+	s.pending = nil
+	if p == nil {
+		return
+	}
+
+	text := strings.TrimSpace(p.xml.String())
+	if !p.hasHeader && text == "" {
+		return
+	}
+	frame := DecodedFrame{Source: s.at(p.line), Topic: p.topic, Length: p.length, Encoding: p.encoding}
+	if !p.hasHeader {
+		frame.Encoding = "unknown"
+	}
+	if text == "" {
+		frame.Error = "ไม่พบ XML ตามหลัง header"
+	}
+	s.add(frame, text)
+}
+
+// เติมข้อมูลที่แปลงจาก XML แล้วเก็บ frame
+func (s *frameScanner) add(frame DecodedFrame, text string) {
+	s.frames = append(s.frames, describeFrame(frame, text))
+}
+
+// เติมข้อมูลที่แปลงจาก XML ลงใน frame
+func describeFrame(frame DecodedFrame, text string) DecodedFrame {
+	frame.XML = text
+
+	// ไม่มี topic ใน log ใช้ชื่อ root element แทน
+	if frame.Topic == 0 {
+		frame.Topic = xmlTopic(text)
+	}
+	if frame.Length == 0 && text != "" {
+		frame.Length = uint32(len(text))
+		if frame.Encoding == protocol.ENCODING_UTF16LE {
+			frame.Length = uint32(2 + 2*len(utf16.Encode([]rune(text))))
+		}
+	}
+
+	switch frame.Topic {
+	case protocol.TOPIC_SEAT:
+		frame.TopicName = "SeatActivity"
+		var seat protocol.SeatActivity
+		if err := xml.Unmarshal([]byte(text), &seat); err != nil {
+			frame.Error = firstNonEmpty(frame.Error, err.Error())
+			break
+		}
+		frame.Seat = &DecodedSeat{ID: seat.Seat.ID, Name: seat.Seat.SeatData.Name, MicOn: seat.Seat.SeatData.MicrophoneActive}
+	case protocol.TOPIC_DISCUSSION:
+		frame.TopicName = "DiscussionActivity"
+		var discussion protocol.DiscussionActivity
+		if err := xml.Unmarshal([]byte(text), &discussion); err != nil {
+			frame.Error = firstNonEmpty(frame.Error, err.Error())
+			break
+		}
+		active := []DecodedSeat{}
+		for _, participant := range discussion.Discussion.ActiveList.Participants.ParticipantContainers {
+			active = append(active, DecodedSeat{
+				ID:    participant.Seat.ID,
+				Name:  participant.Seat.SeatData.Name,
+				MicOn: participant.Seat.SeatData.MicrophoneActive,
+			})
+		}
+		frame.Active = &active
+	default:
+		frame.TopicName = "Unknown"
+	}
+	return frame
+}
+
+// ถอดรหัส frame ที่สมบูรณ์ (header + payload) หนึ่ง frame
+func decodeFrame(source string, data []byte) DecodedFrame {
+	payload := data[protocol.HEADER_SIZE:]
+	encoding := detectEncoding(payload)
+	return describeFrame(DecodedFrame{
+		Source:   source,
+		Topic:    binary.LittleEndian.Uint32(data[0:4]),
+		Length:   binary.LittleEndian.Uint32(data[4:8]),
+		Encoding: encoding,
+	}, decodeText(payload, encoding))
+}
+
+// ปิดทุกอย่างที่ค้างอยู่เมื่อจบไฟล์
+func (s *frameScanner) finish() {
+	s.flushHex()
+	s.finishPending()
+}
+
+func (s *frameScanner) at(line int) string {
+	return fmt.Sprintf("%s:%d", s.source, line)
+}
+
+// XML ครบแล้วเมื่อพบ tag ปิดของ root element
+func xmlComplete(text string) bool {
+	return strings.Contains(text, "</SeatActivity>") || strings.Contains(text, "</DiscussionActivity>")
+}
+
+// แปลงข้อความ hex เป็น bytes
+func parseHexBytes(text string) []byte {
+	text = strings.TrimSpace(text)
+	// hex ที่เขียนติดกันโดยไม่มีตัวคั่น
+	if !strings.ContainsAny(text, " ,:x[]\t") {
+		if b, err := hex.DecodeString(text); err == nil {
+			return b
+		}
+	}
+	var out []byte
+	for _, m := range hexBytePattern.FindAllStringSubmatch(text, -1) {
+		b, _ := strconv.ParseUint(m[1], 16, 8)
+		out = append(out, byte(b))
+	}
+	return out
+}
+
+// ตรวจ encoding ของ payload จาก BOM หรือรูปแบบ byte ของ UTF-16LE ("<\x00?\x00")
+func detectEncoding(payload []byte) string {
+	switch {
+	case len(payload) >= 2 && payload[0] == 0xFF && payload[1] == 0xFE:
+		return protocol.ENCODING_UTF16LE
+	case len(payload) >= 4 && payload[0] != 0 && payload[1] == 0 && payload[3] == 0:
+		return protocol.ENCODING_UTF16LE
+	case len(payload) >= 1:
+		return protocol.ENCODING_UTF8
+	}
+	return "unknown"
+}
+
+// แปลง payload เป็น string ตาม encoding
+func decodeText(payload []byte, encoding string) string {
+	if encoding != protocol.ENCODING_UTF16LE {
+		return string(payload)
+	}
+	if len(payload) >= 2 && payload[0] == 0xFF && payload[1] == 0xFE {
+		payload = payload[2:]
+	}
+	return protocol.DecodeUTF16LE(payload)
+}
+
+func firstNonEmpty(a, b string) string {
+	if a != "" {
+		return a
+	}
+	return b
+}
+
+// แยก frame ทั้งหมดจาก reader
+func scanFrames(source string, r io.Reader) ([]DecodedFrame, error) {
+	s := &frameScanner{source: source}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), 16<<20)
+	n := 0
+	for scanner.Scan() {
+		n++
+		s.line(n, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	s.finish()
+	return s.frames, nil
+}
+
+// เขียน frame ในรูปแบบที่อ่านง่าย
+func printFrame(w io.Writer, frame DecodedFrame, showXML bool) {
+	if frame.Time != "" {
+		fmt.Fprintf(w, "%s  ", frame.Time)
+	}
+	fmt.Fprintf(w, "%s  topic=%d (%s)  length=%d  encoding=%s\n", frame.Source, frame.Topic, frame.TopicName, frame.Length, frame.Encoding)
+	if frame.Seat != nil {
+		fmt.Fprintf(w, "    seat %s %q mic=%s\n", frame.Seat.ID, frame.Seat.Name, micText(frame.Seat.MicOn))
+	}
+	if frame.Active != nil {
+		if len(*frame.Active) == 0 {
+			fmt.Fprintf(w, "    active: (none)\n")
+		}
+		for _, seat := range *frame.Active {
+			fmt.Fprintf(w, "    active: seat %s %q mic=%s\n", seat.ID, seat.Name, micText(seat.MicOn))
+		}
+	}
+	if frame.Error != "" {
+		fmt.Fprintf(w, "    error: %s\n", frame.Error)
+	}
+	if showXML {
+		for _, pretty := range protocol.PrettyXML(frame.XML) {
+			for _, line := range strings.Split(pretty, "\n") {
+				fmt.Fprintf(w, "    %s\n", line)
+			}
+		}
+	}
+}
+
+func micText(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
+
+// คำสั่ง decode: อ่าน log หรือ hex จากไฟล์ (หรือ stdin) แล้วแสดง frame ที่สร้างขึ้นใหม่
+// ไม่พบ frame เลยถือว่าผิดพลาด (exit code 1)
+func runDecode(ctx context.Context, args []string) int {
+	fs := newFlagSet("decode", "[options] [file ...]",
+		"สร้าง frame ขึ้นใหม่จาก log ของ proxy (text หรือ json), ไฟล์ capture, ผลลัพธ์ของ import หรือ hex dump\nอ่านจาก stdin ถ้าไม่ระบุไฟล์")
+	asJSON := fs.Bool("json", false, "แสดงผลเป็น JSON Lines")
+	showXML := fs.Bool("xml", false, "แสดง XML ที่จัดรูปแบบแล้วของแต่ละ frame (ใน JSON จะมี xml เสมอ)")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	var frames []DecodedFrame
+	if fs.NArg() == 0 {
+		found, err := scanFrames("stdin", os.Stdin)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return EXIT_FAILURE
+		}
+		frames = found
+	}
+	for _, path := range fs.Args() {
+		f, err := os.Open(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return EXIT_FAILURE
+		}
+		found, err := scanFrames(path, f)
+		f.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			return EXIT_FAILURE
+		}
+		frames = append(frames, found...)
+	}
+
+	if len(frames) == 0 {
+		fmt.Fprintln(os.Stderr, "ไม่พบ frame")
+		return EXIT_FAILURE
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	enc := json.NewEncoder(out)
+	enc.SetEscapeHTML(false)
+	for _, frame := range frames {
+		if *asJSON {
+			enc.Encode(frame)
+			continue
+		}
+		printFrame(out, frame, *showXML)
+	}
+	return EXIT_OK
+}
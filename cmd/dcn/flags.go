@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ampol-me/phi-DCN/internal/journal"
+	"github.com/ampol-me/phi-DCN/internal/logging"
+)
+
+// สร้าง FlagSet ของคำสั่งย่อยพร้อมข้อความ -help
+//
+//	synopsis    argument ต่อจากชื่อคำสั่ง เช่น "[options] file ..."
+//	description คำอธิบายคำสั่ง
+func newFlagSet(name, synopsis, description string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "ใช้งาน: dcn %s %s\n\n%s\n\noptions:\n", name, synopsis, description)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// อ่าน flags คืนค่า exit code และ false ถ้าต้องจบคำสั่งทันที (-help หรือ flag ไม่ถูกต้อง)
+//
+// flags วางก่อนหรือหลัง argument ก็ได้ เช่น "dcn replay journal -speed 2"
+// ทุกอย่างหลัง "--" ถือเป็น argument
+func parseFlags(fs *flag.FlagSet, args []string) (int, bool) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return EXIT_OK, false
+			}
+			return EXIT_USAGE, false
+		}
+		rest := fs.Args()
+		if len(rest) == 0 {
+			break
+		}
+		if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+			positional = append(positional, rest...)
+			break
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+
+	// ให้ fs.Args() คืนค่า argument ทั้งหมดตามลำดับเดิม
+	fs.Parse(append([]string{"--"}, positional...))
+	return EXIT_OK, true
+}
+
+// แจ้งว่าใช้คำสั่งไม่ถูกต้อง พร้อมแสดงวิธีใช้
+func usageError(fs *flag.FlagSet, format string, args ...any) int {
+	fmt.Fprintf(fs.Output(), format+"\n\n", args...)
+	fs.Usage()
+	return EXIT_USAGE
+}
+
+// flags ของ logging ที่ใช้ร่วมกันทุกคำสั่ง
+type logFlags struct {
+	format     *string
+	level      *string
+	lang       *string
+	subsystems *string
+}
+
+// เพิ่ม flags ของ logging subsystems คือรายชื่อ subsystem ของคำสั่งนั้นสำหรับข้อความ -help
+func addLogFlags(fs *flag.FlagSet, subsystems string) logFlags {
+	return logFlags{
+		format:     fs.String("log-format", "text", "รูปแบบ log: text หรือ json"),
+		level:      fs.String("log-level", "info", "ระดับ log เริ่มต้น: debug, info, warn, error"),
+		lang:       fs.String("log-lang", logging.LANG_TH, "ภาษาของข้อความ log: th หรือ en"),
+		subsystems: fs.String("log-subsystems", "", "ระดับ log แยกตาม subsystem ("+subsystems+") เช่น framing=debug,clients=warn"),
+	}
+}
+
+// ตั้งค่า logging ตาม flags คืนค่า false ถ้าค่าไม่ถูกต้อง
+func (l logFlags) setup() bool {
+	if err := logging.Setup(*l.format, *l.level, *l.lang, *l.subsystems); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return false
+	}
+	return true
+}
+
+// flags ของ journal ที่ใช้ร่วมกันระหว่าง serve และ proxy
+type journalFlags struct {
+	dir        *string
+	maxFileMB  *int64
+	maxTotalMB *int64
+	maxAge     *time.Duration
+}
+
+func addJournalFlags(fs *flag.FlagSet, what string) journalFlags {
+	return journalFlags{
+		dir:        fs.String("journal-dir", "", "ไดเรกทอรีสำหรับบันทึกทุก frame ที่"+what+" (ว่าง = ไม่บันทึก)"),
+		maxFileMB:  fs.Int64("journal-max-file-mb", 64, "ขนาดไฟล์ journal สูงสุดก่อนเริ่มไฟล์ใหม่ (MB)"),
+		maxTotalMB: fs.Int64("journal-max-total-mb", 1024, "ขนาดรวมสูงสุดของไฟล์ journal (MB, 0 = ไม่จำกัด)"),
+		maxAge:     fs.Duration("journal-max-age", 7*24*time.Hour, "อายุสูงสุดของไฟล์ journal (0 = ไม่จำกัด)"),
+	}
+}
+
+func (j journalFlags) options() journal.Options {
+	return journal.Options{
+		Dir:          *j.dir,
+		MaxFileSize:  *j.maxFileMB << 20,
+		MaxTotalSize: *j.maxTotalMB << 20,
+		MaxAge:       *j.maxAge,
+	}
+}
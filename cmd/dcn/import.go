@@ -0,0 +1,317 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"time"
+
+	"github.com/ampol-me/phi-DCN/internal/journal"
+	"github.com/ampol-me/phi-DCN/internal/logging"
+	"github.com/ampol-me/phi-DCN/internal/pcap"
+	"github.com/ampol-me/phi-DCN/protocol"
+)
+
+const (
+	IMPORT_PORT = 20000 // พอร์ต TCP เริ่มต้นของ DCN server
+
+	// ขนาดข้อมูลที่มาไม่ตามลำดับที่เก็บรอได้ต่อ stream ก่อนถือว่าข้อมูลขาดหาย
+	importMaxOutOfOrder = 1 << 20
+)
+
+var logImport = logging.New("import")
+
+// frame ที่ import ได้ในรูปแบบ JSON Lines
+type ImportedFrame struct {
+	Time        time.Time `json:"time"`
+	Source      string    `json:"source"`
+	Destination string    `json:"destination"`
+	Direction   string    `json:"direction"`
+	Topic       uint32    `json:"topic"`
+	Length      int       `json:"length"`
+	XML         string    `json:"xml"`
+}
+
+// สถิติของการ import
+type importStats struct {
+	Packets  int
+	Segments int
+	Streams  int
+	Frames   int
+	Resyncs  int
+	Gaps     int
+}
+
+// ข้อมูลของ TCP หนึ่งทิศทาง
+type tcpStream struct {
+	src, dst   string
+	dir        journal.Direction
+	started    bool
+	nextSeq    uint32
+	outOfOrder map[uint32][]byte // segment ที่มาก่อนลำดับ key เป็น sequence number
+	buffered   int
+	lastTime   time.Time // เวลาของ packet ล่าสุด
+	decoder    protocol.FrameDecoder
+}
+
+// ประกอบ TCP stream บนพอร์ตของ DCN และแยก frame ส่งต่อให้ emit
+type importer struct {
+	port    int
+	streams map[string]*tcpStream
+	stats   importStats
+	emit    func(stream *tcpStream, frame []byte, t time.Time) error
+}
+
+// อ่านทุก packet จากไฟล์ capture หนึ่งไฟล์
+func (im *importer) readFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	reader, err := pcap.NewReader(f)
+	if err != nil {
+		return err
+	}
+	for {
+		packet, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		im.stats.Packets++
+
+		seg, ok := pcap.DecodeTCP(packet)
+		if !ok || (seg.Src.Port != im.port && seg.Dst.Port != im.port) {
+			continue
+		}
+		im.stats.Segments++
+		if err := im.segment(seg, packet.Time); err != nil {
+			return err
+		}
+	}
+}
+
+// รับ TCP segment หนึ่งตัวเข้าสู่ stream ของทิศทางนั้น
+func (im *importer) segment(seg pcap.Segment, t time.Time) error {
+	key := seg.Src.String() + ">" + seg.Dst.String()
+	stream, ok := im.streams[key]
+	if !ok {
+		stream = &tcpStream{src: seg.Src.String(), dst: seg.Dst.String(), dir: journal.DIR_SENT, outOfOrder: make(map[uint32][]byte)}
+		// ข้อมูลที่ออกจากพอร์ตของ DCN คือข้อมูลที่ได้รับจาก server
+		if seg.Src.Port == im.port {
+			stream.dir = journal.DIR_RECEIVED
+		}
+		im.streams[key] = stream
+		im.stats.Streams++
+	}
+
+	stream.lastTime = t
+
+	seq := seg.Seq
+	if seg.Flags&pcap.TCP_SYN != 0 {
+		// การเชื่อมต่อใหม่ที่ใช้ endpoint เดิมซ้ำ เริ่ม stream ใหม่
+		stream.started = true
+		stream.nextSeq = seq + 1
+		stream.outOfOrder = make(map[uint32][]byte)
+		stream.buffered = 0
+		stream.decoder = protocol.FrameDecoder{}
+		seq++
+	}
+	if !stream.started {
+		// capture เริ่มกลางการเชื่อมต่อ เริ่มนับจาก segment แรกที่เห็น
+		stream.started = true
+		stream.nextSeq = seq
+	}
+	if len(seg.Payload) == 0 {
+		return nil
+	}
+
+	if diff := int32(seq - stream.nextSeq); diff > 0 {
+		// มาก่อนลำดับ เก็บไว้รอ segment ที่ขาด
+		if _, exists := stream.outOfOrder[seq]; !exists {
+			stream.outOfOrder[seq] = append([]byte(nil), seg.Payload...)
+			stream.buffered += len(seg.Payload)
+		}
+		if stream.buffered > importMaxOutOfOrder {
+			return im.skipGap(stream, t)
+		}
+		return nil
+	}
+
+	if err := im.deliver(stream, seq, seg.Payload, t); err != nil {
+		return err
+	}
+	return im.drain(stream, t)
+}
+
+// ส่งข้อมูลที่ต่อจาก nextSeq ให้ decoder โดยตัดส่วนที่ส่งซ้ำ (retransmission) ออก
+func (im *importer) deliver(stream *tcpStream, seq uint32, payload []byte, t time.Time) error {
+	overlap := int(int32(stream.nextSeq - seq))
+	if overlap >= len(payload) {
+		return nil
+	}
+	payload = payload[overlap:]
+	stream.nextSeq += uint32(len(payload))
+
+	resyncs := stream.decoder.ResyncEvents
+	frames := stream.decoder.Feed(payload)
+	im.stats.Resyncs += stream.decoder.ResyncEvents - resyncs
+	for _, frame := range frames {
+		im.stats.Frames++
+		if err := im.emit(stream, frame, t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ส่ง segment ที่เก็บไว้ซึ่งต่อกับ nextSeq แล้ว
+func (im *importer) drain(stream *tcpStream, t time.Time) error {
+	for progressed := true; progressed; {
+		progressed = false
+		for seq, payload := range stream.outOfOrder {
+			if int32(seq-stream.nextSeq) > 0 {
+				continue
+			}
+			delete(stream.outOfOrder, seq)
+			stream.buffered -= len(payload)
+			if err := im.deliver(stream, seq, payload, t); err != nil {
+				return err
+			}
+			progressed = true
+		}
+	}
+	return nil
+}
+
+// ข้ามข้อมูลที่ขาดหายไปยัง segment ถัดไปที่มี และเริ่มแยก frame ใหม่
+func (im *importer) skipGap(stream *tcpStream, t time.Time) error {
+	first, found := uint32(0), false
+	for seq := range stream.outOfOrder {
+		if !found || int32(seq-first) < 0 {
+			first, found = seq, true
+		}
+	}
+	if !found {
+		return nil
+	}
+
+	logImport.Warn("import.gap", "stream", stream.src+" > "+stream.dst, "missing_bytes", first-stream.nextSeq)
+	im.stats.Gaps++
+	stream.nextSeq = first
+	stream.decoder = protocol.FrameDecoder{}
+	return im.drain(stream, t)
+}
+
+// ส่งข้อมูลที่ยังรออยู่ทั้งหมดเมื่ออ่านไฟล์ครบแล้ว
+func (im *importer) finish() error {
+	for _, stream := range im.streams {
+		for len(stream.outOfOrder) > 0 {
+			if err := im.skipGap(stream, stream.lastTime); err != nil {
+				return err
+			}
+		}
+		if stream.decoder.Pending() > 0 {
+			logImport.Warn("import.truncated", "stream", stream.src+" > "+stream.dst, "bytes", stream.decoder.Pending())
+		}
+	}
+	return nil
+}
+
+// คำสั่ง import: อ่านไฟล์ pcap/pcapng แล้วเขียน frame ที่พบเป็น journal หรือ JSON Lines
+func runImport(ctx context.Context, args []string) int {
+	fs := newFlagSet("import", "[options] file.pcap [file.pcapng ...]",
+		"ประกอบ TCP stream บนพอร์ตของ DCN จากไฟล์ pcap/pcapng แล้วแยก frame ออกมา\nlog เขียนออก stderr เพื่อไม่ให้ปนกับผลลัพธ์")
+	port := fs.Int("port", IMPORT_PORT, "พอร์ต TCP ของ DCN server")
+	format := fs.String("format", "json", "รูปแบบผลลัพธ์: json (JSON Lines) หรือ journal")
+	out := fs.String("out", "", "json: ไฟล์ผลลัพธ์ (ว่าง = stdout), journal: ไดเรกทอรีของ journal")
+	logs := addLogFlags(fs, "import, journal")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() == 0 {
+		return usageError(fs, "ต้องระบุไฟล์ capture อย่างน้อยหนึ่งไฟล์")
+	}
+
+	// log ไปที่ stderr เพื่อไม่ให้ปนกับผลลัพธ์ JSON
+	logging.Output = os.Stderr
+	if !logs.setup() {
+		return EXIT_USAGE
+	}
+
+	im := &importer{port: *port, streams: make(map[string]*tcpStream)}
+
+	switch *format {
+	case "json":
+		w := io.Writer(os.Stdout)
+		if *out != "" {
+			f, err := os.Create(*out)
+			if err != nil {
+				logImport.Error("import.output_failed", "file", *out, "error", err)
+				return EXIT_FAILURE
+			}
+			defer f.Close()
+			w = f
+		}
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		im.emit = func(stream *tcpStream, frame []byte, t time.Time) error {
+			return enc.Encode(ImportedFrame{
+				Time:        t,
+				Source:      stream.src,
+				Destination: stream.dst,
+				Direction:   stream.dir.String(),
+				Topic:       protocol.FrameTopic(frame),
+				Length:      len(frame) - protocol.HEADER_SIZE,
+				XML:         protocol.DecodePayload(frame[protocol.HEADER_SIZE:]),
+			})
+		}
+	case "journal":
+		if *out == "" {
+			return usageError(fs, "ต้องระบุ -out เป็นไดเรกทอรีของ journal")
+		}
+		j, err := journal.Open(journal.Options{Dir: *out, MaxFileSize: 64 << 20})
+		if err != nil {
+			logImport.Error("import.output_failed", "file", *out, "error", err)
+			return EXIT_FAILURE
+		}
+		defer j.Close()
+		im.emit = func(stream *tcpStream, frame []byte, t time.Time) error {
+			_, err := j.Append(stream.dir, protocol.FrameTopic(frame), frame[protocol.HEADER_SIZE:], t)
+			return err
+		}
+	default:
+		return usageError(fs, "รูปแบบผลลัพธ์ไม่ถูกต้อง %q (ใช้ json หรือ journal)", *format)
+	}
+
+	for _, path := range fs.Args() {
+		if ctx.Err() != nil {
+			return EXIT_FAILURE
+		}
+		if err := im.readFile(path); err != nil {
+			logImport.Error("import.file_failed", "file", path, "error", err)
+			return EXIT_FAILURE
+		}
+	}
+	if err := im.finish(); err != nil {
+		logImport.Error("import.output_failed", "file", *out, "error", err)
+		return EXIT_FAILURE
+	}
+
+	logImport.Info("import.done",
+		"files", fs.NArg(),
+		"packets", im.stats.Packets,
+		"segments", im.stats.Segments,
+		"streams", im.stats.Streams,
+		"frames", im.stats.Frames,
+		"resyncs", im.stats.Resyncs,
+		"gaps", im.stats.Gaps,
+		"port", *port,
+	)
+	return EXIT_OK
+}
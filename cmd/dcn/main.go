@@ -0,0 +1,95 @@
+// คำสั่ง dcn รวมเครื่องมือทั้งหมดของ phi-DCN ไว้ในไฟล์เดียว
+//
+//	dcn serve    จำลอง Bosch DCN server จาก API (หรือ mock)
+//	dcn proxy    เชื่อมต่อ DCN server แล้วส่งต่อ frame ไปยัง clients
+//	dcn watch    เชื่อมต่อ DCN server หรือ proxy แล้วแสดง frame ที่ได้รับ
+//	dcn replay   ส่ง frame จาก journal ที่บันทึกไว้ตามจังหวะเวลาเดิม
+//	dcn send     ส่ง frame จากไฟล์ XML หรือสถานะที่นั่งที่กำหนด
+//	dcn decode   สร้าง frame ขึ้นใหม่จาก log หรือ hex dump
+//	dcn import   อ่าน frame จากไฟล์ pcap/pcapng
+//	dcn camera   เรียก preset ของกล้อง PTZ หนึ่งครั้ง
+//	dcn mockcam  จำลองกล้อง PTZ (VISCA-over-IP และ Panasonic HTTP)
+//
+// exit code: 0 = สำเร็จหรือหยุดด้วย SIGINT/SIGTERM, 1 = ทำงานผิดพลาด, 2 = ใช้คำสั่งไม่ถูกต้อง
+// (ใช้ RestartPreventExitStatus=2 ใน systemd เพื่อไม่ให้ restart เมื่อตั้งค่าผิด)
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// exit code ของทุกคำสั่ง
+const (
+	EXIT_OK      = 0 // สำเร็จ หรือหยุดทำงานตามสัญญาณ
+	EXIT_FAILURE = 1 // ทำงานผิดพลาด
+	EXIT_USAGE   = 2 // flag หรือ argument ไม่ถูกต้อง
+)
+
+// คำสั่งย่อย
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, args []string) int
+}
+
+var commands = []command{
+	{"serve", "จำลอง Bosch DCN server จาก API (หรือ mock)", runServe},
+	{"proxy", "เชื่อมต่อ DCN server แล้วส่งต่อ frame ไปยัง clients", runProxy},
+	{"watch", "เชื่อมต่อ DCN server หรือ proxy แล้วแสดง frame ที่ได้รับ", runWatch},
+	{"replay", "ส่ง frame จาก journal ที่บันทึกไว้ตามจังหวะเวลาเดิม", runReplay},
+	{"send", "ส่ง frame จากไฟล์ XML หรือสถานะที่นั่งที่กำหนด", runSend},
+	{"decode", "สร้าง frame ขึ้นใหม่จาก log หรือ hex dump", runDecode},
+	{"import", "อ่าน frame จากไฟล์ pcap/pcapng เป็น JSON Lines หรือ journal", runImport},
+	{"camera", "เรียก preset ของกล้อง PTZ หนึ่งครั้งเพื่อทดสอบการตั้งค่า", runCamera},
+	{"mockcam", "จำลองกล้อง PTZ ที่รับคำสั่ง VISCA-over-IP และ Panasonic HTTP", runMockCamera},
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "ใช้งาน: dcn <command> [options]\n\ncommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-8s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(w, "\nดูตัวเลือกของแต่ละคำสั่งด้วย dcn <command> -help\n")
+	fmt.Fprintf(w, "exit code: 0 = สำเร็จ, 1 = ทำงานผิดพลาด, 2 = ใช้คำสั่งไม่ถูกต้อง\n")
+}
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	if len(args) == 0 {
+		usage(os.Stderr)
+		return EXIT_USAGE
+	}
+
+	name := args[0]
+	switch name {
+	case "help", "-h", "-help", "--help":
+		// dcn help <command> แสดงตัวเลือกของคำสั่งนั้น
+		if len(args) > 1 && name == "help" {
+			return run([]string{args[1], "-help"})
+		}
+		usage(os.Stdout)
+		return EXIT_OK
+	}
+
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+		// ยกเลิก context เมื่อได้รับ SIGINT หรือ SIGTERM
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		return cmd.run(ctx, args[1:])
+	}
+
+	fmt.Fprintf(os.Stderr, "ไม่รู้จักคำสั่ง %q\n\n", name)
+	usage(os.Stderr)
+	return EXIT_USAGE
+}
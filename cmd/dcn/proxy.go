@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ampol-me/phi-DCN/internal/proxy"
+)
+
+// คำสั่ง proxy: เชื่อมต่อ DCN server แล้วส่งต่อ frame ไปยัง clients
+func runProxy(ctx context.Context, args []string) int {
+	fs := newFlagSet("proxy", "[options]", "เชื่อมต่อ Bosch DCN server (เชื่อมต่อใหม่อัตโนมัติเมื่อหลุด) แล้วส่งต่อทุก frame ไปยัง clients ที่เชื่อมต่อเข้ามา")
+	upstream := fs.String("upstream", proxy.UPSTREAM_ADDR, "ที่อยู่ของ Bosch DCN server")
+	listen := fs.String("listen", proxy.LISTEN_ADDR, "ที่อยู่ TCP ที่รอ clients เชื่อมต่อ")
+	httpAddr := fs.String("http", proxy.HTTP_ADDR, "ที่อยู่ของ HTTP endpoint /metrics, /healthz, /readyz, /capture, /seats, /active, /discussion, /ws/events, /events, /director (ว่าง = ไม่เปิด)")
+	ws := fs.String("ws", "", "ที่อยู่ HTTP ที่ส่ง frame เดียวกับ TCP ผ่าน WebSocket ที่ /ws/frames?seats=&topics= (ว่าง = ไม่เปิด)")
+	healthFailAfter := fs.Duration("health-fail-after", 30*time.Second, "รายงาน fail เมื่อขาดการเชื่อมต่อกับ server นานเกินนี้ (0 = ปิด)")
+	healthFrameDegradedAfter := fs.Duration("health-frame-degraded-after", 0, "รายงาน degraded เมื่อไม่ได้รับ frame นานเกินนี้ (0 = ปิด)")
+	healthFrameFailAfter := fs.Duration("health-frame-fail-after", 0, "รายงาน fail เมื่อไม่ได้รับ frame นานเกินนี้ (0 = ปิด)")
+	journal := addJournalFlags(fs, "รับและส่ง")
+	captureEnabled := fs.Bool("capture", false, "เริ่มบันทึก frame ที่ได้รับเป็นไฟล์ JSON Lines ทันที (เปิด/ปิดภายหลังได้ผ่าน /capture)")
+	captureDir := fs.String("capture-dir", "captures", "ไดเรกทอรีของไฟล์ capture")
+	captureMaxFileMB := fs.Int64("capture-max-file-mb", 16, "ขนาดไฟล์ capture สูงสุดก่อนเริ่มไฟล์ใหม่ (MB, 0 = ไม่จำกัด)")
+	captureMaxTotalMB := fs.Int64("capture-max-total-mb", 256, "ขนาดรวมสูงสุดของไฟล์ capture (MB, 0 = ไม่จำกัด)")
+	pcapngPath := fs.String("pcapng", "", "ไฟล์ pcapng สำหรับบันทึก traffic ทั้งขาเข้าและขาออก เพื่อเปิดด้วย Wireshark (ว่าง = ไม่บันทึก)")
+	mqttAddr := fs.String("mqtt", "", "host:port ของ MQTT broker ที่จะ publish สถานะที่นั่ง (ว่าง = ไม่เปิด)")
+	mqttRoom := fs.String("mqtt-room", proxy.MQTT_ROOM, "ชื่อห้องใน topic <prefix>/<room>/...")
+	mqttPrefix := fs.String("mqtt-prefix", proxy.MQTT_PREFIX, "prefix ของ topic")
+	mqttClientID := fs.String("mqtt-client-id", "", "client id ที่ใช้เชื่อมต่อ broker (ว่าง = dcn-proxy-<room>)")
+	mqttUsername := fs.String("mqtt-username", "", "username ของ broker")
+	mqttPassword := fs.String("mqtt-password", "", "password ของ broker (ว่าง = อ่านจาก environment DCN_MQTT_PASSWORD)")
+	mqttQoS := fs.Int("mqtt-qos", 1, "QoS ของข้อความที่ publish (0, 1 หรือ 2)")
+	mqttKeepAlive := fs.Duration("mqtt-keepalive", 30*time.Second, "ระยะ keepalive ของการเชื่อมต่อ broker")
+	mqttDiscovery := fs.Bool("mqtt-discovery", false, "publish config สำหรับ Home Assistant MQTT discovery")
+	mqttDiscoveryPrefix := fs.String("mqtt-discovery-prefix", proxy.MQTT_DISCOVERY_PREFIX, "prefix ของ discovery topic ของ Home Assistant")
+	webhooksPath := fs.String("webhooks", "", "ไฟล์ JSON ที่กำหนด webhook สำหรับเหตุการณ์ของไมค์ (ว่าง = ไม่ส่ง)")
+	webhookDir := fs.String("webhook-dir", proxy.WEBHOOK_DIR, "ไดเรกทอรีของคิว webhook ที่รอส่งซ้ำและ dead-letter log")
+	webhookMaxAttempts := fs.Int("webhook-max-attempts", proxy.WEBHOOK_MAX_ATTEMPTS, "จำนวนครั้งที่ส่งสูงสุดก่อนย้ายไป dead-letter")
+	webhookRetryDelay := fs.Duration("webhook-retry-delay", proxy.WEBHOOK_RETRY_DELAY*time.Second, "ระยะรอก่อนส่งซ้ำครั้งแรก (เพิ่มเป็นสองเท่าทุกครั้ง)")
+	webhookRetryMaxDelay := fs.Duration("webhook-retry-max-delay", proxy.WEBHOOK_RETRY_MAX_DELAY*time.Second, "ระยะรอสูงสุดก่อนส่งซ้ำ")
+	webhookTimeout := fs.Duration("webhook-timeout", proxy.WEBHOOK_TIMEOUT*time.Second, "timeout ของ request หนึ่งครั้ง")
+	directorPath := fs.String("director", "", "ไฟล์ JSON ที่กำหนดตำแหน่งกล้องของแต่ละที่นั่งสำหรับ camera director (ว่าง = ไม่เปิด)")
+	tallyPath := fs.String("tally", "", "ไฟล์ JSON ที่กำหนดปลายทางและ index ของ TSL UMD สำหรับป้ายชื่อและ tally (ว่าง = ไม่ส่ง)")
+	oscPath := fs.String("osc", "", "ไฟล์ JSON ที่กำหนดช่องของ mixing console และข้อความ OSC ของแต่ละที่นั่ง (ว่าง = ไม่ส่ง)")
+	oscDryRun := fs.Bool("osc-dry-run", false, "แสดงข้อความ OSC ใน log แทนการส่ง")
+	dmxPath := fs.String("dmx", "", "ไฟล์ JSON ที่กำหนดปลายทาง Art-Net/sACN และค่า DMX ของไฟส่องแต่ละที่นั่ง (ว่าง = ไม่ส่ง)")
+	logs := addLogFlags(fs, "framing, upstream, seats, stream, clients, http, journal, capture, mqtt, webhook, director, camera, tally, osc, dmx, main")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 0 {
+		return usageError(fs, "ไม่รับ argument %q", fs.Arg(0))
+	}
+	if *mqttQoS < 0 || *mqttQoS > 2 {
+		return usageError(fs, "-mqtt-qos ต้องเป็น 0, 1 หรือ 2")
+	}
+	if *mqttRoom == "" || strings.ContainsAny(*mqttRoom, "+#/") {
+		return usageError(fs, "-mqtt-room ต้องไม่ว่างและห้ามมี +, # หรือ /")
+	}
+	if *mqttPrefix == "" || strings.ContainsAny(*mqttPrefix, "+#") {
+		return usageError(fs, "-mqtt-prefix ต้องไม่ว่างและห้ามมี + หรือ #")
+	}
+	if *mqttClientID == "" {
+		*mqttClientID = "dcn-proxy-" + *mqttRoom
+	}
+	if *mqttPassword == "" {
+		*mqttPassword = os.Getenv("DCN_MQTT_PASSWORD")
+	}
+	if *mqttAddr != "" && *mqttPassword != "" && *mqttUsername == "" {
+		return usageError(fs, "-mqtt-password (หรือ DCN_MQTT_PASSWORD) ต้องใช้คู่กับ -mqtt-username")
+	}
+	if *webhookMaxAttempts < 1 {
+		return usageError(fs, "-webhook-max-attempts ต้องมากกว่า 0")
+	}
+	var webhooks []proxy.WebhookConfig
+	if *webhooksPath != "" {
+		var err error
+		if webhooks, err = proxy.LoadWebhooks(*webhooksPath); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return EXIT_FAILURE
+		}
+	}
+	var director *proxy.DirectorConfig
+	if *directorPath != "" {
+		cfg, err := proxy.LoadDirector(*directorPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return EXIT_FAILURE
+		}
+		director = &cfg
+	}
+	var tally *proxy.TallyConfig
+	if *tallyPath != "" {
+		cfg, err := proxy.LoadTally(*tallyPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return EXIT_FAILURE
+		}
+		if len(cfg.Cameras) > 0 && director == nil {
+			return usageError(fs, "-tally ที่กำหนด cameras ต้องใช้คู่กับ -director")
+		}
+		for name := range cfg.Cameras {
+			if !director.UsesCamera(name) {
+				fmt.Fprintf(os.Stderr, "%s: กล้อง %s ไม่ได้ใช้ใน -director\n", *tallyPath, name)
+				return EXIT_FAILURE
+			}
+		}
+		tally = &cfg
+	}
+	var oscConfig *proxy.OSCConfig
+	if *oscPath != "" {
+		cfg, err := proxy.LoadOSC(*oscPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return EXIT_FAILURE
+		}
+		cfg.DryRun = cfg.DryRun || *oscDryRun
+		if cfg.Addr == "" && !cfg.DryRun {
+			fmt.Fprintf(os.Stderr, "%s: ต้องกำหนด addr หรือใช้ -osc-dry-run\n", *oscPath)
+			return EXIT_FAILURE
+		}
+		oscConfig = &cfg
+	}
+	var lighting *proxy.LightingConfig
+	if *dmxPath != "" {
+		cfg, err := proxy.LoadLighting(*dmxPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return EXIT_FAILURE
+		}
+		lighting = &cfg
+	}
+	if !logs.setup() {
+		return EXIT_USAGE
+	}
+
+	err := proxy.Run(ctx, proxy.Config{
+		UpstreamAddr: *upstream,
+		ListenAddr:   *listen,
+		HTTPAddr:     *httpAddr,
+		WSAddr:       *ws,
+		Health: proxy.HealthThresholds{
+			FailAfter:          *healthFailAfter,
+			FrameDegradedAfter: *healthFrameDegradedAfter,
+			FrameFailAfter:     *healthFrameFailAfter,
+		},
+		Journal: journal.options(),
+		Capture: proxy.CaptureOptions{
+			Dir:          *captureDir,
+			MaxFileSize:  *captureMaxFileMB << 20,
+			MaxTotalSize: *captureMaxTotalMB << 20,
+		},
+		CaptureEnabled: *captureEnabled,
+		PcapngPath:     *pcapngPath,
+		MQTT: proxy.MQTTOptions{
+			Addr:            *mqttAddr,
+			ClientID:        *mqttClientID,
+			Username:        *mqttUsername,
+			Password:        *mqttPassword,
+			Prefix:          *mqttPrefix,
+			Room:            *mqttRoom,
+			QoS:             byte(*mqttQoS),
+			KeepAlive:       *mqttKeepAlive,
+			Discovery:       *mqttDiscovery,
+			DiscoveryPrefix: *mqttDiscoveryPrefix,
+		},
+		Webhooks: proxy.WebhookOptions{
+			Hooks:         webhooks,
+			Dir:           *webhookDir,
+			MaxAttempts:   *webhookMaxAttempts,
+			RetryDelay:    *webhookRetryDelay,
+			RetryMaxDelay: *webhookRetryMaxDelay,
+			Timeout:       *webhookTimeout,
+		},
+		Director: director,
+		Tally:    tally,
+		OSC:      oscConfig,
+		Lighting: lighting,
+	})
+	if err != nil {
+		return EXIT_FAILURE
+	}
+	return EXIT_OK
+}
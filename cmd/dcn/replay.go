@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+
+	"github.com/ampol-me/phi-DCN/internal/server"
+)
+
+// คำสั่ง replay: ทำงานเหมือน serve แต่ส่ง frame จาก journal แทนการดึงข้อมูลจาก API
+func runReplay(ctx context.Context, args []string) int {
+	fs := newFlagSet("replay", "[options] journal",
+		"ส่ง frame จากไฟล์หรือไดเรกทอรี journal ไปยัง clients ตามจังหวะเวลาเดิม\nควบคุมระหว่างเล่นได้ผ่าน admin API (/replay/pause, /replay/resume, /replay/seek, /replay/speed)")
+	listen := fs.String("listen", server.LISTEN_ADDR, "ที่อยู่ TCP ที่รอ clients เชื่อมต่อ")
+	admin := fs.String("admin", server.ADMIN_ADDR, "ที่อยู่ของ admin HTTP API (ว่าง = ไม่เปิด)")
+	ws := fs.String("ws", "", "ที่อยู่ HTTP ที่ส่ง frame เดียวกับ TCP ผ่าน WebSocket ที่ /ws/frames?seats=&topics= (ว่าง = ไม่เปิด)")
+	speed := fs.Float64("speed", 1, "ตัวคูณความเร็วของ replay")
+	loop := fs.Bool("loop", false, "เล่นซ้ำเมื่อจบ")
+	seek := fs.Duration("seek", 0, "เริ่มที่ระยะเวลานี้นับจาก frame แรก")
+	logs := addLogFlags(fs, "framing, clients, admin, replay, main")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() != 1 {
+		return usageError(fs, "ต้องระบุไฟล์หรือไดเรกทอรี journal หนึ่งรายการ")
+	}
+	if *speed <= 0 {
+		return usageError(fs, "-speed ต้องมากกว่า 0")
+	}
+	if !logs.setup() {
+		return EXIT_USAGE
+	}
+
+	err := server.Run(ctx, server.Config{
+		ListenAddr:   *listen,
+		AdminAddr:    *admin,
+		WSAddr:       *ws,
+		ReplaySource: fs.Arg(0),
+		ReplaySpeed:  *speed,
+		ReplayLoop:   *loop,
+		ReplaySeek:   *seek,
+	})
+	if err != nil {
+		return EXIT_FAILURE
+	}
+	return EXIT_OK
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/ampol-me/phi-DCN/internal/logging"
+	"github.com/ampol-me/phi-DCN/protocol"
+)
+
+const SEND_LISTEN_ADDR = ":20000" // พอร์ตเดียวกับ DCN server เพื่อให้ proxy เชื่อมต่อเข้ามาได้ทันที
+
+var logSend = logging.New("send")
+
+// คำสั่ง send: ส่ง frame จากไฟล์ XML หรือสถานะที่นั่งที่กำหนดไปยัง client หนึ่งราย แล้วจบการทำงาน
+func runSend(ctx context.Context, args []string) int {
+	fs := newFlagSet("send", "[options] [file.xml ...]",
+		"ส่ง frame ไปยัง client หนึ่งรายแล้วจบการทำงาน โดยรอให้ client (เช่น dcn proxy) เชื่อมต่อเข้ามาที่ -listen\n"+
+			"หรือเชื่อมต่อออกไปยัง -connect\n\n"+
+			"frame มาจากไฟล์ XML (\"-\" = stdin ไฟล์หนึ่งมีได้หลายเอกสาร topic ดูจาก root element)\n"+
+			"หรือจาก -seat ซึ่งส่ง SeatActivity ตามด้วย DiscussionActivity ของที่นั่งนั้น")
+	listen := fs.String("listen", SEND_LISTEN_ADDR, "ที่อยู่ TCP ที่รอ client เชื่อมต่อ")
+	connect := fs.String("connect", "", "เชื่อมต่อไปยังที่อยู่นี้แทนการรอ client")
+	seatID := fs.Int("seat", 0, "Seat Id ของที่นั่งที่จะส่งสถานะ (0 = ไม่ส่ง)")
+	seatName := fs.String("name", "", "ชื่อที่นั่ง (ค่าเริ่มต้นคือ Seat Id)")
+	participantID := fs.Int("participant", 0, "Participant Id ของที่นั่ง")
+	micOn := fs.Bool("mic", true, "สถานะไมค์ของที่นั่ง (-mic=false = ปิด)")
+	utf8 := fs.Bool("utf8", false, "ส่ง payload เป็น UTF-8 แทน UTF-16LE พร้อม BOM")
+	interval := fs.Duration("interval", 0, "ระยะเวลารอระหว่างแต่ละ frame")
+	hold := fs.Duration("hold", time.Second, "ระยะเวลาคงการเชื่อมต่อไว้หลังส่งครบ ก่อนปิด")
+	logs := addLogFlags(fs, "send")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if *seatID == 0 && fs.NArg() == 0 {
+		return usageError(fs, "ต้องระบุไฟล์ XML หรือ -seat")
+	}
+	if !logs.setup() {
+		return EXIT_USAGE
+	}
+
+	// อ่านและสร้าง frame ทั้งหมดก่อนเชื่อมต่อ
+	encode := protocol.EncodeUTF16LE
+	if *utf8 {
+		encode = func(s string) []byte { return []byte(s) }
+	}
+	var frames [][]byte
+	for _, path := range fs.Args() {
+		docs, err := readXMLDocuments(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			return EXIT_FAILURE
+		}
+		for _, doc := range docs {
+			frames = append(frames, protocol.BuildFrame(xmlTopic(doc), encode(doc)))
+		}
+	}
+	if *seatID != 0 {
+		name := *seatName
+		if name == "" {
+			name = fmt.Sprint(*seatID)
+		}
+		seat := protocol.Seat{ID: *seatID, Name: name, ParticipantID: *participantID, MicOn: *micOn}
+		now := time.Now()
+		frames = append(frames,
+			protocol.BuildFrame(protocol.TOPIC_SEAT, encode(protocol.SeatActivityXML(seat, now))),
+			protocol.BuildFrame(protocol.TOPIC_DISCUSSION, encode(protocol.DiscussionActivityXML([]protocol.Seat{seat}, now))),
+		)
+	}
+
+	conn, err := sendConnect(ctx, *listen, *connect)
+	if err != nil {
+		if ctx.Err() != nil {
+			return EXIT_OK
+		}
+		logSend.Error("send.failed", "error", err)
+		return EXIT_FAILURE
+	}
+	defer conn.Close()
+	logSend.Info("send.connected", "addr", conn.RemoteAddr().String())
+
+	for i, frame := range frames {
+		if i > 0 && !sleepOrDone(ctx, *interval) {
+			return EXIT_OK
+		}
+		if _, err := conn.Write(frame); err != nil {
+			logSend.Error("send.failed", "addr", conn.RemoteAddr().String(), "error", err)
+			return EXIT_FAILURE
+		}
+		logSend.Info("send.sent", "topic", protocol.FrameTopic(frame), "length", len(frame)-protocol.HEADER_SIZE)
+	}
+
+	// ให้ปลายทางประมวลผลก่อนปิดการเชื่อมต่อ
+	sleepOrDone(ctx, *hold)
+	return EXIT_OK
+}
+
+// เชื่อมต่อไปยัง connect ถ้ากำหนดไว้ ไม่เช่นนั้นรอ client รายแรกที่ listen
+func sendConnect(ctx context.Context, listen, connect string) (net.Conn, error) {
+	if connect != "" {
+		dialer := net.Dialer{Timeout: WATCH_CONNECT_TIMEOUT}
+		return dialer.DialContext(ctx, "tcp", connect)
+	}
+
+	listener, err := net.Listen("tcp", listen)
+	if err != nil {
+		return nil, err
+	}
+	defer listener.Close()
+
+	// ปิด listener เมื่อ ctx ถูกยกเลิก เพื่อให้ Accept คืนค่า
+	stop := context.AfterFunc(ctx, func() { listener.Close() })
+	defer stop()
+
+	logSend.Info("send.listening", "addr", listener.Addr().String())
+	return listener.Accept()
+}
+
+// อ่านเอกสาร XML ทั้งหมดจากไฟล์ ("-" = stdin) แยกด้วย <?xml
+func readXMLDocuments(path string) ([]string, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []string
+	for _, part := range strings.Split(string(data), "<?xml") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		doc := "<?xml" + strings.TrimRightFunc(part, unicode.IsSpace)
+		if xmlTopic(doc) == 0 {
+			return nil, fmt.Errorf("ไม่รู้จัก root element ของเอกสารที่ %d (ต้องเป็น SeatActivity หรือ DiscussionActivity)", len(docs)+1)
+		}
+		docs = append(docs, doc)
+	}
+	if len(docs) == 0 {
+		return nil, fmt.Errorf("ไม่พบเอกสาร XML")
+	}
+	return docs, nil
+}
+
+// topic ตาม root element ของ XML (0 = ไม่รู้จัก)
+func xmlTopic(doc string) uint32 {
+	switch {
+	case strings.Contains(doc, "<SeatActivity"):
+		return protocol.TOPIC_SEAT
+	case strings.Contains(doc, "<DiscussionActivity"):
+		return protocol.TOPIC_DISCUSSION
+	}
+	return 0
+}
+
+// รอตามเวลาที่กำหนด คืนค่า false ถ้า ctx ถูกยกเลิกก่อน
+func sleepOrDone(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/ampol-me/phi-DCN/internal/server"
+)
+
+// คำสั่ง serve: จำลอง Bosch DCN server จาก API (หรือ mock)
+func runServe(ctx context.Context, args []string) int {
+	fs := newFlagSet("serve", "[options]", "ดึงสถานะไมค์จาก API แล้วส่ง SeatActivity และ DiscussionActivity ไปยัง clients ทาง TCP")
+	listen := fs.String("listen", server.LISTEN_ADDR, "ที่อยู่ TCP ที่รอ clients เชื่อมต่อ")
+	admin := fs.String("admin", server.ADMIN_ADDR, "ที่อยู่ของ admin HTTP API (ว่าง = ไม่เปิด)")
+	ws := fs.String("ws", "", "ที่อยู่ HTTP ที่ส่ง frame เดียวกับ TCP ผ่าน WebSocket ที่ /ws/frames?seats=&topics= (ว่าง = ไม่เปิด)")
+	api := fs.String("api", server.API_URL, "endpoint รายการ speakers ของ API จริง")
+	apiSID := fs.String("api-sid", "", "ค่า header Bosch-Sid ของ session (ว่าง = อ่านจาก environment DCN_API_SID)")
+	mock := fs.Bool("mock", true, "ใช้ข้อมูล mock แทน API จริง (-mock=false = ดึงจาก -api)")
+	healthDegradedAfter := fs.Duration("health-degraded-after", 5*time.Second, "รายงาน degraded เมื่อไม่มี poll สำเร็จนานเกินนี้ (0 = ปิด)")
+	healthFailAfter := fs.Duration("health-fail-after", 30*time.Second, "รายงาน fail เมื่อไม่มี poll สำเร็จนานเกินนี้ (0 = ปิด)")
+	journal := addJournalFlags(fs, "ส่งออก")
+	logs := addLogFlags(fs, "framing, upstream, clients, admin, journal, main")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 0 {
+		return usageError(fs, "ไม่รับ argument %q", fs.Arg(0))
+	}
+	if *apiSID == "" {
+		*apiSID = os.Getenv("DCN_API_SID")
+	}
+	if !*mock && *apiSID == "" {
+		return usageError(fs, "-mock=false ต้องกำหนด -api-sid หรือ environment DCN_API_SID")
+	}
+	if !logs.setup() {
+		return EXIT_USAGE
+	}
+
+	err := server.Run(ctx, server.Config{
+		ListenAddr: *listen,
+		AdminAddr:  *admin,
+		WSAddr:     *ws,
+		Health: server.HealthThresholds{
+			DegradedAfter: *healthDegradedAfter,
+			FailAfter:     *healthFailAfter,
+		},
+		API:     server.APIConfig{URL: *api, SID: *apiSID, Mock: *mock},
+		Journal: journal.options(),
+	})
+	if err != nil {
+		return EXIT_FAILURE
+	}
+	return EXIT_OK
+}
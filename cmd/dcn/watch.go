@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"time"
+
+	"github.com/ampol-me/phi-DCN/internal/logging"
+	"github.com/ampol-me/phi-DCN/protocol"
+)
+
+const (
+	WATCH_ADDR            = "localhost:20001" // proxy บนเครื่องเดียวกัน
+	WATCH_CONNECT_TIMEOUT = 5 * time.Second
+	WATCH_RETRY_DELAY     = 2 * time.Second
+)
+
+var logWatch = logging.New("watch")
+
+// คำสั่ง watch: เชื่อมต่อ DCN server หรือ proxy แล้วแสดง frame ที่ได้รับ
+// หยุดด้วย SIGINT/SIGTERM ได้ exit code 0 ถ้าการเชื่อมต่อหลุดโดยไม่ได้ระบุ -reconnect ได้ exit code 1
+func runWatch(ctx context.Context, args []string) int {
+	fs := newFlagSet("watch", "[options] [host:port]",
+		"เชื่อมต่อ DCN server หรือ proxy (ค่าเริ่มต้น "+WATCH_ADDR+") แล้วแสดงทุก frame ที่ได้รับจนกว่าจะหยุดด้วย Ctrl+C\nlog เขียนออก stderr เพื่อไม่ให้ปนกับผลลัพธ์")
+	asJSON := fs.Bool("json", false, "แสดงผลเป็น JSON Lines")
+	showXML := fs.Bool("xml", false, "แสดง XML ที่จัดรูปแบบแล้วของแต่ละ frame (ใน JSON จะมี xml เสมอ)")
+	reconnect := fs.Bool("reconnect", false, "เชื่อมต่อใหม่เมื่อการเชื่อมต่อหลุดหรือเชื่อมต่อไม่สำเร็จ")
+	logs := addLogFlags(fs, "watch")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 1 {
+		return usageError(fs, "ระบุที่อยู่ได้เพียงหนึ่งรายการ")
+	}
+	addr := WATCH_ADDR
+	if fs.NArg() == 1 {
+		addr = fs.Arg(0)
+	}
+
+	logging.Output = os.Stderr
+	if !logs.setup() {
+		return EXIT_USAGE
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	enc := json.NewEncoder(out)
+	enc.SetEscapeHTML(false)
+	show := func(frame DecodedFrame) {
+		if *asJSON {
+			enc.Encode(frame)
+		} else {
+			printFrame(out, frame, *showXML)
+		}
+		out.Flush()
+	}
+
+	dialer := net.Dialer{Timeout: WATCH_CONNECT_TIMEOUT}
+	for {
+		logWatch.Info("watch.connecting", "addr", addr)
+		conn, err := dialer.DialContext(ctx, "tcp", addr)
+		if err == nil {
+			err = watchConnection(ctx, conn, show)
+		}
+		if ctx.Err() != nil {
+			return EXIT_OK
+		}
+		if !*reconnect {
+			logWatch.Error("watch.failed", "addr", addr, "error", err)
+			return EXIT_FAILURE
+		}
+		logWatch.Warn("watch.failed", "addr", addr, "error", err, "retry_in", WATCH_RETRY_DELAY.String())
+		if !sleepOrDone(ctx, WATCH_RETRY_DELAY) {
+			return EXIT_OK
+		}
+	}
+}
+
+// อ่าน frame จากการเชื่อมต่อจนกว่าจะหลุดหรือ ctx ถูกยกเลิก
+func watchConnection(ctx context.Context, conn net.Conn, show func(DecodedFrame)) error {
+	defer conn.Close()
+
+	// ปิดการเชื่อมต่อเมื่อ ctx ถูกยกเลิก เพื่อให้ Read คืนค่า
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	source := conn.RemoteAddr().String()
+	logWatch.Info("watch.connected", "addr", source)
+
+	decoder := &protocol.FrameDecoder{}
+	buffer := make([]byte, 4096)
+	for {
+		n, err := conn.Read(buffer)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		for _, data := range decoder.Feed(buffer[:n]) {
+			frame := decodeFrame(source, data)
+			frame.Time = now.Format(time.RFC3339Nano)
+			show(frame)
+		}
+	}
+}
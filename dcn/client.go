@@ -0,0 +1,259 @@
+// Package dcn เป็น client สำหรับรับสถานะไมค์และที่นั่งจาก Bosch DCN (หรือ proxy ของ DCN)
+//
+// Dial เชื่อมต่อแล้วถอดรหัส frame เป็นเหตุการณ์ที่มีชนิดชัดเจน (ไมค์เปิด/ปิด, ActiveList เปลี่ยน,
+// ที่นั่งอัปเดต) พร้อมเก็บสถานะที่นั่งล่าสุดไว้ในหน่วยความจำ และเชื่อมต่อใหม่อัตโนมัติเมื่อการเชื่อมต่อหลุด
+//
+//	c, err := dcn.Dial(ctx, "localhost:20001", dcn.Options{})
+//	if err != nil {
+//		return err
+//	}
+//	defer c.Close()
+//	for ev := range c.Events() {
+//		if ev.Type == dcn.EVENT_MIC_ON {
+//			fmt.Println("กำลังพูด:", ev.Seat.Name)
+//		}
+//	}
+package dcn
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/ampol-me/phi-DCN/protocol"
+)
+
+// ค่าเริ่มต้นของ Options
+const (
+	DEFAULT_CONNECT_TIMEOUT     = 5 * time.Second  // timeout การเชื่อมต่อแต่ละครั้ง
+	DEFAULT_RECONNECT_DELAY     = time.Second      // เวลารอก่อนเชื่อมต่อใหม่ครั้งแรก
+	DEFAULT_RECONNECT_MAX_DELAY = 30 * time.Second // เวลารอสูงสุดระหว่างการเชื่อมต่อใหม่
+	DEFAULT_EVENT_BUFFER        = 64               // ขนาดคิวของ Events()
+)
+
+// ตัวเลือกของ Dial ค่าศูนย์ใช้ค่าเริ่มต้น
+type Options struct {
+	ConnectTimeout    time.Duration
+	ReconnectDelay    time.Duration // เวลารอเริ่มต้น เพิ่มเป็นสองเท่าทุกครั้งที่เชื่อมต่อไม่สำเร็จ
+	ReconnectMaxDelay time.Duration
+	NoReconnect       bool // ไม่เชื่อมต่อใหม่ client ปิดตัวเองเมื่อการเชื่อมต่อหลุด
+
+	// ถ้ากำหนดไว้ จะเรียกฟังก์ชันนี้แทนการส่งเข้า Events() โดยเรียกจาก goroutine เดียวตามลำดับเหตุการณ์
+	// ฟังก์ชันที่ทำงานนานจะหน่วงการอ่านข้อมูลจาก DCN
+	OnEvent func(Event)
+
+	EventBuffer int // ขนาดคิวของ Events() เมื่อคิวเต็มจะหยุดอ่านข้อมูลจนกว่าผู้ใช้จะรับเหตุการณ์
+}
+
+// Client เชื่อมต่อกับ DCN และติดตามสถานะที่นั่ง
+type Client struct {
+	addr   string
+	opts   Options
+	dialer net.Dialer
+	state  *State
+	events chan Event
+
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu        sync.Mutex
+	connected bool
+	err       error
+}
+
+// เชื่อมต่อกับ DCN ที่ addr และเริ่มรับเหตุการณ์
+//
+// การเชื่อมต่อครั้งแรกต้องสำเร็จ ไม่เช่นนั้นจะคืนค่า error หลังจากนั้นจะเชื่อมต่อใหม่อัตโนมัติ
+// client ทำงานจนกว่าจะเรียก Close หรือ ctx ถูกยกเลิก
+func Dial(ctx context.Context, addr string, opts Options) (*Client, error) {
+	if opts.ConnectTimeout <= 0 {
+		opts.ConnectTimeout = DEFAULT_CONNECT_TIMEOUT
+	}
+	if opts.ReconnectDelay <= 0 {
+		opts.ReconnectDelay = DEFAULT_RECONNECT_DELAY
+	}
+	if opts.ReconnectMaxDelay <= 0 {
+		opts.ReconnectMaxDelay = DEFAULT_RECONNECT_MAX_DELAY
+	}
+	if opts.EventBuffer <= 0 {
+		opts.EventBuffer = DEFAULT_EVENT_BUFFER
+	}
+
+	c := &Client{
+		addr:   addr,
+		opts:   opts,
+		dialer: net.Dialer{Timeout: opts.ConnectTimeout},
+		state:  NewState(),
+		events: make(chan Event, opts.EventBuffer),
+		done:   make(chan struct{}),
+	}
+
+	conn, err := c.dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, c.cancel = context.WithCancel(ctx)
+	go c.run(ctx, conn)
+	return c, nil
+}
+
+// ช่องทางรับเหตุการณ์ ถูกปิดเมื่อ client หยุดทำงาน (ไม่มีเหตุการณ์เมื่อกำหนด Options.OnEvent)
+func (c *Client) Events() <-chan Event {
+	return c.events
+}
+
+// ปิดการเชื่อมต่อและรอจนกว่า client หยุดทำงาน
+func (c *Client) Close() error {
+	c.cancel()
+	<-c.done
+	return nil
+}
+
+// ช่องทางที่ถูกปิดเมื่อ client หยุดทำงาน
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// สาเหตุที่ client หยุดทำงาน nil ถ้ายังทำงานอยู่หรือถูกปิดด้วย Close/ctx
+func (c *Client) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// สถานะการเชื่อมต่อปัจจุบัน
+func (c *Client) Connected() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.connected
+}
+
+// สถานะที่นั่งที่ client ติดตามอยู่
+func (c *Client) State() *State {
+	return c.state
+}
+
+// สำเนาสถานะของทุกที่นั่ง เรียงตาม ID
+func (c *Client) Seats() []Seat {
+	return c.state.Seats()
+}
+
+// สำเนาสถานะของที่นั่งตาม ID
+func (c *Client) Seat(id string) (Seat, bool) {
+	return c.state.Seat(id)
+}
+
+// ที่นั่งใน ActiveList ล่าสุดตามลำดับ
+func (c *Client) Active() []Seat {
+	return c.state.Active()
+}
+
+// รับข้อมูลจากการเชื่อมต่อปัจจุบันและเชื่อมต่อใหม่จนกว่า ctx จะถูกยกเลิก
+func (c *Client) run(ctx context.Context, conn net.Conn) {
+	defer close(c.done)
+	defer close(c.events)
+
+	delay := c.opts.ReconnectDelay
+	for {
+		err := c.serve(ctx, conn)
+		if ctx.Err() != nil {
+			return
+		}
+		if c.opts.NoReconnect {
+			c.mu.Lock()
+			c.err = err
+			c.mu.Unlock()
+			return
+		}
+
+		for {
+			if !sleepContext(ctx, delay) {
+				return
+			}
+			conn, err = c.dialer.DialContext(ctx, "tcp", c.addr)
+			if err == nil {
+				delay = c.opts.ReconnectDelay
+				break
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			delay = min(delay*2, c.opts.ReconnectMaxDelay)
+		}
+	}
+}
+
+// อ่านและถอดรหัส frame จากการเชื่อมต่อเดียวจนกว่าจะหลุด
+//
+// frame ที่ถอดรหัส XML ไม่ได้จะถูกข้าม เมื่อการเชื่อมต่อหลุดจะถือว่าไมค์ทุกตัวปิด
+func (c *Client) serve(ctx context.Context, conn net.Conn) error {
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	addr := conn.RemoteAddr().String()
+	c.setConnected(true)
+	c.emit(ctx, Event{Type: EVENT_CONNECTED, Time: time.Now(), Addr: addr})
+
+	decoder := &protocol.FrameDecoder{}
+	buffer := make([]byte, 4096)
+	var err error
+	for {
+		var n int
+		n, err = conn.Read(buffer)
+		if err != nil {
+			break
+		}
+		for _, frame := range decoder.Feed(buffer[:n]) {
+			topic := protocol.FrameTopic(frame)
+			events, _ := c.state.Apply(topic, protocol.DecodePayload(frame[protocol.HEADER_SIZE:]), time.Now())
+			for _, ev := range events {
+				c.emit(ctx, ev)
+			}
+		}
+	}
+
+	c.setConnected(false)
+	if ctx.Err() != nil {
+		return nil
+	}
+	now := time.Now()
+	c.emit(ctx, Event{Type: EVENT_DISCONNECTED, Time: now, Addr: addr, Err: err})
+	for _, ev := range c.state.Reset(now) {
+		c.emit(ctx, ev)
+	}
+	return err
+}
+
+// ส่งเหตุการณ์ให้ผู้ใช้ คืนค่าเมื่อส่งสำเร็จหรือ ctx ถูกยกเลิก
+func (c *Client) emit(ctx context.Context, ev Event) {
+	if c.opts.OnEvent != nil {
+		c.opts.OnEvent(ev)
+		return
+	}
+	select {
+	case c.events <- ev:
+	case <-ctx.Done():
+	}
+}
+
+func (c *Client) setConnected(connected bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.connected = connected
+}
+
+// รอตามเวลาที่กำหนด คืนค่า false ถ้า ctx ถูกยกเลิกก่อน
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,379 @@
+package dcn
+
+import (
+	"encoding/xml"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ampol-me/phi-DCN/protocol"
+)
+
+// ชนิดของเหตุการณ์
+type EventType int
+
+const (
+	EVENT_CONNECTED           EventType = iota + 1 // เชื่อมต่อ DCN สำเร็จ
+	EVENT_DISCONNECTED                             // การเชื่อมต่อหลุด
+	EVENT_SEAT_UPDATED                             // ได้รับ SeatActivity ของที่นั่ง
+	EVENT_MIC_ON                                   // ไมค์ของที่นั่งเปิด
+	EVENT_MIC_OFF                                  // ไมค์ของที่นั่งปิด
+	EVENT_ACTIVE_LIST_CHANGED                      // รายชื่อผู้พูดใน ActiveList เปลี่ยน
+	EVENT_INCONSISTENT                             // SeatActivity และ DiscussionActivity รายงานไม่ตรงกัน
+)
+
+// สาเหตุของ EVENT_INCONSISTENT
+const (
+	INCONSISTENT_MIC_ON_NOT_ACTIVE = "mic_on_not_active" // SeatActivity บอกว่าเปิดไมค์ แต่ไม่อยู่ใน ActiveList
+	INCONSISTENT_ACTIVE_MIC_OFF    = "active_mic_off"    // อยู่ใน ActiveList แต่ SeatActivity บอกว่าปิดไมค์
+	INCONSISTENT_NAME              = "name_mismatch"     // ชื่อที่นั่งในสอง topic ไม่ตรงกัน
+)
+
+// ชื่อของชนิดเหตุการณ์สำหรับ log และ JSON
+func (t EventType) String() string {
+	switch t {
+	case EVENT_CONNECTED:
+		return "connected"
+	case EVENT_DISCONNECTED:
+		return "disconnected"
+	case EVENT_SEAT_UPDATED:
+		return "seat_updated"
+	case EVENT_MIC_ON:
+		return "mic_on"
+	case EVENT_MIC_OFF:
+		return "mic_off"
+	case EVENT_ACTIVE_LIST_CHANGED:
+		return "active_list_changed"
+	case EVENT_INCONSISTENT:
+		return "inconsistent"
+	}
+	return "unknown"
+}
+
+// ข้อมูลผู้เข้าร่วมประชุมที่นั่งอยู่ที่ที่นั่ง
+type Participant struct {
+	ID         string `json:"id"`
+	FirstName  string `json:"firstName,omitempty"`
+	MiddleName string `json:"middleName,omitempty"`
+	LastName   string `json:"lastName,omitempty"`
+	Title      string `json:"title,omitempty"`
+	Country    string `json:"country,omitempty"`
+	Present    bool   `json:"present"`
+}
+
+// สถานะของที่นั่งหนึ่งที่
+type Seat struct {
+	ID          string       `json:"id"`
+	Name        string       `json:"name"`
+	MicOn       bool         `json:"micOn"`
+	SeatType    string       `json:"seatType,omitempty"`
+	Participant *Participant `json:"participant,omitempty"` // nil จนกว่าจะได้รับ SeatActivity ของที่นั่งนี้
+	Updated     time.Time    `json:"updated"`               // เวลาที่ได้รับ frame ที่ทำให้สถานะไมค์เปลี่ยนครั้งล่าสุด
+	TimeStamp   time.Time    `json:"timestamp"`             // TimeStamp ของ DCN ใน frame เดียวกับ Updated
+	LastSeen    time.Time    `json:"lastSeen"`              // เวลาที่ได้รับ frame ที่กล่าวถึงที่นั่งนี้ครั้งล่าสุด
+}
+
+// ข้อมูลของ DiscussionActivity ล่าสุด
+type Discussion struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	TimeStamp time.Time `json:"timestamp"` // TimeStamp ของ DCN ใน frame ล่าสุด
+	Updated   time.Time `json:"updated"`   // เวลาที่ได้รับ frame ล่าสุด
+	Changed   time.Time `json:"changed"`   // เวลาที่ ActiveList เปลี่ยนครั้งล่าสุด
+	Active    []Seat    `json:"active"`    // ที่นั่งใน ActiveList ตามลำดับ
+}
+
+// เหตุการณ์ที่ได้จากการถอดรหัส frame หรือจากการเชื่อมต่อ
+type Event struct {
+	Type   EventType
+	Time   time.Time
+	Seat   Seat   // EVENT_SEAT_UPDATED, EVENT_MIC_ON, EVENT_MIC_OFF, EVENT_INCONSISTENT
+	Active []Seat // EVENT_ACTIVE_LIST_CHANGED เรียงตามลำดับใน ActiveList
+	Addr   string // EVENT_CONNECTED, EVENT_DISCONNECTED
+	Err    error  // EVENT_DISCONNECTED
+	Detail string // EVENT_INCONSISTENT: สาเหตุ (INCONSISTENT_*)
+}
+
+// State เก็บสถานะที่นั่งทั้งหมดที่รวมจาก SeatActivity และ DiscussionActivity
+//
+// ไมค์ของที่นั่งถือว่าเปิดเมื่อ topic ใด topic หนึ่งรายงานว่าเปิดครั้งล่าสุด
+// เหตุการณ์ EVENT_MIC_ON/EVENT_MIC_OFF เกิดเฉพาะเมื่อสถานะเปลี่ยนจริงเท่านั้น จึงไม่ซ้ำกันระหว่างสอง topic
+//
+// DCN ส่ง SeatActivity ก่อน DiscussionActivity ของการเปลี่ยนแปลงเดียวกัน จึงตรวจความสอดคล้องของสอง topic
+// เมื่อได้รับ DiscussionActivity เท่านั้น และแจ้ง EVENT_INCONSISTENT ครั้งเดียวต่อปัญหาของแต่ละที่นั่ง
+// ใช้งานพร้อมกันจากหลาย goroutine ได้
+type State struct {
+	mu     sync.Mutex
+	seats  map[string]*seatRecord
+	active []string // ID ของที่นั่งใน ActiveList ล่าสุดตามลำดับ
+
+	discussion Discussion // ข้อมูลของ DiscussionActivity ล่าสุด (ไม่รวม Active)
+}
+
+// สถานะภายในของที่นั่ง พร้อมค่าล่าสุดที่ SeatActivity รายงานสำหรับตรวจความสอดคล้อง
+type seatRecord struct {
+	Seat
+	reported bool   // เคยได้รับ SeatActivity ของที่นั่งนี้
+	seatMic  bool   // สถานะไมค์ล่าสุดจาก SeatActivity
+	conflict string // ปัญหาความสอดคล้องที่แจ้งไปแล้ว ("" ถ้าไม่มี)
+}
+
+// สร้าง State ว่าง
+func NewState() *State {
+	return &State{seats: make(map[string]*seatRecord)}
+}
+
+// ถอดรหัส XML ของ topic และอัปเดตสถานะ คืนค่าเหตุการณ์ที่เกิดขึ้นตามลำดับ
+//
+// topic ที่ไม่รู้จักจะถูกข้ามโดยไม่มี error
+func (s *State) Apply(topic uint32, xmlStr string, at time.Time) ([]Event, error) {
+	switch topic {
+	case protocol.TOPIC_SEAT:
+		var activity protocol.SeatActivity
+		if err := xml.Unmarshal([]byte(xmlStr), &activity); err != nil {
+			return nil, err
+		}
+		return s.ApplySeat(&activity, at), nil
+	case protocol.TOPIC_DISCUSSION:
+		var activity protocol.DiscussionActivity
+		if err := xml.Unmarshal([]byte(xmlStr), &activity); err != nil {
+			return nil, err
+		}
+		return s.ApplyDiscussion(&activity, at), nil
+	}
+	return nil, nil
+}
+
+// อัปเดตสถานะจาก SeatActivity ที่ถอดรหัสแล้ว
+func (s *State) ApplySeat(activity *protocol.SeatActivity, at time.Time) []Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := activity.Seat.SeatData
+	participant := activity.Seat.Participant
+	stamp, _ := protocol.ParseTimestamp(activity.TimeStamp)
+	seat := s.seat(activity.Seat.ID, at)
+	seat.LastSeen = at
+	seat.reported = true
+	seat.seatMic = data.MicrophoneActive
+	seat.Name = data.Name
+	seat.SeatType = data.SeatType
+	seat.Participant = &Participant{
+		ID:         participant.ID,
+		FirstName:  participant.ParticipantData.FirstName,
+		MiddleName: participant.ParticipantData.MiddleName,
+		LastName:   participant.ParticipantData.LastName,
+		Title:      participant.ParticipantData.Title,
+		Country:    participant.ParticipantData.Country,
+		Present:    participant.ParticipantData.Present,
+	}
+
+	var events []Event
+	if ev, ok := setMic(seat, data.MicrophoneActive, at, stamp); ok {
+		events = append(events, ev)
+	}
+	return append(events, Event{Type: EVENT_SEAT_UPDATED, Time: at, Seat: copySeat(seat)})
+}
+
+// อัปเดตสถานะจาก DiscussionActivity ที่ถอดรหัสแล้ว
+//
+// ที่นั่งใน ActiveList ถือว่าเปิดไมค์ ที่นั่งที่เปิดอยู่แต่ไม่อยู่ในรายการถือว่าปิดไมค์
+func (s *State) ApplyDiscussion(activity *protocol.DiscussionActivity, at time.Time) []Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stamp, _ := protocol.ParseTimestamp(activity.TimeStamp)
+	s.discussion.ID = activity.Discussion.ID
+	s.discussion.Type = activity.Type
+	s.discussion.TimeStamp = stamp
+	s.discussion.Updated = at
+
+	var events []Event
+	active := []string{}
+	conflicts := make(map[string]string)
+	for _, container := range activity.Discussion.ActiveList.Participants.ParticipantContainers {
+		data := container.Seat.SeatData
+		seat := s.seat(container.Seat.ID, at)
+		seat.LastSeen = at
+		switch {
+		case data.Name != "" && seat.reported && data.Name != seat.Name:
+			conflicts[seat.ID] = INCONSISTENT_NAME
+		case data.Name != "" && !seat.reported:
+			seat.Name = data.Name
+		}
+		if !data.MicrophoneActive || (seat.reported && !seat.seatMic) {
+			conflicts[seat.ID] = INCONSISTENT_ACTIVE_MIC_OFF
+		}
+		if ev, ok := setMic(seat, true, at, stamp); ok {
+			events = append(events, ev)
+		}
+		if !slices.Contains(active, seat.ID) {
+			active = append(active, seat.ID)
+		}
+	}
+	for _, id := range s.sortedIDs() {
+		seat := s.seats[id]
+		if slices.Contains(active, id) {
+			continue
+		}
+		if seat.reported && seat.seatMic {
+			conflicts[id] = INCONSISTENT_MIC_ON_NOT_ACTIVE
+		}
+		if ev, ok := setMic(seat, false, at, stamp); ok {
+			events = append(events, ev)
+		}
+	}
+
+	for _, id := range s.sortedIDs() {
+		seat := s.seats[id]
+		if conflicts[id] == seat.conflict {
+			continue
+		}
+		seat.conflict = conflicts[id]
+		if seat.conflict != "" {
+			events = append(events, Event{Type: EVENT_INCONSISTENT, Time: at, Seat: copySeat(seat), Detail: seat.conflict})
+		}
+	}
+
+	if !slices.Equal(active, s.active) {
+		s.active = active
+		s.discussion.Changed = at
+		events = append(events, Event{Type: EVENT_ACTIVE_LIST_CHANGED, Time: at, Active: s.activeSeats()})
+	}
+	return events
+}
+
+// ปิดไมค์ทุกที่นั่งและล้าง ActiveList เมื่อไม่รู้สถานะจริง เช่นระหว่างการเชื่อมต่อหลุด
+func (s *State) Reset(at time.Time) []Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var events []Event
+	for _, id := range s.sortedIDs() {
+		seat := s.seats[id]
+		seat.seatMic = false
+		seat.conflict = ""
+		if ev, ok := setMic(seat, false, at, time.Time{}); ok {
+			events = append(events, ev)
+		}
+	}
+	if len(s.active) > 0 {
+		s.active = nil
+		s.discussion.Changed = at
+		events = append(events, Event{Type: EVENT_ACTIVE_LIST_CHANGED, Time: at, Active: []Seat{}})
+	}
+	return events
+}
+
+// สำเนาสถานะของทุกที่นั่ง เรียงตาม ID
+func (s *State) Seats() []Seat {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seats := make([]Seat, 0, len(s.seats))
+	for _, id := range s.sortedIDs() {
+		seats = append(seats, copySeat(s.seats[id]))
+	}
+	return seats
+}
+
+// สำเนาสถานะของที่นั่งตาม ID
+func (s *State) Seat(id string) (Seat, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seat, ok := s.seats[id]
+	if !ok {
+		return Seat{}, false
+	}
+	return copySeat(seat), true
+}
+
+// สำเนาที่นั่งใน ActiveList ล่าสุดตามลำดับ
+func (s *State) Active() []Seat {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.activeSeats()
+}
+
+// ข้อมูลของ DiscussionActivity ล่าสุดพร้อมที่นั่งใน ActiveList
+func (s *State) Discussion() Discussion {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := s.discussion
+	d.Active = s.activeSeats()
+	return d
+}
+
+// ที่นั่งตาม ID สร้างใหม่ถ้ายังไม่มี (ต้องถือ mu อยู่)
+func (s *State) seat(id string, at time.Time) *seatRecord {
+	seat, ok := s.seats[id]
+	if !ok {
+		seat = &seatRecord{Seat: Seat{ID: id, Updated: at}}
+		s.seats[id] = seat
+	}
+	return seat
+}
+
+// ID ของทุกที่นั่ง เรียงตามตัวเลขถ้าเป็นตัวเลข (ต้องถือ mu อยู่)
+func (s *State) sortedIDs() []string {
+	ids := make([]string, 0, len(s.seats))
+	for id := range s.seats {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return lessID(ids[i], ids[j]) })
+	return ids
+}
+
+// สำเนาที่นั่งใน ActiveList (ต้องถือ mu อยู่)
+func (s *State) activeSeats() []Seat {
+	seats := make([]Seat, 0, len(s.active))
+	for _, id := range s.active {
+		seats = append(seats, copySeat(s.seats[id]))
+	}
+	return seats
+}
+
+// เปลี่ยนสถานะไมค์ คืนค่าเหตุการณ์และ true เมื่อสถานะเปลี่ยนจริง
+func setMic(seat *seatRecord, on bool, at, stamp time.Time) (Event, bool) {
+	if seat.MicOn == on {
+		return Event{}, false
+	}
+	seat.MicOn = on
+	seat.Updated = at
+	seat.TimeStamp = stamp
+
+	ev := Event{Type: EVENT_MIC_OFF, Time: at, Seat: copySeat(seat)}
+	if on {
+		ev.Type = EVENT_MIC_ON
+	}
+	return ev, true
+}
+
+// สำเนาของที่นั่งที่ไม่แชร์ Participant กับสถานะภายใน
+func copySeat(seat *seatRecord) Seat {
+	c := seat.Seat
+	if seat.Participant != nil {
+		participant := *seat.Participant
+		c.Participant = &participant
+	}
+	return c
+}
+
+// เปรียบเทียบ ID ที่นั่ง ตัวเลขเรียงตามค่า ที่ไม่ใช่ตัวเลขเรียงตามตัวอักษรและอยู่หลังตัวเลข
+func lessID(a, b string) bool {
+	na, errA := strconv.Atoi(a)
+	nb, errB := strconv.Atoi(b)
+	switch {
+	case errA == nil && errB == nil:
+		return na < nb
+	case errA == nil:
+		return true
+	case errB == nil:
+		return false
+	}
+	return a < b
+}
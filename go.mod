@@ -0,0 +1,3 @@
+module github.com/ampol-me/phi-DCN
+
+go 1.22
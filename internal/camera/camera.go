@@ -0,0 +1,91 @@
+// Package camera ควบคุมกล้อง PTZ ผ่านเครือข่ายเท่าที่ camera director ต้องใช้ คือเรียกตำแหน่ง preset
+//
+// รองรับ VISCA-over-IP (UDP พอร์ต 52381 ของ Sony และกล้องที่เข้ากันได้) และ HTTP CGI ของ Panasonic AW series
+// หมายเลข preset ที่ส่งให้ driver นับจาก 1 ตามที่แสดงบนกล้องและ remote controller
+// driver แปลงเป็นหมายเลขของแต่ละ protocol เอง (VISCA และ Panasonic นับจาก 0)
+package camera
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ชนิดของกล้อง
+const (
+	TYPE_VISCA     = "visca"
+	TYPE_PANASONIC = "panasonic"
+)
+
+// ค่าเริ่มต้นของ Config
+const (
+	VISCA_PORT          = 52381
+	DEFAULT_ACK_TIMEOUT = 500 * time.Millisecond // เวลารอ ACK ก่อนส่งคำสั่งซ้ำ
+	DEFAULT_TIMEOUT     = 10 * time.Second       // เวลารอจนกล้องทำคำสั่งเสร็จ
+	DEFAULT_RETRIES     = 3                      // จำนวนครั้งที่ส่งคำสั่งซ้ำเมื่อไม่ได้รับ ACK
+)
+
+var ErrTimeout = errors.New("camera: กล้องไม่ตอบภายในเวลาที่กำหนด")
+
+// Driver ควบคุมกล้องหนึ่งตัว เรียกพร้อมกันจากหลาย goroutine ได้ (คำสั่งถูกส่งทีละคำสั่ง)
+type Driver interface {
+	// เรียกตำแหน่ง preset (นับจาก 1) และรอจนกล้องตอบรับหรือ ctx ถูกยกเลิก
+	RecallPreset(ctx context.Context, preset int) error
+	Close() error
+}
+
+// การตั้งค่าของกล้องหนึ่งตัว
+type Config struct {
+	Type       string        // TYPE_VISCA หรือ TYPE_PANASONIC
+	Addr       string        // host:port สำหรับ VISCA (ไม่ระบุพอร์ต = VISCA_PORT), host[:port] หรือ URL สำหรับ Panasonic
+	Username   string        // Panasonic: ใช้ basic authentication ถ้ากำหนด
+	Password   string        //
+	AckTimeout time.Duration // VISCA: 0 = DEFAULT_ACK_TIMEOUT
+	Timeout    time.Duration // 0 = DEFAULT_TIMEOUT
+	Retries    int           // VISCA: 0 = DEFAULT_RETRIES
+}
+
+// สร้าง driver ตามชนิดของกล้อง
+func Open(cfg Config) (Driver, error) {
+	if cfg.Addr == "" {
+		return nil, errors.New("camera: ไม่ได้กำหนดที่อยู่ของกล้อง")
+	}
+	if cfg.AckTimeout <= 0 {
+		cfg.AckTimeout = DEFAULT_ACK_TIMEOUT
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DEFAULT_TIMEOUT
+	}
+	if cfg.Retries <= 0 {
+		cfg.Retries = DEFAULT_RETRIES
+	}
+	switch cfg.Type {
+	case TYPE_VISCA:
+		return dialVISCA(cfg)
+	case TYPE_PANASONIC:
+		return newPanasonic(cfg)
+	default:
+		return nil, fmt.Errorf("camera: ไม่รู้จักชนิดกล้อง %q (ใช้ %s หรือ %s)", cfg.Type, TYPE_VISCA, TYPE_PANASONIC)
+	}
+}
+
+// หมายเลข preset สูงสุดของกล้องชนิดนี้ (0 = ไม่รู้จักชนิดกล้อง)
+func MaxPreset(cameraType string) int {
+	switch cameraType {
+	case TYPE_VISCA:
+		return VISCA_MAX_PRESET
+	case TYPE_PANASONIC:
+		return PANASONIC_MAX_PRESET
+	default:
+		return 0
+	}
+}
+
+// ตรวจสอบช่วงของหมายเลข preset (นับจาก 1)
+func checkPreset(preset, max int) error {
+	if preset < 1 || preset > max {
+		return fmt.Errorf("camera: preset %d อยู่นอกช่วง 1-%d", preset, max)
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package camera
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// นับ packet ที่กล้องจำลองได้รับ (รวม packet ที่ถูกทิ้ง)
+type countingConn struct {
+	net.PacketConn
+	received atomic.Int64
+}
+
+func (c *countingConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	n, addr, err := c.PacketConn.ReadFrom(p)
+	if err == nil {
+		c.received.Add(1)
+	}
+	return n, addr, err
+}
+
+// เปิดกล้อง VISCA จำลองบน UDP port ว่างของ localhost และ driver ที่ต่อกับกล้องนั้น
+func startVISCA(t *testing.T, opts MockOptions, cfg Config) (*Mock, *countingConn, Driver) {
+	t.Helper()
+	packetConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket: %v", err)
+	}
+	conn := &countingConn{PacketConn: packetConn}
+	t.Cleanup(func() { conn.Close() })
+	mock := NewMock(opts)
+	go mock.serveVISCA(conn)
+
+	cfg.Type = TYPE_VISCA
+	cfg.Addr = conn.LocalAddr().String()
+	driver, err := Open(cfg)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { driver.Close() })
+	return mock, conn, driver
+}
+
+// เปิดกล้อง Panasonic จำลองและ driver ที่ต่อกับกล้องนั้น
+func startPanasonic(t *testing.T, opts MockOptions, cfg Config) (*Mock, Driver) {
+	t.Helper()
+	mock := NewMock(opts)
+	server := httptest.NewServer(http.HandlerFunc(mock.handlePanasonic))
+	t.Cleanup(server.Close)
+
+	cfg.Type = TYPE_PANASONIC
+	cfg.Addr = server.URL
+	driver, err := Open(cfg)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { driver.Close() })
+	return mock, driver
+}
+
+func TestVISCARecallPreset(t *testing.T) {
+	mock, _, driver := startVISCA(t, MockOptions{MoveTime: 10 * time.Millisecond}, Config{})
+	for _, preset := range []int{1, 7, VISCA_MAX_PRESET} {
+		if err := driver.RecallPreset(context.Background(), preset); err != nil {
+			t.Fatalf("RecallPreset(%d): %v", preset, err)
+		}
+		if got := mock.Preset(); got != preset {
+			t.Errorf("กล้องอยู่ที่ preset %d ต้องเป็น %d", got, preset)
+		}
+	}
+}
+
+func TestVISCAResendDropped(t *testing.T) {
+	// ทิ้งครึ่งหนึ่งของ packet: driver ต้องส่งซ้ำด้วย sequence เดิมจนกล้องได้รับ
+	cfg := Config{AckTimeout: 20 * time.Millisecond, Timeout: 5 * time.Second, Retries: 50}
+	mock, conn, driver := startVISCA(t, MockOptions{Drop: 0.5}, cfg)
+
+	const recalls = 20
+	for i := 1; i <= recalls; i++ {
+		if err := driver.RecallPreset(context.Background(), i); err != nil {
+			t.Fatalf("RecallPreset(%d): %v", i, err)
+		}
+		if got := mock.Preset(); got != i {
+			t.Fatalf("กล้องอยู่ที่ preset %d ต้องเป็น %d", got, i)
+		}
+	}
+	// reset หนึ่งครั้งและคำสั่งละหนึ่ง packet ถ้าไม่มีการส่งซ้ำ
+	if received := conn.received.Load(); received <= recalls+1 {
+		t.Errorf("กล้องได้รับ %d packet ต้องมากกว่า %d เมื่อมีการส่งซ้ำ", received, recalls+1)
+	}
+}
+
+func TestVISCASequenceReset(t *testing.T) {
+	mock, _, driver := startVISCA(t, MockOptions{}, Config{})
+	if err := driver.RecallPreset(context.Background(), 3); err != nil {
+		t.Fatalf("RecallPreset(3): %v", err)
+	}
+
+	// กล้องเปิดเครื่องใหม่: รอ sequence 0 และตอบ sequence error กับคำสั่งถัดไปของ driver
+	mock.mu.Lock()
+	clear(mock.seqs)
+	mock.mu.Unlock()
+
+	if err := driver.RecallPreset(context.Background(), 4); err != nil {
+		t.Fatalf("RecallPreset(4) หลังกล้องเปิดใหม่: %v", err)
+	}
+	if got := mock.Preset(); got != 4 {
+		t.Errorf("กล้องอยู่ที่ preset %d ต้องเป็น 4", got)
+	}
+}
+
+func TestVISCATimeout(t *testing.T) {
+	cfg := Config{AckTimeout: 10 * time.Millisecond, Timeout: 100 * time.Millisecond, Retries: 2}
+	_, conn, driver := startVISCA(t, MockOptions{Drop: 1}, cfg)
+	if err := driver.RecallPreset(context.Background(), 1); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("RecallPreset error = %v ต้องเป็น ErrTimeout", err)
+	}
+	if received := conn.received.Load(); received < 2 {
+		t.Errorf("กล้องได้รับ %d packet ต้องส่งซ้ำอย่างน้อยหนึ่งครั้ง", received)
+	}
+}
+
+func TestPanasonicRecallPreset(t *testing.T) {
+	opts := MockOptions{Username: "admin", Password: "12345"}
+	mock, driver := startPanasonic(t, opts, Config{Username: "admin", Password: "12345"})
+	for _, preset := range []int{1, 42, PANASONIC_MAX_PRESET} {
+		if err := driver.RecallPreset(context.Background(), preset); err != nil {
+			t.Fatalf("RecallPreset(%d): %v", preset, err)
+		}
+		if got := mock.Preset(); got != preset {
+			t.Errorf("กล้องอยู่ที่ preset %d ต้องเป็น %d", got, preset)
+		}
+	}
+}
+
+func TestPanasonicUnauthorized(t *testing.T) {
+	mock, driver := startPanasonic(t, MockOptions{Username: "admin", Password: "12345"}, Config{Username: "admin", Password: "wrong"})
+	err := driver.RecallPreset(context.Background(), 1)
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("RecallPreset error = %v ต้องเป็น 401", err)
+	}
+	if got := mock.Preset(); got != 0 {
+		t.Errorf("กล้องอยู่ที่ preset %d ต้องไม่ถูกเรียก", got)
+	}
+}
+
+func TestPresetRange(t *testing.T) {
+	viscaMock, viscaConn, visca := startVISCA(t, MockOptions{}, Config{})
+	panasonicMock, panasonic := startPanasonic(t, MockOptions{}, Config{})
+	cases := []struct {
+		name   string
+		driver Driver
+		mock   *Mock
+		preset int
+	}{
+		{TYPE_VISCA, visca, viscaMock, 0},
+		{TYPE_VISCA, visca, viscaMock, VISCA_MAX_PRESET + 1},
+		{TYPE_PANASONIC, panasonic, panasonicMock, 0},
+		{TYPE_PANASONIC, panasonic, panasonicMock, PANASONIC_MAX_PRESET + 1},
+	}
+	for _, c := range cases {
+		err := c.driver.RecallPreset(context.Background(), c.preset)
+		if err == nil || !strings.Contains(err.Error(), "นอกช่วง") {
+			t.Errorf("%s: RecallPreset(%d) error = %v ต้องแจ้งว่าอยู่นอกช่วง", c.name, c.preset, err)
+		}
+		if got := c.mock.Preset(); got != 0 {
+			t.Errorf("%s: RecallPreset(%d) เรียก preset %d ของกล้อง", c.name, c.preset, got)
+		}
+	}
+	// ตรวจสอบช่วงก่อนส่ง จึงไม่มี packet ถึงกล้อง
+	if received := viscaConn.received.Load(); received != 0 {
+		t.Errorf("กล้อง VISCA ได้รับ %d packet ต้องไม่ได้รับเลย", received)
+	}
+}
+
+func TestMaxPreset(t *testing.T) {
+	for cameraType, want := range map[string]int{TYPE_VISCA: VISCA_MAX_PRESET, TYPE_PANASONIC: PANASONIC_MAX_PRESET, "ndi": 0} {
+		if got := MaxPreset(cameraType); got != want {
+			t.Errorf("MaxPreset(%q) = %d ต้องเป็น %d", cameraType, got, want)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package camera
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ampol-me/phi-DCN/internal/logging"
+)
+
+var logCamera = logging.New("camera")
+
+// การตั้งค่าของกล้องจำลอง
+type MockOptions struct {
+	VISCAAddr string        // ที่อยู่ UDP ที่รับคำสั่ง VISCA-over-IP (ว่าง = ไม่เปิด)
+	HTTPAddr  string        // ที่อยู่ HTTP ที่รับคำสั่ง Panasonic aw_ptz (ว่าง = ไม่เปิด)
+	MoveTime  time.Duration // เวลาที่ใช้เคลื่อนไปยัง preset ก่อนส่ง VISCA completion
+	Drop      float64       // สัดส่วนของ packet VISCA ที่ทิ้งโดยไม่ตอบ (0-1) สำหรับทดสอบการส่งซ้ำ
+	Username  string        // ต้องใช้ basic authentication กับ HTTP ถ้ากำหนด
+	Password  string
+}
+
+// Mock คือกล้องจำลองที่รับคำสั่งเรียก preset ทั้งแบบ VISCA-over-IP และ Panasonic HTTP
+// และจำ preset ล่าสุดไว้
+type Mock struct {
+	opts MockOptions
+
+	mu     sync.Mutex
+	preset int                   // preset ล่าสุด (นับจาก 1, 0 = ยังไม่เคยเรียก)
+	seqs   map[string]viscaState // สถานะ sequence ของแต่ละ client
+}
+
+type viscaState struct {
+	expected uint32 // sequence number ที่รอ
+	lastSeq  uint32 // sequence number ของคำสั่งล่าสุด สำหรับตอบคำสั่งที่ส่งซ้ำ
+	valid    bool   // มีคำสั่งล่าสุดแล้ว
+}
+
+func NewMock(opts MockOptions) *Mock {
+	return &Mock{opts: opts, seqs: make(map[string]viscaState)}
+}
+
+// preset ล่าสุดที่ถูกเรียก
+func (m *Mock) Preset() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.preset
+}
+
+// รับคำสั่งจนกว่า ctx จะถูกยกเลิก
+func (m *Mock) Serve(ctx context.Context) error {
+	if m.opts.VISCAAddr == "" && m.opts.HTTPAddr == "" {
+		return errors.New("camera: ต้องเปิด VISCA หรือ HTTP อย่างน้อยหนึ่งอย่าง")
+	}
+
+	errs := make(chan error, 2)
+	if m.opts.VISCAAddr != "" {
+		conn, err := net.ListenPacket("udp", m.opts.VISCAAddr)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		logCamera.Info("camera.mock_listening", "protocol", TYPE_VISCA, "addr", conn.LocalAddr().String())
+		go m.serveVISCA(conn)
+	}
+	if m.opts.HTTPAddr != "" {
+		listener, err := net.Listen("tcp", m.opts.HTTPAddr)
+		if err != nil {
+			return err
+		}
+		mux := http.NewServeMux()
+		mux.HandleFunc("GET "+PANASONIC_PTZ_PATH, m.handlePanasonic)
+		server := &http.Server{Handler: mux}
+		defer server.Close()
+		logCamera.Info("camera.mock_listening", "protocol", TYPE_PANASONIC, "addr", listener.Addr().String())
+		go func() {
+			if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+				errs <- err
+			}
+		}()
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil
+	case err := <-errs:
+		return err
+	}
+}
+
+func (m *Mock) serveVISCA(conn net.PacketConn) {
+	buf := make([]byte, 256)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		if n < VISCA_HEADER_SIZE || m.opts.Drop > 0 && rand.Float64() < m.opts.Drop {
+			continue
+		}
+		payloadType := binary.BigEndian.Uint16(buf[0:])
+		seq := binary.BigEndian.Uint32(buf[4:])
+		payload := buf[VISCA_HEADER_SIZE:n]
+
+		reply := func(replyType uint16, data ...byte) {
+			packet := make([]byte, VISCA_HEADER_SIZE+len(data))
+			binary.BigEndian.PutUint16(packet[0:], replyType)
+			binary.BigEndian.PutUint16(packet[2:], uint16(len(data)))
+			binary.BigEndian.PutUint32(packet[4:], seq)
+			copy(packet[VISCA_HEADER_SIZE:], data)
+			conn.WriteTo(packet, addr)
+		}
+
+		m.mu.Lock()
+		state := m.seqs[addr.String()]
+		switch {
+		case payloadType == VISCA_CONTROL && len(payload) == 1 && payload[0] == VISCA_CONTROL_RESET:
+			m.seqs[addr.String()] = viscaState{}
+			m.mu.Unlock()
+			reply(VISCA_CONTROL_REPLY, VISCA_CONTROL_RESET)
+			continue
+		case payloadType != VISCA_COMMAND:
+			m.mu.Unlock()
+			reply(VISCA_CONTROL_REPLY, VISCA_CONTROL_ERROR, 0x02)
+			continue
+		case state.valid && seq == state.lastSeq:
+			// คำสั่งที่ส่งซ้ำเพราะไม่ได้รับ ACK: ตอบอีกครั้งโดยไม่ทำคำสั่งซ้ำ
+			m.mu.Unlock()
+			m.acknowledge(reply)
+			continue
+		case seq != state.expected:
+			m.mu.Unlock()
+			reply(VISCA_CONTROL_REPLY, VISCA_CONTROL_ERROR, VISCA_ERROR_SEQUENCE)
+			continue
+		}
+		m.seqs[addr.String()] = viscaState{expected: seq + 1, lastSeq: seq, valid: true}
+
+		// รองรับเฉพาะ CAM_Memory Recall: 81 01 04 3F 02 pp FF
+		if len(payload) != 7 || payload[0] != VISCA_CAMERA_ADDRESS || !strings.HasPrefix(string(payload[1:5]), "\x01\x04\x3F\x02") || payload[6] != VISCA_MESSAGE_TRAILER || payload[5] == VISCA_MESSAGE_TRAILER {
+			m.mu.Unlock()
+			reply(VISCA_REPLY, VISCA_REPLY_ADDRESS, VISCA_REPLY_ERROR, 0x02, VISCA_MESSAGE_TRAILER)
+			continue
+		}
+		preset := int(payload[5]) + 1
+		m.preset = preset
+		m.mu.Unlock()
+
+		logCamera.Info("camera.mock_recalled", "protocol", TYPE_VISCA, "preset", preset, "client", addr.String())
+		m.acknowledge(reply)
+	}
+}
+
+// ตอบ ACK ทันทีและ completion หลังเคลื่อนกล้องเสร็จ (socket 1)
+func (m *Mock) acknowledge(reply func(uint16, ...byte)) {
+	reply(VISCA_REPLY, VISCA_REPLY_ADDRESS, VISCA_REPLY_ACK|1, VISCA_MESSAGE_TRAILER)
+	time.AfterFunc(m.opts.MoveTime, func() {
+		reply(VISCA_REPLY, VISCA_REPLY_ADDRESS, VISCA_REPLY_COMPLETE|1, VISCA_MESSAGE_TRAILER)
+	})
+}
+
+// GET /cgi-bin/aw_ptz?cmd=#Rnn&res=1
+func (m *Mock) handlePanasonic(w http.ResponseWriter, r *http.Request) {
+	if m.opts.Username != "" {
+		username, password, ok := r.BasicAuth()
+		if !ok || username != m.opts.Username || password != m.opts.Password {
+			w.Header().Set("WWW-Authenticate", `Basic realm="mock camera"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	cmd := r.URL.Query().Get("cmd")
+	number, ok := strings.CutPrefix(cmd, "#R")
+	if !ok {
+		fmt.Fprint(w, "E1")
+		return
+	}
+	n, err := strconv.Atoi(number)
+	if err != nil || len(number) != 2 || n >= PANASONIC_MAX_PRESET {
+		fmt.Fprint(w, "E3")
+		return
+	}
+
+	m.mu.Lock()
+	m.preset = n + 1
+	m.mu.Unlock()
+	logCamera.Info("camera.mock_recalled", "protocol", TYPE_PANASONIC, "preset", n+1, "client", r.RemoteAddr)
+	fmt.Fprint(w, "s"+number)
+}
@@ -0,0 +1,207 @@
+package camera
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ชนิด payload ใน header ของ VISCA-over-IP (8 bytes: type u16, length u16, sequence u32 แบบ big-endian)
+const (
+	VISCA_COMMAND         = 0x0100
+	VISCA_INQUIRY         = 0x0110
+	VISCA_REPLY           = 0x0111
+	VISCA_CONTROL         = 0x0200
+	VISCA_CONTROL_REPLY   = 0x0201
+	VISCA_HEADER_SIZE     = 8
+	VISCA_MAX_PRESET      = 255 // preset 1-255 = 0x00-0xFE ในคำสั่ง
+	VISCA_REPLY_ACK       = 0x40
+	VISCA_REPLY_COMPLETE  = 0x50
+	VISCA_REPLY_ERROR     = 0x60
+	VISCA_CONTROL_RESET   = 0x01
+	VISCA_CONTROL_ERROR   = 0x0F
+	VISCA_ERROR_SEQUENCE  = 0x01 // control reply 0x0F 0x01: sequence number ไม่ตรงกับที่กล้องรอ
+	VISCA_CAMERA_ADDRESS  = 0x81 // กล้องหมายเลข 1 (VISCA-over-IP มีกล้องเดียวต่อที่อยู่)
+	VISCA_REPLY_ADDRESS   = 0x90
+	VISCA_MESSAGE_TRAILER = 0xFF
+)
+
+// ViscaError คือข้อความ error ที่กล้องตอบกลับ (90 6y ee FF)
+type ViscaError struct {
+	Code byte
+}
+
+func (e *ViscaError) Error() string {
+	reasons := map[byte]string{
+		0x01: "ความยาวข้อความไม่ถูกต้อง",
+		0x02: "คำสั่งไม่ถูกต้อง",
+		0x03: "buffer คำสั่งเต็ม",
+		0x04: "คำสั่งถูกยกเลิก",
+		0x05: "ไม่มี socket",
+		0x41: "ไม่สามารถทำคำสั่งได้ในขณะนี้",
+	}
+	if reason, ok := reasons[e.Code]; ok {
+		return "visca: " + reason
+	}
+	return fmt.Sprintf("visca: error 0x%02X", e.Code)
+}
+
+var errViscaSequence = errors.New("visca: sequence number ไม่ตรงกับที่กล้องรอ")
+
+type viscaDriver struct {
+	cfg  Config
+	conn net.Conn
+
+	mu    sync.Mutex
+	seq   uint32
+	reset bool // ต้อง reset sequence number ก่อนส่งคำสั่งถัดไป
+	buf   [256]byte
+}
+
+func dialVISCA(cfg Config) (*viscaDriver, error) {
+	addr := cfg.Addr
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, strconv.Itoa(VISCA_PORT))
+	}
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &viscaDriver{cfg: cfg, conn: conn, reset: true}, nil
+}
+
+// คำสั่ง CAM_Memory Recall: 8x 01 04 3F 02 pp FF
+func (v *viscaDriver) RecallPreset(ctx context.Context, preset int) error {
+	if err := checkPreset(preset, VISCA_MAX_PRESET); err != nil {
+		return err
+	}
+	payload := []byte{VISCA_CAMERA_ADDRESS, 0x01, 0x04, 0x3F, 0x02, byte(preset - 1), VISCA_MESSAGE_TRAILER}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	deadline := time.Now().Add(v.cfg.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	stop := context.AfterFunc(ctx, func() { v.conn.SetReadDeadline(time.Now()) })
+	defer stop()
+
+	// ส่งใหม่หลัง reset หนึ่งครั้งถ้ากล้องแจ้งว่า sequence number ไม่ตรง (เช่นกล้องเพิ่งเปิดเครื่องใหม่)
+	for attempt := 0; ; attempt++ {
+		if v.reset {
+			if err := v.exchange(ctx, VISCA_CONTROL, []byte{VISCA_CONTROL_RESET}, deadline); err != nil {
+				return err
+			}
+			v.seq = 0
+			v.reset = false
+		}
+		err := v.exchange(ctx, VISCA_COMMAND, payload, deadline)
+		if errors.Is(err, errViscaSequence) && attempt == 0 {
+			v.reset = true
+			continue
+		}
+		if errors.Is(err, ErrTimeout) || errors.Is(err, errViscaSequence) {
+			v.reset = true
+		}
+		return err
+	}
+}
+
+func (v *viscaDriver) Close() error {
+	return v.conn.Close()
+}
+
+// ส่งข้อความหนึ่งข้อความและรอ reply ของ sequence นั้น ส่งซ้ำด้วย sequence เดิมเมื่อไม่ได้รับ ACK
+// ภายใน AckTimeout และสำหรับคำสั่งจะรอต่อจนได้รับ completion
+func (v *viscaDriver) exchange(ctx context.Context, payloadType uint16, payload []byte, deadline time.Time) error {
+	defer func() { v.seq++ }()
+
+	acked := false
+	for attempt := 1; ; attempt++ {
+		if err := v.send(payloadType, payload); err != nil {
+			return err
+		}
+		waitUntil := time.Now().Add(v.cfg.AckTimeout)
+		if acked || attempt >= v.cfg.Retries || waitUntil.After(deadline) {
+			waitUntil = deadline
+		}
+
+		for {
+			replyType, reply, err := v.receive(ctx, waitUntil)
+			if errors.Is(err, ErrTimeout) && !acked && waitUntil.Before(deadline) {
+				break // ส่งซ้ำ
+			}
+			if err != nil {
+				return err
+			}
+
+			switch {
+			case replyType == VISCA_CONTROL_REPLY && len(reply) >= 2 && reply[0] == VISCA_CONTROL_ERROR:
+				if reply[1] == VISCA_ERROR_SEQUENCE {
+					return errViscaSequence
+				}
+				return fmt.Errorf("visca: กล้องปฏิเสธข้อความ (control error 0x%02X)", reply[1])
+			case replyType == VISCA_CONTROL_REPLY && payloadType == VISCA_CONTROL:
+				return nil
+			case replyType == VISCA_REPLY && len(reply) >= 3 && reply[0]&0xF0 == VISCA_REPLY_ADDRESS:
+				switch reply[1] & 0xF0 {
+				case VISCA_REPLY_ACK:
+					// รอ completion จนถึง deadline โดยไม่ส่งซ้ำ
+					acked = true
+					waitUntil = deadline
+				case VISCA_REPLY_COMPLETE:
+					return nil
+				case VISCA_REPLY_ERROR:
+					return &ViscaError{Code: reply[2]}
+				}
+			}
+		}
+	}
+}
+
+func (v *viscaDriver) send(payloadType uint16, payload []byte) error {
+	packet := make([]byte, VISCA_HEADER_SIZE+len(payload))
+	binary.BigEndian.PutUint16(packet[0:], payloadType)
+	binary.BigEndian.PutUint16(packet[2:], uint16(len(payload)))
+	binary.BigEndian.PutUint32(packet[4:], v.seq)
+	copy(packet[VISCA_HEADER_SIZE:], payload)
+	_, err := v.conn.Write(packet)
+	return err
+}
+
+// อ่าน reply ของ sequence ปัจจุบันจนถึง deadline (reply ของ sequence อื่นถูกข้าม)
+func (v *viscaDriver) receive(ctx context.Context, deadline time.Time) (uint16, []byte, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return 0, nil, err
+		}
+		v.conn.SetReadDeadline(deadline)
+		n, err := v.conn.Read(v.buf[:])
+		if err != nil {
+			if ctx.Err() != nil {
+				return 0, nil, ctx.Err()
+			}
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				return 0, nil, ErrTimeout
+			}
+			return 0, nil, err
+		}
+		if n < VISCA_HEADER_SIZE {
+			continue
+		}
+		payloadType := binary.BigEndian.Uint16(v.buf[0:])
+		length := int(binary.BigEndian.Uint16(v.buf[2:]))
+		seq := binary.BigEndian.Uint32(v.buf[4:])
+		if length != n-VISCA_HEADER_SIZE || seq != v.seq {
+			continue
+		}
+		return payloadType, v.buf[VISCA_HEADER_SIZE:n], nil
+	}
+}
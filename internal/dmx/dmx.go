@@ -0,0 +1,224 @@
+// Package dmx ส่งค่า DMX512 หนึ่ง universe ผ่านเครือข่ายด้วย Art-Net (ArtDmx) หรือ sACN (ANSI E1.31)
+package dmx
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+)
+
+// protocol ที่รองรับ
+const (
+	PROTOCOL_ARTNET = "artnet"
+	PROTOCOL_SACN   = "sacn"
+)
+
+const (
+	UNIVERSE_SIZE = 512 // จำนวน channel ใน universe
+
+	ARTNET_PORT         = 6454
+	ARTNET_OPCODE_DMX   = 0x5000
+	ARTNET_VERSION      = 14
+	ARTNET_MAX_UNIVERSE = 32767 // port address 15 bits (net, sub-net และ universe)
+	ARTNET_BROADCAST    = "255.255.255.255"
+
+	SACN_PORT             = 5568
+	SACN_MIN_UNIVERSE     = 1
+	SACN_MAX_UNIVERSE     = 63999
+	SACN_DEFAULT_PRIORITY = 100
+	SACN_MAX_PRIORITY     = 200
+
+	SACN_VECTOR_ROOT_DATA   = 0x00000004
+	SACN_VECTOR_FRAMING     = 0x00000002
+	SACN_VECTOR_DMP_SET     = 0x02
+	SACN_OPTION_TERMINATED  = 0x40 // แจ้ง receiver ว่า source หยุดส่ง universe นี้
+	SACN_SOURCE_NAME_LENGTH = 64
+)
+
+// ACN packet identifier ของ root layer
+var sacnIdentifier = []byte{0x41, 0x53, 0x43, 0x2d, 0x45, 0x31, 0x2e, 0x31, 0x37, 0x00, 0x00, 0x00}
+
+// สร้าง packet ArtDmx ของ universe (port address 15 bits) ด้วยค่า channel ใน data (ความยาวคู่ 2-512)
+func EncodeArtDmx(universe int, sequence byte, data []byte) []byte {
+	if len(data)%2 == 1 {
+		data = append(data[:len(data):len(data)], 0)
+	}
+	packet := make([]byte, 0, 18+len(data))
+	packet = append(packet, "Art-Net\x00"...)
+	packet = binary.LittleEndian.AppendUint16(packet, ARTNET_OPCODE_DMX)
+	packet = binary.BigEndian.AppendUint16(packet, ARTNET_VERSION)
+	packet = append(packet, sequence, 0, byte(universe&0xFF), byte(universe>>8&0x7F))
+	packet = binary.BigEndian.AppendUint16(packet, uint16(len(data)))
+	return append(packet, data...)
+}
+
+// ข้อมูลของ source ใน sACN
+type SACNSource struct {
+	CID      [16]byte // รหัสประจำ source (UUID)
+	Name     string   // ชื่อที่แสดงบน receiver (ไม่เกิน 63 bytes)
+	Priority byte     // 0-200
+}
+
+// สร้าง packet ข้อมูล E1.31 ของ universe ด้วยค่า channel ใน data (ไม่เกิน 512)
+//
+// root layer (38 bytes), framing layer (77 bytes) และ DMP layer (10 bytes + start code + ค่า channel)
+func EncodeSACN(source SACNSource, universe int, sequence byte, options byte, data []byte) []byte {
+	size := 126 + len(data)
+	packet := make([]byte, 0, size)
+
+	// root layer
+	packet = binary.BigEndian.AppendUint16(packet, 0x0010) // preamble size
+	packet = binary.BigEndian.AppendUint16(packet, 0x0000) // postamble size
+	packet = append(packet, sacnIdentifier...)
+	packet = binary.BigEndian.AppendUint16(packet, 0x7000|uint16(size-16))
+	packet = binary.BigEndian.AppendUint32(packet, SACN_VECTOR_ROOT_DATA)
+	packet = append(packet, source.CID[:]...)
+
+	// framing layer
+	packet = binary.BigEndian.AppendUint16(packet, 0x7000|uint16(size-38))
+	packet = binary.BigEndian.AppendUint32(packet, SACN_VECTOR_FRAMING)
+	name := make([]byte, SACN_SOURCE_NAME_LENGTH)
+	copy(name[:SACN_SOURCE_NAME_LENGTH-1], source.Name)
+	packet = append(packet, name...)
+	packet = append(packet, source.Priority)
+	packet = binary.BigEndian.AppendUint16(packet, 0) // synchronization address
+	packet = append(packet, sequence, options)
+	packet = binary.BigEndian.AppendUint16(packet, uint16(universe))
+
+	// DMP layer
+	packet = binary.BigEndian.AppendUint16(packet, 0x7000|uint16(size-115))
+	packet = append(packet, SACN_VECTOR_DMP_SET, 0xA1)
+	packet = binary.BigEndian.AppendUint16(packet, 0x0000) // first property address
+	packet = binary.BigEndian.AppendUint16(packet, 0x0001) // address increment
+	packet = binary.BigEndian.AppendUint16(packet, uint16(len(data)+1))
+	packet = append(packet, 0x00) // DMX start code
+	return append(packet, data...)
+}
+
+// ที่อยู่ multicast ของ universe ใน sACN: 239.255.<universe สูง>.<universe ต่ำ>
+func SACNMulticastAddr(universe int) string {
+	return fmt.Sprintf("239.255.%d.%d", universe>>8, universe&0xFF)
+}
+
+// การตั้งค่าของ Sender
+type Options struct {
+	Protocol string // PROTOCOL_ARTNET หรือ PROTOCOL_SACN
+	Addr     string // host[:port] ปลายทาง (ว่าง = broadcast สำหรับ Art-Net และ multicast ของ universe สำหรับ sACN)
+	Universe int    // Art-Net: 0-32767, sACN: 1-63999
+	Name     string // ชื่อ source ของ sACN
+	Priority int    // priority ของ sACN (0 = SACN_DEFAULT_PRIORITY)
+}
+
+// ตรวจสอบ protocol และช่วงของ universe และ priority
+func (o Options) Validate() error {
+	switch o.Protocol {
+	case PROTOCOL_ARTNET:
+		if o.Universe < 0 || o.Universe > ARTNET_MAX_UNIVERSE {
+			return fmt.Errorf("dmx: universe ของ Art-Net ต้องอยู่ระหว่าง 0-%d", ARTNET_MAX_UNIVERSE)
+		}
+	case PROTOCOL_SACN:
+		if o.Universe < SACN_MIN_UNIVERSE || o.Universe > SACN_MAX_UNIVERSE {
+			return fmt.Errorf("dmx: universe ของ sACN ต้องอยู่ระหว่าง %d-%d", SACN_MIN_UNIVERSE, SACN_MAX_UNIVERSE)
+		}
+		if o.Priority < 0 || o.Priority > SACN_MAX_PRIORITY {
+			return fmt.Errorf("dmx: priority ของ sACN ต้องอยู่ระหว่าง 0-%d", SACN_MAX_PRIORITY)
+		}
+	default:
+		return fmt.Errorf("dmx: ไม่รู้จัก protocol %q (ใช้ %s หรือ %s)", o.Protocol, PROTOCOL_ARTNET, PROTOCOL_SACN)
+	}
+	return nil
+}
+
+// Sender ส่งค่าของ universe หนึ่ง universe ผ่าน UDP
+type Sender struct {
+	opts   Options
+	source SACNSource
+	conn   *net.UDPConn
+	addr   *net.UDPAddr
+
+	mu       sync.Mutex
+	sequence byte
+}
+
+// เปิด socket UDP ไปยังปลายทาง
+func NewSender(opts Options) (*Sender, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	s := &Sender{opts: opts}
+
+	addr, port := opts.Addr, ARTNET_PORT
+	if opts.Protocol == PROTOCOL_SACN {
+		port = SACN_PORT
+		if addr == "" {
+			addr = SACNMulticastAddr(opts.Universe)
+		}
+		if opts.Priority == 0 {
+			s.opts.Priority = SACN_DEFAULT_PRIORITY
+		}
+		s.source = SACNSource{Name: opts.Name, Priority: byte(s.opts.Priority)}
+		rand.Read(s.source.CID[:])
+		s.source.CID[6] = s.source.CID[6]&0x0F | 0x40 // UUID version 4
+		s.source.CID[8] = s.source.CID[8]&0x3F | 0x80
+	} else if addr == "" {
+		addr = ARTNET_BROADCAST
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, strconv.Itoa(port))
+	}
+
+	// ใช้ socket ที่ไม่ได้ connect เพราะ ICMP port unreachable จาก receiver ที่ยังไม่เปิด
+	// จะทำให้การส่งครั้งถัดไปของ socket ที่ connect แล้วล้มเหลวสลับกันไป
+	udpAddr, err := net.ResolveUDPAddr("udp4", addr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return nil, err
+	}
+	s.conn, s.addr = conn, udpAddr
+	return s, nil
+}
+
+// ที่อยู่ปลายทางจริงที่ส่ง
+func (s *Sender) Addr() string {
+	return s.addr.String()
+}
+
+// ส่งค่าของทุก channel ใน universe
+func (s *Sender) Send(data []byte) error {
+	return s.send(data, 0)
+}
+
+func (s *Sender) send(data []byte, options byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// sequence 0 ของ Art-Net หมายถึงไม่ใช้ sequence จึงวนเฉพาะ 1-255
+	s.sequence++
+	if s.sequence == 0 && s.opts.Protocol == PROTOCOL_ARTNET {
+		s.sequence = 1
+	}
+	var packet []byte
+	if s.opts.Protocol == PROTOCOL_SACN {
+		packet = EncodeSACN(s.source, s.opts.Universe, s.sequence, options, data)
+	} else {
+		packet = EncodeArtDmx(s.opts.Universe, s.sequence, data)
+	}
+	_, err := s.conn.WriteToUDP(packet, s.addr)
+	return err
+}
+
+// ปิด socket สำหรับ sACN จะส่ง packet ที่แจ้งว่าหยุดส่งสามครั้งก่อน (ตาม E1.31) ให้ receiver ปล่อย universe ทันที
+func (s *Sender) Close(data []byte) error {
+	if s.opts.Protocol == PROTOCOL_SACN {
+		for range 3 {
+			s.send(data, SACN_OPTION_TERMINATED)
+		}
+	}
+	return s.conn.Close()
+}
@@ -0,0 +1,434 @@
+// Package journal บันทึก frame ของ DCN ลงไฟล์ไบนารีพร้อม CRC แบบหมุนไฟล์ และอ่านกลับมาเพื่อ replay
+package journal
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ampol-me/phi-DCN/internal/logging"
+)
+
+// รูปแบบไฟล์ journal
+//
+//	file header (8 bytes): "DCNJ" + version (uint16) + reserved (uint16)
+//	record:
+//	  0   8  sequence (uint64)
+//	  8   8  timestamp (unix nano, int64)
+//	  16  1  direction (0 = รับจาก upstream, 1 = ส่งไปยัง clients)
+//	  17  3  reserved
+//	  20  4  topic (uint32)
+//	  24  4  payload length (uint32)
+//	  28  n  payload (XML ตามที่ส่งจริง รวม BOM)
+//	  28+n 4 CRC-32C ของ bytes ทั้งหมดก่อนหน้าใน record
+//
+// ตัวเลขทั้งหมดเป็น little endian เหมือน header ของ DCN
+const (
+	JOURNAL_MAGIC       = "DCNJ"
+	JOURNAL_VERSION     = 1
+	JOURNAL_EXT         = ".dcnj"
+	journalHeaderSize   = 8
+	journalRecordHeader = 28
+	journalMaxPayload   = 64 << 20
+
+	// ตรวจ retention ซ้ำระหว่างเขียน เพราะไฟล์ที่ไม่ถึง MaxFileSize จะไม่ถูกหมุน
+	JOURNAL_RETENTION_INTERVAL = time.Hour
+)
+
+// ทิศทางของ frame ที่บันทึก
+type Direction uint8
+
+const (
+	DIR_RECEIVED Direction = 0 // รับจาก upstream
+	DIR_SENT     Direction = 1 // ส่งไปยัง clients
+)
+
+func (d Direction) String() string {
+	if d == DIR_RECEIVED {
+		return "received"
+	}
+	return "sent"
+}
+
+var (
+	ErrJournalChecksum = errors.New("journal: checksum ไม่ตรงกัน")
+	ErrJournalFormat   = errors.New("journal: รูปแบบไฟล์ไม่ถูกต้อง")
+)
+
+var logJournal = logging.New("journal")
+
+var journalCRC = crc32.MakeTable(crc32.Castagnoli)
+
+// frame หนึ่ง frame ใน journal
+type Record struct {
+	Seq       uint64
+	Time      time.Time
+	Direction Direction
+	Topic     uint32
+	Payload   []byte
+}
+
+// การตั้งค่า journal
+type Options struct {
+	Dir          string
+	MaxFileSize  int64         // ขนาดไฟล์สูงสุดก่อนเริ่มไฟล์ใหม่
+	MaxTotalSize int64         // ขนาดรวมสูงสุดของทุกไฟล์ (0 = ไม่จำกัด)
+	MaxAge       time.Duration // อายุสูงสุดของไฟล์ (0 = ไม่จำกัด)
+}
+
+// Journal เขียน frame ต่อท้ายไฟล์แบบ append-only และหมุนไฟล์ตามขนาด
+type Journal struct {
+	opts Options
+	mu   sync.Mutex
+	file *os.File
+	size int64
+	seq  uint64
+
+	retentionAt time.Time // เวลาที่ตรวจ retention ล่าสุด
+}
+
+// เปิด journal ในไดเรกทอรีที่กำหนด โดยนับ sequence ต่อจากไฟล์ล่าสุด
+func Open(opts Options) (*Journal, error) {
+	if err := os.MkdirAll(opts.Dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	j := &Journal{opts: opts}
+
+	files, err := journalFiles(opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+	// ไฟล์ล่าสุดอาจไม่มี record (เช่นเปิดแล้วปิดโดยไม่มี frame) จึงย้อนหาไฟล์ที่มี record
+	// sequence เริ่มที่ 1 เสมอ ค่า 0 จึงหมายถึงไม่มี record
+	for i := len(files) - 1; i >= 0 && j.seq == 0; i-- {
+		last, err := lastJournalSeq(files[i])
+		if err != nil {
+			return nil, err
+		}
+		j.seq = last
+	}
+
+	if err := j.rotate(); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// บันทึก frame หนึ่ง frame คืนค่า sequence ที่ได้
+func (j *Journal) Append(dir Direction, topic uint32, payload []byte, t time.Time) (uint64, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	recordSize := int64(journalRecordHeader + len(payload) + 4)
+	if j.opts.MaxFileSize > 0 && j.size > journalHeaderSize && j.size+recordSize > j.opts.MaxFileSize {
+		if err := j.rotate(); err != nil {
+			return 0, err
+		}
+	} else if time.Since(j.retentionAt) >= JOURNAL_RETENTION_INTERVAL {
+		j.enforceRetention(j.file.Name())
+	}
+
+	j.seq++
+	record := encodeJournalRecord(Record{
+		Seq:       j.seq,
+		Time:      t,
+		Direction: dir,
+		Topic:     topic,
+		Payload:   payload,
+	})
+	n, err := j.file.Write(record)
+	j.size += int64(n)
+	if err != nil {
+		return 0, err
+	}
+	return j.seq, nil
+}
+
+// ปิดไฟล์ปัจจุบัน
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.file == nil {
+		return nil
+	}
+	err := j.closeFile()
+	j.file = nil
+	return err
+}
+
+func (j *Journal) closeFile() error {
+	if err := j.file.Sync(); err != nil {
+		j.file.Close()
+		return err
+	}
+	return j.file.Close()
+}
+
+// ปิดไฟล์ปัจจุบัน เริ่มไฟล์ใหม่ แล้วลบไฟล์เก่าตามเงื่อนไข retention (ต้องถือ mu อยู่)
+func (j *Journal) rotate() error {
+	if j.file != nil {
+		if err := j.closeFile(); err != nil {
+			return err
+		}
+		j.file = nil
+	}
+
+	name := filepath.Join(j.opts.Dir, "journal-"+time.Now().UTC().Format("20060102T150405.000000000")+JOURNAL_EXT)
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+
+	header := make([]byte, journalHeaderSize)
+	copy(header, JOURNAL_MAGIC)
+	binary.LittleEndian.PutUint16(header[4:6], JOURNAL_VERSION)
+	if _, err := f.Write(header); err != nil {
+		f.Close()
+		return err
+	}
+
+	j.file = f
+	j.size = journalHeaderSize
+	logJournal.Info("journal.opened", "file", name, "seq", j.seq)
+
+	j.enforceRetention(name)
+	return nil
+}
+
+// ลบไฟล์ที่เก่าเกิน MaxAge หรือทำให้ขนาดรวมเกิน MaxTotalSize โดยไม่ลบไฟล์ปัจจุบัน (ต้องถือ mu อยู่)
+func (j *Journal) enforceRetention(current string) {
+	j.retentionAt = time.Now()
+	files, err := journalFiles(j.opts.Dir)
+	if err != nil {
+		logJournal.Warn("journal.retention_failed", "error", err)
+		return
+	}
+
+	type fileInfo struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	infos := make([]fileInfo, 0, len(files))
+	var total int64
+	for _, path := range files {
+		st, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		infos = append(infos, fileInfo{path, st.Size(), st.ModTime()})
+		total += st.Size()
+	}
+
+	// ไฟล์เรียงจากเก่าไปใหม่ ลบจากไฟล์เก่าสุดก่อน
+	for _, info := range infos {
+		if info.path == current {
+			break
+		}
+		tooOld := j.opts.MaxAge > 0 && time.Since(info.modTime) > j.opts.MaxAge
+		tooBig := j.opts.MaxTotalSize > 0 && total > j.opts.MaxTotalSize
+		if !tooOld && !tooBig {
+			continue
+		}
+		if err := os.Remove(info.path); err != nil {
+			logJournal.Warn("journal.retention_failed", "file", info.path, "error", err)
+			continue
+		}
+		total -= info.size
+		logJournal.Info("journal.removed", "file", info.path, "too_old", tooOld, "too_big", tooBig)
+	}
+}
+
+func encodeJournalRecord(r Record) []byte {
+	buf := make([]byte, journalRecordHeader+len(r.Payload)+4)
+	binary.LittleEndian.PutUint64(buf[0:8], r.Seq)
+	binary.LittleEndian.PutUint64(buf[8:16], uint64(r.Time.UnixNano()))
+	buf[16] = byte(r.Direction)
+	binary.LittleEndian.PutUint32(buf[20:24], r.Topic)
+	binary.LittleEndian.PutUint32(buf[24:28], uint32(len(r.Payload)))
+	copy(buf[journalRecordHeader:], r.Payload)
+	crcOffset := journalRecordHeader + len(r.Payload)
+	binary.LittleEndian.PutUint32(buf[crcOffset:], crc32.Checksum(buf[:crcOffset], journalCRC))
+	return buf
+}
+
+// รายชื่อไฟล์ journal ในไดเรกทอรี เรียงจากเก่าไปใหม่
+func journalFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), JOURNAL_EXT) {
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// sequence ของ record สุดท้ายที่อ่านได้ในไฟล์
+func lastJournalSeq(path string) (uint64, error) {
+	r, err := OpenReader(path)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+
+	var last uint64
+	for {
+		record, err := r.Next()
+		if err == io.EOF {
+			return last, nil
+		}
+		if err != nil {
+			// ไฟล์เสียหาย ใช้ sequence สุดท้ายที่อ่านได้และเริ่มไฟล์ใหม่ต่อ
+			logJournal.Warn("journal.read_failed", "file", path, "error", err)
+			return last, nil
+		}
+		last = record.Seq
+	}
+}
+
+// Reader อ่าน record จากไฟล์ journal ไฟล์เดียวหรือทุกไฟล์ในไดเรกทอรีตามลำดับ
+type Reader struct {
+	files  []string
+	index  int
+	file   *os.File
+	reader *bufio.Reader
+	offset int64
+}
+
+// เปิด journal สำหรับอ่าน path เป็นได้ทั้งไฟล์และไดเรกทอรี
+func OpenReader(path string) (*Reader, error) {
+	st, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{path}
+	if st.IsDir() {
+		if files, err = journalFiles(path); err != nil {
+			return nil, err
+		}
+	}
+	return &Reader{files: files, index: -1}, nil
+}
+
+// อ่าน record ถัดไป คืนค่า io.EOF เมื่ออ่านครบทุกไฟล์
+//
+// record ท้ายไฟล์ที่เขียนไม่ครบ (เช่นโปรแกรมหยุดกลางคัน) จะถูกข้ามไปยังไฟล์ถัดไป
+// ส่วน record ที่ checksum ไม่ตรงจะคืนค่า error ที่ห่อ ErrJournalChecksum
+func (r *Reader) Next() (Record, error) {
+	for {
+		if r.reader == nil {
+			if err := r.openNext(); err != nil {
+				return Record{}, err
+			}
+		}
+
+		record, err := r.readRecord()
+		if err == io.EOF || errors.Is(err, io.ErrUnexpectedEOF) {
+			r.file.Close()
+			r.file, r.reader = nil, nil
+			continue
+		}
+		return record, err
+	}
+}
+
+// ข้ามส่วนที่เหลือของไฟล์ปัจจุบัน Next ครั้งถัดไปจะอ่านจากไฟล์ถัดไป
+func (r *Reader) SkipFile() {
+	if r.file != nil {
+		r.file.Close()
+		r.file, r.reader = nil, nil
+	}
+}
+
+// ปิดไฟล์ที่เปิดอยู่
+func (r *Reader) Close() error {
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file, r.reader = nil, nil
+	r.index = len(r.files)
+	return err
+}
+
+func (r *Reader) openNext() error {
+	r.index++
+	if r.index >= len(r.files) {
+		return io.EOF
+	}
+
+	path := r.files[r.index]
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	reader := bufio.NewReader(f)
+	header := make([]byte, journalHeaderSize)
+	if _, err := io.ReadFull(reader, header); err != nil || string(header[:4]) != JOURNAL_MAGIC {
+		f.Close()
+		return fmt.Errorf("%w: %s", ErrJournalFormat, path)
+	}
+	if version := binary.LittleEndian.Uint16(header[4:6]); version != JOURNAL_VERSION {
+		f.Close()
+		return fmt.Errorf("%w: %s version %d", ErrJournalFormat, path, version)
+	}
+
+	r.file, r.reader, r.offset = f, reader, journalHeaderSize
+	return nil
+}
+
+func (r *Reader) readRecord() (Record, error) {
+	header := make([]byte, journalRecordHeader)
+	if _, err := io.ReadFull(r.reader, header); err != nil {
+		return Record{}, err
+	}
+
+	payloadLen := binary.LittleEndian.Uint32(header[24:28])
+	if payloadLen > journalMaxPayload {
+		return Record{}, fmt.Errorf("%w: %s offset %d payload %d bytes", ErrJournalFormat, r.files[r.index], r.offset, payloadLen)
+	}
+
+	rest := make([]byte, int(payloadLen)+4)
+	if _, err := io.ReadFull(r.reader, rest); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return Record{}, err
+	}
+
+	crc := crc32.Update(crc32.Checksum(header, journalCRC), journalCRC, rest[:payloadLen])
+	if crc != binary.LittleEndian.Uint32(rest[payloadLen:]) {
+		return Record{}, fmt.Errorf("%w: %s offset %d", ErrJournalChecksum, r.files[r.index], r.offset)
+	}
+	r.offset += int64(len(header) + len(rest))
+
+	return Record{
+		Seq:       binary.LittleEndian.Uint64(header[0:8]),
+		Time:      time.Unix(0, int64(binary.LittleEndian.Uint64(header[8:16]))),
+		Direction: Direction(header[16]),
+		Topic:     binary.LittleEndian.Uint32(header[20:24]),
+		Payload:   rest[:payloadLen],
+	}, nil
+}
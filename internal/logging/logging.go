@@ -0,0 +1,249 @@
+// Package logging เขียน log แยกตาม subsystem โดยแปลข้อความจาก catalog เป็นภาษาไทยหรืออังกฤษ
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// ภาษาของข้อความ log
+const (
+	LANG_TH = "th"
+	LANG_EN = "en"
+)
+
+// ปลายทางของ log (คำสั่งที่เขียนผลลัพธ์ออก stdout เปลี่ยนเป็น stderr ก่อนเรียก Setup)
+var Output io.Writer = os.Stdout
+
+// การตั้งค่า logging ปัจจุบัน (กำหนดครั้งเดียวตอนเริ่มโปรแกรมผ่าน Setup)
+var (
+	baseLogger      = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	logLang         = LANG_TH
+	defaultLogLevel = slog.LevelInfo
+	subsystemLevels = map[string]slog.Level{}
+)
+
+// Logger เขียน log ของ subsystem หนึ่ง โดยแปลข้อความจาก catalog ตามภาษาที่เลือก
+type Logger struct {
+	subsystem string
+}
+
+// สร้าง Logger ของ subsystem ที่กำหนด
+func New(subsystem string) Logger {
+	return Logger{subsystem: subsystem}
+}
+
+// ตรวจสอบว่า log ระดับนี้ของ subsystem จะถูกเขียนหรือไม่
+func (l Logger) Enabled(level slog.Level) bool {
+	min, ok := subsystemLevels[l.subsystem]
+	if !ok {
+		min = defaultLogLevel
+	}
+	return level >= min
+}
+
+func (l Logger) log(level slog.Level, key string, args []any) {
+	if !l.Enabled(level) {
+		return
+	}
+	attrs := append([]any{"subsystem", l.subsystem, "event", key}, args...)
+	baseLogger.Log(context.Background(), level, Msg(key), attrs...)
+}
+
+func (l Logger) Debug(key string, args ...any) { l.log(slog.LevelDebug, key, args) }
+func (l Logger) Info(key string, args ...any)  { l.log(slog.LevelInfo, key, args) }
+func (l Logger) Warn(key string, args ...any)  { l.log(slog.LevelWarn, key, args) }
+func (l Logger) Error(key string, args ...any) { l.log(slog.LevelError, key, args) }
+
+// ตั้งค่า logging จาก flags
+//
+//	format     "text" หรือ "json"
+//	level      ระดับ log เริ่มต้น เช่น "info"
+//	lang       ภาษาของข้อความ "th" หรือ "en"
+//	subsystems ระดับ log แยกตาม subsystem เช่น "framing=debug,clients=warn"
+func Setup(format, level, lang, subsystems string) error {
+	if err := defaultLogLevel.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("log level ไม่ถูกต้อง %q", level)
+	}
+
+	opts := &slog.HandlerOptions{Level: slog.LevelDebug}
+	switch format {
+	case "text":
+		baseLogger = slog.New(slog.NewTextHandler(Output, opts))
+	case "json":
+		baseLogger = slog.New(slog.NewJSONHandler(Output, opts))
+	default:
+		return fmt.Errorf("log format ไม่ถูกต้อง %q (ใช้ text หรือ json)", format)
+	}
+
+	switch lang {
+	case LANG_TH, LANG_EN:
+		logLang = lang
+	default:
+		return fmt.Errorf("ภาษาของ log ไม่ถูกต้อง %q (ใช้ th หรือ en)", lang)
+	}
+
+	for _, entry := range strings.Split(subsystems, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, levelText, ok := strings.Cut(entry, "=")
+		var subsystemLevel slog.Level
+		if !ok || subsystemLevel.UnmarshalText([]byte(levelText)) != nil {
+			return fmt.Errorf("ระดับ log ของ subsystem ไม่ถูกต้อง %q (ใช้รูปแบบ name=level)", entry)
+		}
+		subsystemLevels[strings.TrimSpace(name)] = subsystemLevel
+	}
+	return nil
+}
+
+// แปลข้อความ log ตามภาษาที่เลือก ถ้าไม่พบจะใช้ภาษาอังกฤษหรือ key แทน
+func Msg(key string) string {
+	if texts, ok := catalog[key]; ok {
+		if text, ok := texts[logLang]; ok {
+			return text
+		}
+		if text, ok := texts[LANG_EN]; ok {
+			return text
+		}
+	}
+	return key
+}
+
+// ข้อความ log ทั้งหมด แยกตามภาษา
+var catalog = map[string]map[string]string{
+	"server.listening":         {LANG_TH: "🚀 Server กำลังทำงาน", LANG_EN: "server listening"},
+	"server.start_failed":      {LANG_TH: "❌ ไม่สามารถเริ่ม server ได้", LANG_EN: "failed to start server"},
+	"server.accept_failed":     {LANG_TH: "⚠️ ไม่สามารถรับการเชื่อมต่อจาก client ได้", LANG_EN: "failed to accept client connection"},
+	"server.shutting_down":     {LANG_TH: "🛑 ได้รับสัญญาณหยุดทำงาน กำลังปิด server...", LANG_EN: "shutdown signal received, stopping server"},
+	"server.stopped":           {LANG_TH: "✅ ปิด server เรียบร้อย", LANG_EN: "server stopped"},
+	"server.final_state":       {LANG_TH: "🔇 ส่งสถานะปิดไมค์ทั้งหมดไปยัง clients", LANG_EN: "broadcasting all microphones off"},
+	"admin.listening":          {LANG_TH: "🛠️ Admin API กำลังทำงาน", LANG_EN: "admin API listening"},
+	"admin.start_failed":       {LANG_TH: "⚠️ ไม่สามารถเริ่ม admin API ได้", LANG_EN: "failed to start admin API"},
+	"client.connected":         {LANG_TH: "👥 Client เชื่อมต่อ", LANG_EN: "client connected"},
+	"client.disconnected":      {LANG_TH: "👋 Client ยกเลิกการเชื่อมต่อ", LANG_EN: "client disconnected"},
+	"client.write_failed":      {LANG_TH: "⚠️ ไม่สามารถส่งข้อมูลไปยัง Client", LANG_EN: "failed to write to client"},
+	"client.queue_full":        {LANG_TH: "⚠️ คิวส่งข้อมูลของ Client เต็ม ตัดการเชื่อมต่อ", LANG_EN: "client send queue full, disconnecting"},
+	"client.drain_timeout":     {LANG_TH: "⚠️ หมดเวลารอส่งข้อมูลไปยัง Client", LANG_EN: "timed out draining client queue"},
+	"frame.broadcast":          {LANG_TH: "📤 ส่ง frame ไปยัง clients", LANG_EN: "frame broadcast"},
+	"upstream.poll_failed":     {LANG_TH: "⚠️ ไม่สามารถดึงข้อมูล speakers", LANG_EN: "failed to poll speakers"},
+	"upstream.request":         {LANG_TH: "ไม่สามารถสร้าง request", LANG_EN: "cannot create request"},
+	"upstream.connect":         {LANG_TH: "ไม่สามารถเชื่อมต่อกับ API", LANG_EN: "cannot reach API"},
+	"upstream.read":            {LANG_TH: "ไม่สามารถอ่านข้อมูลจาก API", LANG_EN: "cannot read API response"},
+	"upstream.decode":          {LANG_TH: "ไม่สามารถแปลงข้อมูล JSON", LANG_EN: "cannot decode API JSON"},
+	"mock.toggled":             {LANG_TH: "🔄 สลับสถานะไมค์ mock", LANG_EN: "mock microphone toggled"},
+	"mock.set":                 {LANG_TH: "🎛️ กำหนดสถานะไมค์ mock", LANG_EN: "mock microphone set"},
+	"seat.mic_changed":         {LANG_TH: "🎙️ สถานะไมค์เปลี่ยน", LANG_EN: "seat microphone changed"},
+	"discussion.changed":       {LANG_TH: "🎙️ รายการไมค์ที่เปิดเปลี่ยน", LANG_EN: "active list changed"},
+	"journal.opened":           {LANG_TH: "📒 เริ่มไฟล์ journal", LANG_EN: "journal file opened"},
+	"journal.open_failed":      {LANG_TH: "❌ ไม่สามารถเปิด journal ได้", LANG_EN: "failed to open journal"},
+	"journal.write_failed":     {LANG_TH: "⚠️ ไม่สามารถบันทึก frame ลง journal", LANG_EN: "failed to write journal record"},
+	"journal.read_failed":      {LANG_TH: "⚠️ อ่านไฟล์ journal ไม่สำเร็จ", LANG_EN: "failed to read journal file"},
+	"journal.removed":          {LANG_TH: "🗑️ ลบไฟล์ journal เก่า", LANG_EN: "old journal file removed"},
+	"journal.retention_failed": {LANG_TH: "⚠️ ไม่สามารถลบไฟล์ journal เก่า", LANG_EN: "journal retention failed"},
+	"replay.started":           {LANG_TH: "▶️ เริ่ม replay", LANG_EN: "replay started"},
+	"replay.load_failed":       {LANG_TH: "❌ ไม่สามารถโหลดไฟล์สำหรับ replay ได้", LANG_EN: "failed to load replay source"},
+	"replay.file_skipped":      {LANG_TH: "⚠️ ข้ามไฟล์ journal ที่อ่านไม่ได้", LANG_EN: "skipped unreadable journal file"},
+	"replay.looped":            {LANG_TH: "🔁 เล่น replay ซ้ำตั้งแต่ต้น", LANG_EN: "replay looped"},
+	"replay.paused":            {LANG_TH: "⏸️ หยุด replay ชั่วคราว", LANG_EN: "replay paused"},
+	"replay.resumed":           {LANG_TH: "▶️ เล่น replay ต่อ", LANG_EN: "replay resumed"},
+	"replay.speed":             {LANG_TH: "⏩ เปลี่ยนความเร็ว replay", LANG_EN: "replay speed changed"},
+	"replay.seek":              {LANG_TH: "⏭️ เลื่อนตำแหน่ง replay", LANG_EN: "replay seek"},
+	"discussion.poll_empty":    {LANG_TH: "📭 ส่ง ActiveList ว่างเนื่องจากไม่มีข้อมูลจาก API", LANG_EN: "broadcasting empty active list, no API data"},
+	"proxy.listening":          {LANG_TH: "🚀 Proxy server กำลังทำงาน", LANG_EN: "proxy listening"},
+	"proxy.start_failed":       {LANG_TH: "❌ ไม่สามารถเริ่ม proxy server ได้", LANG_EN: "failed to start proxy"},
+	"proxy.accept_failed":      {LANG_TH: "⚠️ ไม่สามารถรับการเชื่อมต่อจาก client ได้", LANG_EN: "failed to accept client connection"},
+	"proxy.final_state":        {LANG_TH: "🔇 ส่งสถานะปิดไมค์ทั้งหมดไปยัง clients", LANG_EN: "broadcasting all microphones off"},
+	"proxy.stopped":            {LANG_TH: "✅ ปิด proxy เรียบร้อย", LANG_EN: "proxy stopped"},
+	"http.listening":           {LANG_TH: "📊 HTTP endpoint กำลังทำงาน", LANG_EN: "HTTP endpoint listening"},
+	"http.start_failed":        {LANG_TH: "⚠️ ไม่สามารถเริ่ม HTTP endpoint ได้", LANG_EN: "failed to start HTTP endpoint"},
+	"upstream.connecting":      {LANG_TH: "🔄 กำลังเชื่อมต่อไปยัง server", LANG_EN: "connecting to upstream"},
+	"upstream.connected":       {LANG_TH: "🔗 เชื่อมต่อกับ server สำเร็จ กำลังรอรับข้อมูล", LANG_EN: "connected to upstream, waiting for data"},
+	"upstream.connect_failed":  {LANG_TH: "❌ ไม่สามารถเชื่อมต่อกับ server ได้", LANG_EN: "failed to connect to upstream"},
+	"upstream.closed":          {LANG_TH: "⚠️ การเชื่อมต่อถูกปิด", LANG_EN: "upstream connection closed"},
+	"upstream.shutdown":        {LANG_TH: "🛑 ได้รับสัญญาณหยุดทำงาน ปิดการเชื่อมต่อกับ server", LANG_EN: "shutdown signal received, closing upstream"},
+	"frame.raw":                {LANG_TH: "📝 Raw data", LANG_EN: "raw data"},
+	"frame.header":             {LANG_TH: "📨 พบ Header", LANG_EN: "frame header"},
+	"frame.xml":                {LANG_TH: "📜 XML", LANG_EN: "frame XML"},
+	"frame.decode_failed":      {LANG_TH: "⚠️ ไม่สามารถแปลง XML", LANG_EN: "failed to decode frame XML"},
+	"capture.opened":           {LANG_TH: "💾 เริ่มไฟล์ capture", LANG_EN: "capture file opened"},
+	"capture.write_failed":     {LANG_TH: "⚠️ ไม่สามารถบันทึก frame ลงไฟล์ capture", LANG_EN: "failed to write capture record"},
+	"capture.enabled":          {LANG_TH: "⏺️ เริ่มบันทึก capture", LANG_EN: "capture enabled"},
+	"capture.disabled":         {LANG_TH: "⏹️ หยุดบันทึก capture", LANG_EN: "capture disabled"},
+	"capture.removed":          {LANG_TH: "🗑️ ลบไฟล์ capture เก่า", LANG_EN: "old capture file removed"},
+	"capture.retention_failed": {LANG_TH: "⚠️ ไม่สามารถลบไฟล์ capture เก่า", LANG_EN: "capture retention failed"},
+	"pcapng.opened":            {LANG_TH: "🦈 เริ่มบันทึก traffic เป็นไฟล์ pcapng", LANG_EN: "pcapng capture started"},
+	"pcapng.open_failed":       {LANG_TH: "❌ ไม่สามารถสร้างไฟล์ pcapng ได้", LANG_EN: "failed to create pcapng file"},
+	"pcapng.write_failed":      {LANG_TH: "⚠️ ไม่สามารถบันทึก packet ลงไฟล์ pcapng", LANG_EN: "failed to write pcapng packet"},
+	"pcapng.close_failed":      {LANG_TH: "⚠️ ปิดไฟล์ pcapng ไม่สำเร็จ", LANG_EN: "failed to close pcapng file"},
+	"import.file_failed":       {LANG_TH: "❌ อ่านไฟล์ capture ไม่สำเร็จ", LANG_EN: "failed to read capture file"},
+	"import.output_failed":     {LANG_TH: "❌ ไม่สามารถเขียนผลลัพธ์ได้", LANG_EN: "failed to write import output"},
+	"import.gap":               {LANG_TH: "⚠️ ข้อมูลใน TCP stream ขาดหาย ข้ามไปยังข้อมูลถัดไป", LANG_EN: "missing data in TCP stream, skipping ahead"},
+	"import.truncated":         {LANG_TH: "⚠️ frame สุดท้ายของ stream ไม่ครบ", LANG_EN: "stream ended with an incomplete frame"},
+	"import.done":              {LANG_TH: "✅ import เสร็จสิ้น", LANG_EN: "import finished"},
+	"discussion.active_list":   {LANG_TH: "🎙️ สถานะไมค์ทั้งหมด", LANG_EN: "active list"},
+	"watch.connecting":         {LANG_TH: "🔄 กำลังเชื่อมต่อเพื่อดู frame", LANG_EN: "connecting to watch frames"},
+	"watch.connected":          {LANG_TH: "👀 เชื่อมต่อสำเร็จ กำลังรอรับ frame", LANG_EN: "connected, watching frames"},
+	"watch.failed":             {LANG_TH: "⚠️ การเชื่อมต่อล้มเหลวหรือถูกปิด", LANG_EN: "watch connection failed or closed"},
+	"send.listening":           {LANG_TH: "⏳ รอ client เชื่อมต่อเพื่อส่ง frame", LANG_EN: "waiting for a client to send frames to"},
+	"send.connected":           {LANG_TH: "🔗 เชื่อมต่อกับปลายทางแล้ว", LANG_EN: "connected to peer"},
+	"send.sent":                {LANG_TH: "📤 ส่ง frame แล้ว", LANG_EN: "frame sent"},
+	"send.failed":              {LANG_TH: "❌ ส่ง frame ไม่สำเร็จ", LANG_EN: "failed to send frames"},
+	"seat.updated":             {LANG_TH: "💺 ได้รับข้อมูลที่นั่ง", LANG_EN: "seat updated"},
+	"seat.inconsistent":        {LANG_TH: "⚠️ SeatActivity และ DiscussionActivity ไม่ตรงกัน", LANG_EN: "seat and discussion topics disagree"},
+	"stream.connected":         {LANG_TH: "🔌 client เชื่อมต่อรับเหตุการณ์", LANG_EN: "event stream client connected"},
+	"stream.disconnected":      {LANG_TH: "🔌 client ยกเลิกการรับเหตุการณ์", LANG_EN: "event stream client disconnected"},
+	"stream.queue_full":        {LANG_TH: "⚠️ คิวเหตุการณ์ของ client เต็ม ตัดการเชื่อมต่อ", LANG_EN: "event stream queue full, disconnecting client"},
+	"stream.upgrade_failed":    {LANG_TH: "⚠️ WebSocket handshake ไม่สำเร็จ", LANG_EN: "WebSocket handshake failed"},
+	"stream.bad_message":       {LANG_TH: "⚠️ ได้รับคำสั่งที่ไม่รู้จักจาก client", LANG_EN: "unknown command from event stream client"},
+	"ws.listening":             {LANG_TH: "🌐 รอ client เชื่อมต่อรับ frame ผ่าน WebSocket", LANG_EN: "WebSocket frame listener started"},
+	"ws.start_failed":          {LANG_TH: "⚠️ ไม่สามารถเปิด WebSocket listener ได้", LANG_EN: "failed to start WebSocket frame listener"},
+	"client.upgrade_failed":    {LANG_TH: "⚠️ WebSocket handshake ของ client ไม่สำเร็จ", LANG_EN: "client WebSocket handshake failed"},
+	"mqtt.connecting":          {LANG_TH: "🔄 กำลังเชื่อมต่อ MQTT broker", LANG_EN: "connecting to MQTT broker"},
+	"mqtt.connected":           {LANG_TH: "✅ เชื่อมต่อ MQTT broker สำเร็จ", LANG_EN: "connected to MQTT broker"},
+	"mqtt.connect_failed":      {LANG_TH: "❌ ไม่สามารถเชื่อมต่อ MQTT broker", LANG_EN: "failed to connect to MQTT broker"},
+	"mqtt.disconnected":        {LANG_TH: "⚠️ การเชื่อมต่อ MQTT broker หลุด", LANG_EN: "disconnected from MQTT broker"},
+	"mqtt.publish_failed":      {LANG_TH: "⚠️ publish ข้อความไปยัง MQTT broker ไม่สำเร็จ", LANG_EN: "failed to publish MQTT message"},
+	"mqtt.queue_full":          {LANG_TH: "⚠️ คิวข้อความ MQTT เต็ม ทิ้งข้อความใหม่", LANG_EN: "MQTT queue full, dropping messages"},
+	"mqtt.drain_timeout":       {LANG_TH: "⏱️ ส่งข้อความ MQTT ที่ค้างไม่ทันก่อนปิด", LANG_EN: "timed out publishing remaining MQTT messages"},
+	"webhook.open_failed":      {LANG_TH: "❌ ไม่สามารถเปิดคิวของ webhook ได้", LANG_EN: "failed to open webhook queue"},
+	"webhook.loaded":           {LANG_TH: "🪝 โหลด webhook แล้ว", LANG_EN: "webhook loaded"},
+	"webhook.load_failed":      {LANG_TH: "⚠️ อ่าน delivery ที่ค้างในคิวไม่ได้", LANG_EN: "failed to load queued webhook delivery"},
+	"webhook.template_failed":  {LANG_TH: "⚠️ สร้าง payload จาก template ไม่สำเร็จ", LANG_EN: "failed to render webhook template"},
+	"webhook.queue_full":       {LANG_TH: "⚠️ คิวของ webhook เต็ม ย้าย delivery ใหม่ไป dead-letter", LANG_EN: "webhook queue full, dead-lettering new deliveries"},
+	"webhook.delivered":        {LANG_TH: "📨 ส่ง webhook สำเร็จ", LANG_EN: "webhook delivered"},
+	"webhook.retry":            {LANG_TH: "🔁 ส่ง webhook ไม่สำเร็จ จะลองใหม่", LANG_EN: "webhook delivery failed, will retry"},
+	"webhook.dead_letter":      {LANG_TH: "☠️ ส่ง webhook ไม่สำเร็จ ย้ายไป dead-letter", LANG_EN: "webhook delivery failed, moved to dead-letter log"},
+	"webhook.persist_failed":   {LANG_TH: "⚠️ บันทึกคิวของ webhook ไม่สำเร็จ", LANG_EN: "failed to persist webhook queue"},
+	"webhook.drain_timeout":    {LANG_TH: "⏱️ ส่ง webhook ที่ค้างไม่ทันก่อนปิด (ยังอยู่ในคิว)", LANG_EN: "timed out sending remaining webhooks (kept in queue)"},
+	"director.started":         {LANG_TH: "🎬 เริ่ม camera director", LANG_EN: "camera director started"},
+	"director.shot":            {LANG_TH: "🎥 เปลี่ยนภาพกล้อง", LANG_EN: "camera shot changed"},
+	"director.held":            {LANG_TH: "⏳ ค้างภาพปัจจุบันไว้ก่อนเปลี่ยน", LANG_EN: "holding current shot before switching"},
+	"director.unmapped":        {LANG_TH: "ℹ️ ที่นั่งที่เปิดไมค์ไม่มีตำแหน่งกล้อง", LANG_EN: "open microphone has no camera preset"},
+	"director.queue_full":      {LANG_TH: "⚠️ คิวภาพของ director เต็ม ทิ้งภาพใหม่", LANG_EN: "director shot queue full, dropping shot"},
+	"camera.open_failed":       {LANG_TH: "❌ ไม่สามารถสร้าง driver ของกล้องได้", LANG_EN: "failed to open camera driver"},
+	"camera.recalled":          {LANG_TH: "🎥 กล้องเรียก preset แล้ว", LANG_EN: "camera recalled preset"},
+	"camera.recall_failed":     {LANG_TH: "⚠️ ส่งคำสั่งเรียก preset ไปยังกล้องไม่สำเร็จ", LANG_EN: "failed to recall camera preset"},
+	"camera.superseded":        {LANG_TH: "⏭️ ข้ามภาพที่ยังไม่ได้ส่งเพราะมีภาพใหม่", LANG_EN: "pending shot superseded by a newer one"},
+	"camera.mock_listening":    {LANG_TH: "📷 กล้องจำลองรอรับคำสั่ง", LANG_EN: "mock camera listening"},
+	"camera.mock_recalled":     {LANG_TH: "📷 กล้องจำลองเรียก preset", LANG_EN: "mock camera recalled preset"},
+	"tally.started":            {LANG_TH: "🚦 เริ่มส่ง TSL UMD", LANG_EN: "TSL UMD output started"},
+	"tally.send_failed":        {LANG_TH: "⚠️ ส่ง TSL UMD ไม่สำเร็จ", LANG_EN: "failed to send TSL UMD packets"},
+	"tally.recovered":          {LANG_TH: "✅ ส่ง TSL UMD ได้อีกครั้ง", LANG_EN: "TSL UMD output recovered"},
+	"osc.started":              {LANG_TH: "🎚️ เริ่มส่ง OSC ไปยัง mixing console", LANG_EN: "OSC output started"},
+	"osc.sent":                 {LANG_TH: "🎚️ ส่งข้อความ OSC แล้ว", LANG_EN: "OSC message sent"},
+	"osc.dry_run":              {LANG_TH: "🧪 ข้อความ OSC ที่จะส่ง (dry-run)", LANG_EN: "OSC message (dry-run, not sent)"},
+	"osc.send_failed":          {LANG_TH: "⚠️ ส่งข้อความ OSC ไม่สำเร็จ", LANG_EN: "failed to send OSC message"},
+	"osc.template_failed":      {LANG_TH: "⚠️ สร้างข้อความ OSC จาก template ไม่สำเร็จ", LANG_EN: "failed to render OSC message template"},
+	"osc.open_failed":          {LANG_TH: "❌ ไม่สามารถเปิดการเชื่อมต่อ OSC ได้", LANG_EN: "failed to open OSC connection"},
+	"dmx.started":              {LANG_TH: "💡 เริ่มส่งไฟส่องผู้พูดด้วย DMX", LANG_EN: "DMX lighting output started"},
+	"dmx.cue":                  {LANG_TH: "💡 เปลี่ยน cue ของไฟ", LANG_EN: "lighting cue changed"},
+	"dmx.send_failed":          {LANG_TH: "⚠️ ส่ง DMX ไม่สำเร็จ", LANG_EN: "failed to send DMX packet"},
+	"dmx.recovered":            {LANG_TH: "✅ ส่ง DMX ได้อีกครั้ง", LANG_EN: "DMX output recovered"},
+	"dmx.open_failed":          {LANG_TH: "❌ ไม่สามารถเปิดการเชื่อมต่อ DMX ได้", LANG_EN: "failed to open DMX output"},
+}
@@ -0,0 +1,197 @@
+// Package metrics เขียน metrics ในรูปแบบ Prometheus text exposition โดยไม่ต้องพึ่ง library ภายนอก
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ค่าหนึ่งค่าของ metric พร้อม label
+type Sample struct {
+	Labels []string
+	Value  float64
+}
+
+// metric ที่เขียนออกในรูปแบบ Prometheus text exposition ได้
+type Metric interface {
+	Write(w io.Writer)
+}
+
+// ตัวนับค่าที่เพิ่มขึ้นอย่างเดียว แยกตาม label
+type Counter struct {
+	name   string
+	help   string
+	labels []string
+	mu     sync.Mutex
+	values map[string]*Sample
+}
+
+func NewCounter(name, help string, labels ...string) *Counter {
+	return &Counter{name: name, help: help, labels: labels, values: make(map[string]*Sample)}
+}
+
+// เพิ่มค่าตามจำนวนที่กำหนด labelValues ต้องเรียงตาม labels ตอนสร้าง
+func (c *Counter) Add(v float64, labelValues ...string) {
+	key := strings.Join(labelValues, "\xff")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s, ok := c.values[key]
+	if !ok {
+		s = &Sample{Labels: labelValues}
+		c.values[key] = s
+	}
+	s.Value += v
+}
+
+// เพิ่มค่าทีละหนึ่ง
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+func (c *Counter) Write(w io.Writer) {
+	c.mu.Lock()
+	samples := make([]Sample, 0, len(c.values))
+	for _, s := range c.values {
+		samples = append(samples, *s)
+	}
+	c.mu.Unlock()
+
+	// counter ที่ไม่มี label ให้แสดงค่า 0 ตั้งแต่เริ่ม
+	if len(samples) == 0 && len(c.labels) == 0 {
+		samples = append(samples, Sample{})
+	}
+	writeSamples(w, c.name, c.help, "counter", c.labels, samples)
+}
+
+// metric ที่คำนวณค่าตอนถูกอ่าน (ใช้กับ gauge หรือ counter ที่เก็บค่าไว้ที่อื่น)
+type FuncMetric struct {
+	Name    string
+	Help    string
+	Kind    string // "gauge" หรือ "counter"
+	Labels  []string
+	Collect func() []Sample
+}
+
+func NewGaugeFunc(name, help string, fn func() float64) *FuncMetric {
+	return &FuncMetric{Name: name, Help: help, Kind: "gauge", Collect: func() []Sample {
+		return []Sample{{Value: fn()}}
+	}}
+}
+
+func (f *FuncMetric) Write(w io.Writer) {
+	writeSamples(w, f.Name, f.Help, f.Kind, f.Labels, f.Collect())
+}
+
+// histogram แบบ cumulative bucket
+type Histogram struct {
+	name    string
+	help    string
+	buckets []float64
+	mu      sync.Mutex
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+func NewHistogram(name, help string, buckets ...float64) *Histogram {
+	return &Histogram{name: name, help: help, buckets: buckets, counts: make([]uint64, len(buckets))}
+}
+
+// บันทึกค่าหนึ่งค่า
+func (h *Histogram) Observe(v float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i, upper := range h.buckets {
+		if v <= upper {
+			h.counts[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+func (h *Histogram) Write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+	for i, upper := range h.buckets {
+		fmt.Fprintf(w, "%s_bucket{le=\"%s\"} %d\n", h.name, formatFloat(upper), h.counts[i])
+	}
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", h.name, h.count)
+	fmt.Fprintf(w, "%s_sum %s\n", h.name, formatFloat(h.sum))
+	fmt.Fprintf(w, "%s_count %d\n", h.name, h.count)
+}
+
+// เขียน samples ของ metric หนึ่งตัวพร้อม HELP และ TYPE
+func writeSamples(w io.Writer, name, help, kind string, labels []string, samples []Sample) {
+	sort.Slice(samples, func(i, j int) bool {
+		return strings.Join(samples[i].Labels, "\xff") < strings.Join(samples[j].Labels, "\xff")
+	})
+
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	for _, s := range samples {
+		fmt.Fprintf(w, "%s%s %s\n", name, formatLabels(labels, s.Labels), formatFloat(s.Value))
+	}
+}
+
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		value := ""
+		if i < len(values) {
+			value = values[i]
+		}
+		pairs[i] = fmt.Sprintf("%s=\"%s\"", name, escapeLabel(value))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func escapeLabel(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return strings.ReplaceAll(s, "\n", `\n`)
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// ชุด metrics ที่แสดงผ่าน /metrics
+type Registry struct {
+	mu      sync.Mutex
+	metrics []Metric
+}
+
+func (r *Registry) Register(m ...Metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.metrics = append(r.metrics, m...)
+}
+
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	metrics := append([]Metric(nil), r.metrics...)
+	r.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	for _, m := range metrics {
+		m.Write(w)
+	}
+}
@@ -0,0 +1,430 @@
+// Package mqtt เป็น MQTT 3.1.1 client เท่าที่ proxy ต้องใช้ในการ publish สถานะไปยัง broker
+//
+// รองรับ CONNECT (username/password และ will message), PUBLISH แบบ QoS 0, 1 และ 2,
+// keepalive ด้วย PINGREQ และ DISCONNECT ไม่รองรับ SUBSCRIBE และ session ที่ต่อเนื่องข้ามการเชื่อมต่อ
+// (เชื่อมต่อแบบ clean session เสมอ) การเชื่อมต่อใหม่เป็นหน้าที่ของผู้เรียก
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// ชนิดของ control packet
+const (
+	PACKET_CONNECT    = 1
+	PACKET_CONNACK    = 2
+	PACKET_PUBLISH    = 3
+	PACKET_PUBACK     = 4
+	PACKET_PUBREC     = 5
+	PACKET_PUBREL     = 6
+	PACKET_PUBCOMP    = 7
+	PACKET_PINGREQ    = 12
+	PACKET_PINGRESP   = 13
+	PACKET_DISCONNECT = 14
+)
+
+// ค่าเริ่มต้นของ Options
+const (
+	DEFAULT_KEEPALIVE       = 30 * time.Second
+	DEFAULT_CONNECT_TIMEOUT = 5 * time.Second
+	DEFAULT_ACK_TIMEOUT     = 10 * time.Second
+)
+
+// ขนาด packet สูงสุดที่ส่งได้ตาม remaining length ของ MQTT
+const MAX_PACKET_SIZE = 268435455
+
+var (
+	ErrClosed     = errors.New("mqtt: การเชื่อมต่อถูกปิดแล้ว")
+	ErrAckTimeout = errors.New("mqtt: broker ไม่ตอบรับ PUBLISH ภายในเวลาที่กำหนด")
+	ErrProtocol   = errors.New("mqtt: packet จาก broker ไม่ถูกต้อง")
+	ErrPassword   = errors.New("mqtt: password ต้องใช้คู่กับ username (MQTT 3.1.1 §3.1.2.9)")
+)
+
+// ConnectError คือ CONNACK ที่ broker ปฏิเสธการเชื่อมต่อ
+type ConnectError struct {
+	Code byte
+}
+
+func (e *ConnectError) Error() string {
+	reasons := map[byte]string{
+		1: "ไม่รองรับ protocol version",
+		2: "client id ไม่ได้รับอนุญาต",
+		3: "broker ไม่พร้อมให้บริการ",
+		4: "username หรือ password ไม่ถูกต้อง",
+		5: "ไม่ได้รับอนุญาต",
+	}
+	if reason, ok := reasons[e.Code]; ok {
+		return "mqtt: broker ปฏิเสธการเชื่อมต่อ: " + reason
+	}
+	return fmt.Sprintf("mqtt: broker ปฏิเสธการเชื่อมต่อ (code %d)", e.Code)
+}
+
+// ข้อความที่ publish
+type Message struct {
+	Topic   string
+	Payload []byte
+	QoS     byte // 0, 1 หรือ 2
+	Retain  bool
+}
+
+// การตั้งค่าการเชื่อมต่อ
+type Options struct {
+	ClientID       string
+	Username       string
+	Password       string
+	KeepAlive      time.Duration // 0 = DEFAULT_KEEPALIVE
+	ConnectTimeout time.Duration // 0 = DEFAULT_CONNECT_TIMEOUT
+	AckTimeout     time.Duration // เวลารอ PUBACK/PUBREC/PUBCOMP (0 = DEFAULT_ACK_TIMEOUT)
+	Will           *Message      // ข้อความที่ broker publish แทนเมื่อการเชื่อมต่อหลุดโดยไม่ส่ง DISCONNECT
+}
+
+// Client คือการเชื่อมต่อกับ broker หนึ่งครั้ง
+//
+// Publish เรียกพร้อมกันจากหลาย goroutine ได้ เมื่อการเชื่อมต่อหลุด Done จะถูกปิดและ Err คืนค่าสาเหตุ
+type Client struct {
+	conn net.Conn
+	opts Options
+	wmu  sync.Mutex
+
+	mu       sync.Mutex
+	nextID   uint16
+	inflight map[uint16]chan byte // packet id -> ชนิดของ ack ที่ได้รับ
+	err      error
+
+	done chan struct{}
+}
+
+// เชื่อมต่อกับ broker ที่ addr (host:port) แล้วรอ CONNACK
+func Dial(ctx context.Context, addr string, opts Options) (*Client, error) {
+	if opts.ConnectTimeout <= 0 {
+		opts.ConnectTimeout = DEFAULT_CONNECT_TIMEOUT
+	}
+	dialer := net.Dialer{Timeout: opts.ConnectTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(conn, opts)
+}
+
+// ส่ง CONNECT ผ่าน conn ที่เชื่อมต่อกับ broker แล้วรอ CONNACK (conn ถูกปิดเมื่อไม่สำเร็จ)
+func NewClient(conn net.Conn, opts Options) (*Client, error) {
+	if opts.Password != "" && opts.Username == "" {
+		conn.Close()
+		return nil, ErrPassword
+	}
+	if opts.KeepAlive <= 0 {
+		opts.KeepAlive = DEFAULT_KEEPALIVE
+	}
+	if opts.ConnectTimeout <= 0 {
+		opts.ConnectTimeout = DEFAULT_CONNECT_TIMEOUT
+	}
+	if opts.AckTimeout <= 0 {
+		opts.AckTimeout = DEFAULT_ACK_TIMEOUT
+	}
+
+	c := &Client{
+		conn:     conn,
+		opts:     opts,
+		inflight: make(map[uint16]chan byte),
+		done:     make(chan struct{}),
+	}
+
+	conn.SetDeadline(time.Now().Add(opts.ConnectTimeout))
+	br := bufio.NewReader(conn)
+	if err := c.writePacket(PACKET_CONNECT<<4, connectBody(opts)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	kind, body, err := readPacket(br)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if kind>>4 != PACKET_CONNACK || len(body) != 2 {
+		conn.Close()
+		return nil, ErrProtocol
+	}
+	if body[1] != 0 {
+		conn.Close()
+		return nil, &ConnectError{Code: body[1]}
+	}
+	conn.SetDeadline(time.Time{})
+
+	go c.readLoop(br)
+	go c.keepAlive()
+	return c, nil
+}
+
+// ข้อมูลของ CONNECT packet หลัง fixed header
+func connectBody(opts Options) []byte {
+	flags := byte(0x02) // clean session
+	if opts.Will != nil {
+		flags |= 0x04 | opts.Will.QoS<<3
+		if opts.Will.Retain {
+			flags |= 0x20
+		}
+	}
+	if opts.Username != "" {
+		flags |= 0x80
+	}
+	if opts.Password != "" {
+		flags |= 0x40
+	}
+
+	body := appendString(nil, "MQTT")
+	body = append(body, 4, flags) // protocol level 4 = 3.1.1
+	body = binary.BigEndian.AppendUint16(body, uint16(opts.KeepAlive/time.Second))
+	body = appendString(body, opts.ClientID)
+	if opts.Will != nil {
+		body = appendString(body, opts.Will.Topic)
+		body = appendBytes(body, opts.Will.Payload)
+	}
+	if opts.Username != "" {
+		body = appendString(body, opts.Username)
+	}
+	if opts.Password != "" {
+		body = appendString(body, opts.Password)
+	}
+	return body
+}
+
+// publish ข้อความหนึ่งข้อความ และรอให้ broker ตอบรับตาม QoS
+func (c *Client) Publish(msg Message) error {
+	if msg.QoS > 2 {
+		return fmt.Errorf("mqtt: QoS %d ไม่ถูกต้อง", msg.QoS)
+	}
+
+	header := byte(PACKET_PUBLISH<<4) | msg.QoS<<1
+	if msg.Retain {
+		header |= 0x01
+	}
+	body := appendString(nil, msg.Topic)
+
+	if msg.QoS == 0 {
+		return c.writePacket(header, append(body, msg.Payload...))
+	}
+
+	id, acks := c.register()
+	defer c.unregister(id)
+
+	body = binary.BigEndian.AppendUint16(body, id)
+	if err := c.writePacket(header, append(body, msg.Payload...)); err != nil {
+		return err
+	}
+	if msg.QoS == 1 {
+		return c.waitAck(acks, PACKET_PUBACK)
+	}
+
+	// QoS 2: PUBLISH -> PUBREC -> PUBREL -> PUBCOMP
+	if err := c.waitAck(acks, PACKET_PUBREC); err != nil {
+		return err
+	}
+	if err := c.writePacket(PACKET_PUBREL<<4|0x02, binary.BigEndian.AppendUint16(nil, id)); err != nil {
+		return err
+	}
+	return c.waitAck(acks, PACKET_PUBCOMP)
+}
+
+// จอง packet id สำหรับข้อความ QoS 1/2
+func (c *Client) register() (uint16, chan byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for {
+		c.nextID++
+		if c.nextID == 0 {
+			continue
+		}
+		if _, used := c.inflight[c.nextID]; !used {
+			break
+		}
+	}
+	acks := make(chan byte, 2)
+	c.inflight[c.nextID] = acks
+	return c.nextID, acks
+}
+
+func (c *Client) unregister(id uint16) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.inflight, id)
+}
+
+// รอ ack ชนิดที่ต้องการ
+func (c *Client) waitAck(acks chan byte, want byte) error {
+	timer := time.NewTimer(c.opts.AckTimeout)
+	defer timer.Stop()
+
+	select {
+	case kind := <-acks:
+		if kind != want {
+			return ErrProtocol
+		}
+		return nil
+	case <-c.done:
+		return c.Err()
+	case <-timer.C:
+		return ErrAckTimeout
+	}
+}
+
+// อ่าน packet จาก broker และส่ง ack ไปยังผู้ที่รออยู่ จนกว่าการเชื่อมต่อจะหลุด
+//
+// ถ้าไม่ได้รับ packet ใด ๆ (รวม PINGRESP) นานเกิน 1.5 เท่าของ keepalive ถือว่าการเชื่อมต่อหลุด
+func (c *Client) readLoop(br *bufio.Reader) {
+	for {
+		c.conn.SetReadDeadline(time.Now().Add(c.opts.KeepAlive * 3 / 2))
+		kind, body, err := readPacket(br)
+		if err != nil {
+			c.fail(err)
+			return
+		}
+
+		switch kind >> 4 {
+		case PACKET_PUBACK, PACKET_PUBREC, PACKET_PUBCOMP:
+			if len(body) != 2 {
+				c.fail(ErrProtocol)
+				return
+			}
+			id := binary.BigEndian.Uint16(body)
+			c.mu.Lock()
+			acks := c.inflight[id]
+			c.mu.Unlock()
+			if acks != nil {
+				select {
+				case acks <- kind >> 4:
+				default:
+				}
+			}
+		case PACKET_PINGRESP:
+		default:
+			// client นี้ไม่ได้ subscribe จึงไม่ควรได้รับ packet ชนิดอื่น
+			c.fail(ErrProtocol)
+			return
+		}
+	}
+}
+
+// ส่ง PINGREQ ทุกครึ่งหนึ่งของ keepalive จนกว่าการเชื่อมต่อจะปิด
+func (c *Client) keepAlive() {
+	ticker := time.NewTicker(c.opts.KeepAlive / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			if err := c.writePacket(PACKET_PINGREQ<<4, nil); err != nil {
+				c.fail(err)
+				return
+			}
+		}
+	}
+}
+
+// บันทึกสาเหตุ ปิดการเชื่อมต่อ และปลุกทุกคนที่รออยู่ (เรียกซ้ำได้ ใช้สาเหตุแรก)
+func (c *Client) fail(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil {
+		return
+	}
+	c.err = err
+	c.conn.Close()
+	close(c.done)
+}
+
+// ปิดเมื่อการเชื่อมต่อหลุดหรือถูกปิด
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// สาเหตุที่การเชื่อมต่อสิ้นสุด (nil ระหว่างยังเชื่อมต่ออยู่)
+func (c *Client) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// ส่ง DISCONNECT แล้วปิดการเชื่อมต่อ (broker จะไม่ publish will message)
+func (c *Client) Close() error {
+	c.conn.SetWriteDeadline(time.Now().Add(time.Second))
+	err := c.writePacket(PACKET_DISCONNECT<<4, nil)
+	c.fail(ErrClosed)
+	return err
+}
+
+// เขียน packet หนึ่ง packet (fixed header + body)
+func (c *Client) writePacket(header byte, body []byte) error {
+	if len(body) > MAX_PACKET_SIZE {
+		return fmt.Errorf("mqtt: packet ยาว %d bytes เกินกำหนด", len(body))
+	}
+	packet := make([]byte, 0, 5+len(body))
+	packet = append(packet, header)
+	for n := len(body); ; {
+		b := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			b |= 0x80
+		}
+		packet = append(packet, b)
+		if n == 0 {
+			break
+		}
+	}
+	packet = append(packet, body...)
+
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	_, err := c.conn.Write(packet)
+	return err
+}
+
+// อ่าน packet หนึ่ง packet คืนค่า byte แรกของ fixed header และ body
+func readPacket(br *bufio.Reader) (byte, []byte, error) {
+	header, err := br.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		if i == 4 {
+			return 0, nil, ErrProtocol
+		}
+		b, err := br.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		length += int(b&0x7F) * multiplier
+		multiplier *= 128
+		if b&0x80 == 0 {
+			break
+		}
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(br, body); err != nil {
+		return 0, nil, err
+	}
+	return header, body, nil
+}
+
+// ต่อ string แบบ MQTT (ความยาว uint16 + UTF-8)
+func appendString(b []byte, s string) []byte {
+	return appendBytes(b, []byte(s))
+}
+
+func appendBytes(b []byte, data []byte) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(data)))
+	return append(b, data...)
+}
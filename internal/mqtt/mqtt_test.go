@@ -0,0 +1,237 @@
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+// broker จำลองบนปลายหนึ่งของ net.Pipe ตอบ CONNECT, PUBLISH (QoS 0/1/2) และ PINGREQ
+type fakeBroker struct {
+	t        *testing.T
+	conn     net.Conn
+	br       *bufio.Reader
+	connack  byte // return code ของ CONNACK
+	noAck    bool // ไม่ตอบรับ PUBLISH เพื่อทดสอบ ack timeout
+	connect  chan []byte
+	publish  chan publishPacket
+	released chan uint16 // packet id ของ PUBREL ที่ได้รับ
+}
+
+type publishPacket struct {
+	header  byte
+	id      uint16
+	topic   string
+	payload []byte
+}
+
+func newFakeBroker(t *testing.T, conn net.Conn) *fakeBroker {
+	return &fakeBroker{
+		t:        t,
+		conn:     conn,
+		br:       bufio.NewReader(conn),
+		connect:  make(chan []byte, 1),
+		publish:  make(chan publishPacket, 8),
+		released: make(chan uint16, 8),
+	}
+}
+
+func (b *fakeBroker) write(header byte, body []byte) {
+	packet := append([]byte{header, byte(len(body))}, body...)
+	if _, err := b.conn.Write(packet); err != nil {
+		b.t.Errorf("broker: เขียน packet 0x%02x ไม่สำเร็จ: %v", header, err)
+	}
+}
+
+// อ่านและตอบ packet จนกว่าการเชื่อมต่อจะปิดหรือได้รับ DISCONNECT
+func (b *fakeBroker) serve() {
+	defer b.conn.Close()
+	for {
+		header, body, err := readPacket(b.br)
+		if err != nil {
+			return
+		}
+		switch header >> 4 {
+		case PACKET_CONNECT:
+			b.connect <- body
+			b.write(PACKET_CONNACK<<4, []byte{0, b.connack})
+			if b.connack != 0 {
+				return
+			}
+		case PACKET_PUBLISH:
+			qos := header >> 1 & 0x03
+			topicLen := int(binary.BigEndian.Uint16(body))
+			p := publishPacket{header: header, topic: string(body[2 : 2+topicLen])}
+			rest := body[2+topicLen:]
+			if qos > 0 {
+				p.id = binary.BigEndian.Uint16(rest)
+				rest = rest[2:]
+			}
+			p.payload = rest
+			b.publish <- p
+			if b.noAck {
+				continue
+			}
+			switch qos {
+			case 1:
+				b.write(PACKET_PUBACK<<4, binary.BigEndian.AppendUint16(nil, p.id))
+			case 2:
+				b.write(PACKET_PUBREC<<4, binary.BigEndian.AppendUint16(nil, p.id))
+			}
+		case PACKET_PUBREL:
+			if header != PACKET_PUBREL<<4|0x02 {
+				b.t.Errorf("broker: flags ของ PUBREL = 0x%02x ต้องเป็น 0x62", header)
+			}
+			id := binary.BigEndian.Uint16(body)
+			b.released <- id
+			b.write(PACKET_PUBCOMP<<4, body)
+		case PACKET_PINGREQ:
+			b.write(PACKET_PINGRESP<<4, nil)
+		case PACKET_DISCONNECT:
+			return
+		default:
+			b.t.Errorf("broker: ไม่คาดว่าจะได้รับ packet 0x%02x", header)
+			return
+		}
+	}
+}
+
+// เชื่อมต่อ client กับ broker จำลอง
+func connectFake(t *testing.T, opts Options, setup func(*fakeBroker)) (*Client, *fakeBroker, error) {
+	t.Helper()
+	clientConn, brokerConn := net.Pipe()
+	broker := newFakeBroker(t, brokerConn)
+	if setup != nil {
+		setup(broker)
+	}
+	go broker.serve()
+	client, err := NewClient(clientConn, opts)
+	if err == nil {
+		t.Cleanup(func() { client.Close() })
+	}
+	return client, broker, err
+}
+
+func TestConnectPacket(t *testing.T) {
+	opts := Options{
+		ClientID:  "dcn-proxy-test",
+		Username:  "user",
+		Password:  "secret",
+		KeepAlive: 20 * time.Second,
+		Will:      &Message{Topic: "dcn/room/status", Payload: []byte("offline"), QoS: 1, Retain: true},
+	}
+	_, broker, err := connectFake(t, opts, nil)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	body := <-broker.connect
+	want := appendString(nil, "MQTT")
+	want = append(want, 4, 0x80|0x40|0x20|1<<3|0x04|0x02)
+	want = binary.BigEndian.AppendUint16(want, 20)
+	want = appendString(want, "dcn-proxy-test")
+	want = appendString(want, "dcn/room/status")
+	want = appendBytes(want, []byte("offline"))
+	want = appendString(want, "user")
+	want = appendString(want, "secret")
+	if string(body) != string(want) {
+		t.Errorf("CONNECT body\n got % x\nwant % x", body, want)
+	}
+}
+
+func TestConnectWithoutCredentials(t *testing.T) {
+	_, broker, err := connectFake(t, Options{ClientID: "c"}, nil)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	body := <-broker.connect
+	if flags := body[7]; flags != 0x02 {
+		t.Errorf("connect flags = 0x%02x ต้องเป็น 0x02 (clean session เท่านั้น)", flags)
+	}
+}
+
+func TestConnectPasswordWithoutUsername(t *testing.T) {
+	clientConn, brokerConn := net.Pipe()
+	defer brokerConn.Close()
+	if _, err := NewClient(clientConn, Options{ClientID: "c", Password: "secret"}); !errors.Is(err, ErrPassword) {
+		t.Fatalf("NewClient error = %v ต้องเป็น ErrPassword", err)
+	}
+}
+
+func TestConnectRefused(t *testing.T) {
+	_, _, err := connectFake(t, Options{ClientID: "c", Username: "u", Password: "wrong"}, func(b *fakeBroker) { b.connack = 4 })
+	var connectErr *ConnectError
+	if !errors.As(err, &connectErr) || connectErr.Code != 4 {
+		t.Fatalf("NewClient error = %v ต้องเป็น ConnectError code 4", err)
+	}
+}
+
+func TestPublish(t *testing.T) {
+	for _, qos := range []byte{0, 1, 2} {
+		client, broker, err := connectFake(t, Options{ClientID: "c"}, nil)
+		if err != nil {
+			t.Fatalf("QoS %d: NewClient: %v", qos, err)
+		}
+		<-broker.connect
+
+		msg := Message{Topic: "dcn/room/seat/7", Payload: []byte(`{"mic":true}`), QoS: qos, Retain: true}
+		if err := client.Publish(msg); err != nil {
+			t.Fatalf("QoS %d: Publish: %v", qos, err)
+		}
+
+		p := <-broker.publish
+		if wantHeader := byte(PACKET_PUBLISH<<4) | qos<<1 | 0x01; p.header != wantHeader {
+			t.Errorf("QoS %d: header = 0x%02x ต้องเป็น 0x%02x", qos, p.header, wantHeader)
+		}
+		if p.topic != msg.Topic || string(p.payload) != string(msg.Payload) {
+			t.Errorf("QoS %d: ได้รับ %q %q", qos, p.topic, p.payload)
+		}
+		if qos > 0 && p.id == 0 {
+			t.Errorf("QoS %d: packet id ต้องไม่เป็น 0", qos)
+		}
+		if qos == 2 {
+			// Publish คืนค่าหลังได้รับ PUBCOMP จึงต้องส่ง PUBREL ของ packet เดียวกันแล้ว
+			select {
+			case id := <-broker.released:
+				if id != p.id {
+					t.Errorf("PUBREL packet id = %d ต้องเป็น %d", id, p.id)
+				}
+			default:
+				t.Error("ไม่ได้ส่ง PUBREL")
+			}
+		}
+	}
+}
+
+func TestPublishAckTimeout(t *testing.T) {
+	client, broker, err := connectFake(t, Options{ClientID: "c", AckTimeout: 50 * time.Millisecond}, func(b *fakeBroker) { b.noAck = true })
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	<-broker.connect
+
+	if err := client.Publish(Message{Topic: "t", QoS: 1}); !errors.Is(err, ErrAckTimeout) {
+		t.Fatalf("Publish error = %v ต้องเป็น ErrAckTimeout", err)
+	}
+}
+
+func TestDisconnectClosesClient(t *testing.T) {
+	client, broker, err := connectFake(t, Options{ClientID: "c"}, nil)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	<-broker.connect
+
+	client.Close()
+	select {
+	case <-client.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Done ไม่ถูกปิดหลัง Close")
+	}
+	if !errors.Is(client.Err(), ErrClosed) {
+		t.Errorf("Err = %v ต้องเป็น ErrClosed", client.Err())
+	}
+}
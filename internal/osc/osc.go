@@ -0,0 +1,132 @@
+// Package osc สร้างและส่งข้อความ Open Sound Control 1.0 ผ่าน UDP เท่าที่ proxy ต้องใช้ควบคุม mixing console
+//
+// รองรับ argument ชนิด int32 (i), float32 (f), string (s) และ true/false (T/F) ไม่รองรับ bundle
+package osc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ข้อความ OSC หนึ่งข้อความ Args เป็น int32, float32, string หรือ bool
+type Message struct {
+	Address string
+	Args    []any
+}
+
+// แสดงข้อความในรูปแบบที่อ่านได้ เช่น /ch/01/mix/on ,i 1
+func (m Message) String() string {
+	tags, _ := typeTags(m.Args)
+	parts := []string{m.Address, tags}
+	for _, arg := range m.Args {
+		switch v := arg.(type) {
+		case string:
+			parts = append(parts, strconv.Quote(v))
+		case bool:
+			// ค่าอยู่ใน type tag แล้ว
+		default:
+			parts = append(parts, fmt.Sprint(v))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func typeTags(args []any) (string, error) {
+	tags := []byte{','}
+	for _, arg := range args {
+		switch v := arg.(type) {
+		case int32:
+			tags = append(tags, 'i')
+		case float32:
+			tags = append(tags, 'f')
+		case string:
+			tags = append(tags, 's')
+		case bool:
+			if v {
+				tags = append(tags, 'T')
+			} else {
+				tags = append(tags, 'F')
+			}
+		default:
+			return "", fmt.Errorf("osc: ไม่รองรับ argument ชนิด %T", arg)
+		}
+	}
+	return string(tags), nil
+}
+
+// สร้าง packet ของข้อความ: address, type tag และ argument ทั้งหมดจัดให้ยาวเป็นพหุคูณของ 4 bytes
+func Encode(m Message) ([]byte, error) {
+	if !strings.HasPrefix(m.Address, "/") {
+		return nil, fmt.Errorf("osc: address %q ต้องขึ้นต้นด้วย /", m.Address)
+	}
+	tags, err := typeTags(m.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	packet := appendString(nil, m.Address)
+	packet = appendString(packet, tags)
+	for _, arg := range m.Args {
+		switch v := arg.(type) {
+		case int32:
+			packet = binary.BigEndian.AppendUint32(packet, uint32(v))
+		case float32:
+			packet = binary.BigEndian.AppendUint32(packet, math.Float32bits(v))
+		case string:
+			packet = appendString(packet, v)
+		}
+	}
+	return packet, nil
+}
+
+// string ของ OSC ปิดท้ายด้วย null อย่างน้อยหนึ่ง byte และเติม null จนยาวเป็นพหุคูณของ 4
+func appendString(packet []byte, s string) []byte {
+	packet = append(packet, s...)
+	padding := 4 - len(s)%4
+	for range padding {
+		packet = append(packet, 0)
+	}
+	return packet
+}
+
+// Client ส่งข้อความไปยังปลายทางหนึ่งแห่งผ่าน UDP
+type Client struct {
+	mu   sync.Mutex
+	conn *net.UDPConn
+	addr *net.UDPAddr
+}
+
+func Dial(addr string) (*Client, error) {
+	// ใช้ socket ที่ไม่ได้ connect เพราะ ICMP port unreachable จาก receiver ที่ยังไม่เปิด
+	// จะทำให้การส่งครั้งถัดไปของ socket ที่ connect แล้วล้มเหลวสลับกันไป
+	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenUDP("udp", nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{conn: conn, addr: udpAddr}, nil
+}
+
+// ส่งข้อความหนึ่งข้อความต่อหนึ่ง datagram
+func (c *Client) Send(m Message) error {
+	packet, err := Encode(m)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err = c.conn.WriteToUDP(packet, c.addr)
+	return err
+}
+
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,379 @@
+// Package pcap อ่านไฟล์ pcap/pcapng และเขียน traffic ของ DCN เป็นไฟล์ pcapng ที่เปิดด้วย Wireshark ได้
+package pcap
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"time"
+)
+
+// link type ที่อ่านได้ (https://www.tcpdump.org/linktypes.html)
+const (
+	linkTypeNull     = 0
+	linkTypeEthernet = 1
+	linkTypeRaw      = 101
+	linkTypeLoop     = 108
+	linkTypeLinuxSLL = 113
+	linkTypeIPv4     = 228
+	linkTypeIPv6     = 229
+	linkTypeSLL2     = 276
+
+	pcapMagicMicro = 0xA1B2C3D4
+	pcapMagicNano  = 0xA1B23C4D
+
+	pcapngBlockSPB    = 0x00000003
+	pcapngBlockPacket = 0x00000002 // Packet Block แบบเก่า
+
+	pcapMaxPacket = 256 << 10
+)
+
+var ErrFormat = errors.New("capture: รูปแบบไฟล์ไม่ถูกต้อง")
+
+// packet หนึ่งตัวที่อ่านจากไฟล์ capture
+type Packet struct {
+	Time     time.Time
+	LinkType uint32
+	Data     []byte
+}
+
+// interface ใน section ปัจจุบันของ pcapng
+type pcapngInterface struct {
+	linkType uint32
+	tsUnit   float64 // วินาทีต่อหนึ่งหน่วยของ timestamp
+	tsOffset int64   // วินาทีที่ต้องบวกเพิ่ม
+}
+
+// Reader อ่าน packet จากไฟล์ pcap หรือ pcapng
+type Reader struct {
+	r     *bufio.Reader
+	order binary.ByteOrder
+	ng    bool
+
+	// pcap
+	linkType uint32
+	nano     bool
+
+	// pcapng
+	interfaces []pcapngInterface
+	lastTime   time.Time
+}
+
+// เปิด reader โดยตรวจรูปแบบจาก magic number ของไฟล์
+func NewReader(r io.Reader) (*Reader, error) {
+	c := &Reader{r: bufio.NewReaderSize(r, 64<<10)}
+
+	magic, err := c.r.Peek(4)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFormat, err)
+	}
+
+	if binary.LittleEndian.Uint32(magic) == pcapngBlockSHB {
+		c.ng = true
+		return c, nil
+	}
+
+	var header [24]byte
+	if _, err := io.ReadFull(c.r, header[:]); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFormat, err)
+	}
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		switch order.Uint32(header[0:4]) {
+		case pcapMagicMicro:
+			c.order = order
+		case pcapMagicNano:
+			c.order, c.nano = order, true
+		}
+	}
+	if c.order == nil {
+		return nil, fmt.Errorf("%w: magic %x", ErrFormat, header[0:4])
+	}
+	c.linkType = c.order.Uint32(header[20:24]) & 0xFFFF
+	return c, nil
+}
+
+// อ่าน packet ถัดไป คืนค่า io.EOF เมื่อจบไฟล์
+func (c *Reader) Next() (Packet, error) {
+	if c.ng {
+		return c.nextPcapng()
+	}
+
+	var header [16]byte
+	if _, err := io.ReadFull(c.r, header[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return Packet{}, io.EOF
+		}
+		return Packet{}, err
+	}
+	sec := int64(c.order.Uint32(header[0:4]))
+	frac := int64(c.order.Uint32(header[4:8]))
+	length := c.order.Uint32(header[8:12])
+	if length > pcapMaxPacket {
+		return Packet{}, fmt.Errorf("%w: packet ยาว %d bytes", ErrFormat, length)
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(c.r, data); err != nil {
+		// packet สุดท้ายไม่ครบ (capture ถูกตัดกลางคัน)
+		return Packet{}, io.EOF
+	}
+
+	if !c.nano {
+		frac *= 1000
+	}
+	return Packet{Time: time.Unix(sec, frac), LinkType: c.linkType, Data: data}, nil
+}
+
+func (c *Reader) nextPcapng() (Packet, error) {
+	for {
+		var head [8]byte
+		if _, err := io.ReadFull(c.r, head[:]); err != nil {
+			if err == io.ErrUnexpectedEOF {
+				return Packet{}, io.EOF
+			}
+			return Packet{}, err
+		}
+
+		// Section Header Block กำหนด byte order ของ section ใหม่
+		if binary.LittleEndian.Uint32(head[0:4]) == pcapngBlockSHB {
+			bom, err := c.r.Peek(4)
+			if err != nil {
+				return Packet{}, io.EOF
+			}
+			switch {
+			case binary.LittleEndian.Uint32(bom) == pcapngByteOrder:
+				c.order = binary.LittleEndian
+			case binary.BigEndian.Uint32(bom) == pcapngByteOrder:
+				c.order = binary.BigEndian
+			default:
+				return Packet{}, fmt.Errorf("%w: byte order %x", ErrFormat, bom)
+			}
+			c.interfaces = nil
+		}
+		if c.order == nil {
+			return Packet{}, fmt.Errorf("%w: ไม่พบ Section Header Block", ErrFormat)
+		}
+
+		blockType := c.order.Uint32(head[0:4])
+		length := c.order.Uint32(head[4:8])
+		if length < 12 || length%4 != 0 || length > pcapMaxPacket+1024 {
+			return Packet{}, fmt.Errorf("%w: block ยาว %d bytes", ErrFormat, length)
+		}
+		body := make([]byte, length-8)
+		if _, err := io.ReadFull(c.r, body); err != nil {
+			return Packet{}, io.EOF
+		}
+		body = body[:len(body)-4] // ตัดความยาวที่ซ้ำท้าย block
+
+		switch blockType {
+		case pcapngBlockIDB:
+			if len(body) < 8 {
+				return Packet{}, fmt.Errorf("%w: Interface Description Block สั้นเกินไป", ErrFormat)
+			}
+			iface := pcapngInterface{linkType: uint32(c.order.Uint16(body[0:2])), tsUnit: 1e-6}
+			c.parseInterfaceOptions(&iface, body[8:])
+			c.interfaces = append(c.interfaces, iface)
+
+		case pcapngBlockEPB, pcapngBlockPacket:
+			if len(body) < 20 {
+				return Packet{}, fmt.Errorf("%w: Packet Block สั้นเกินไป", ErrFormat)
+			}
+			var id uint32
+			if blockType == pcapngBlockEPB {
+				id = c.order.Uint32(body[0:4])
+			} else {
+				id = uint32(c.order.Uint16(body[0:2]))
+			}
+			if int(id) >= len(c.interfaces) {
+				return Packet{}, fmt.Errorf("%w: interface %d ไม่มีอยู่", ErrFormat, id)
+			}
+			iface := c.interfaces[id]
+			ts := uint64(c.order.Uint32(body[4:8]))<<32 | uint64(c.order.Uint32(body[8:12]))
+			capLen := c.order.Uint32(body[12:16])
+			if int(capLen) > len(body)-20 {
+				return Packet{}, fmt.Errorf("%w: packet ยาวเกิน block", ErrFormat)
+			}
+			c.lastTime = iface.time(ts)
+			return Packet{Time: c.lastTime, LinkType: iface.linkType, Data: body[20 : 20+capLen]}, nil
+
+		case pcapngBlockSPB:
+			// Simple Packet Block ไม่มีเวลา ใช้เวลาของ packet ก่อนหน้า
+			if len(body) < 4 || len(c.interfaces) == 0 {
+				return Packet{}, fmt.Errorf("%w: Simple Packet Block ไม่ถูกต้อง", ErrFormat)
+			}
+			origLen := int(c.order.Uint32(body[0:4]))
+			data := body[4:]
+			if origLen < len(data) {
+				data = data[:origLen]
+			}
+			return Packet{Time: c.lastTime, LinkType: c.interfaces[0].linkType, Data: data}, nil
+		}
+		// block ประเภทอื่น (statistics, name resolution ฯลฯ) ข้ามไป
+	}
+}
+
+// อ่าน if_tsresol และ if_tsoffset จาก options ของ interface
+func (c *Reader) parseInterfaceOptions(iface *pcapngInterface, opts []byte) {
+	for len(opts) >= 4 {
+		code := c.order.Uint16(opts[0:2])
+		length := int(c.order.Uint16(opts[2:4]))
+		if 4+length > len(opts) {
+			return
+		}
+		value := opts[4 : 4+length]
+		switch {
+		case code == pcapngOptEnd:
+			return
+		case code == pcapngOptIfTsresol && length >= 1:
+			if value[0]&0x80 != 0 {
+				iface.tsUnit = math.Pow(2, -float64(value[0]&0x7F))
+			} else {
+				iface.tsUnit = math.Pow(10, -float64(value[0]))
+			}
+		case code == 14 && length >= 8: // if_tsoffset
+			iface.tsOffset = int64(c.order.Uint64(value))
+		}
+		opts = opts[4+length+pcapngPad(length):]
+	}
+}
+
+// แปลง timestamp ของ interface เป็นเวลา
+func (i pcapngInterface) time(ts uint64) time.Time {
+	// หน่วยที่ละเอียดกว่า nanosecond ไม่ได้ใช้ในทางปฏิบัติ แปลงผ่านจำนวนเต็มเพื่อไม่ให้เสียความละเอียด
+	switch i.tsUnit {
+	case 1e-6:
+		return time.Unix(i.tsOffset, int64(ts)*1000)
+	case 1e-9:
+		return time.Unix(i.tsOffset, int64(ts))
+	}
+	sec := float64(ts) * i.tsUnit
+	whole := math.Floor(sec)
+	return time.Unix(i.tsOffset+int64(whole), int64((sec-whole)*1e9))
+}
+
+// flag ของ TCP
+const (
+	TCP_FIN = 0x01
+	TCP_SYN = 0x02
+	TCP_PSH = 0x08
+	TCP_ACK = 0x10
+)
+
+// TCP segment ที่แยกออกมาจาก packet
+type Segment struct {
+	Src, Dst net.TCPAddr
+	Seq      uint32
+	Flags    byte
+	Payload  []byte
+}
+
+// แยก IP และ TCP ออกจาก packet คืนค่า false ถ้าไม่ใช่ TCP หรือเป็น fragment
+func DecodeTCP(p Packet) (Segment, bool) {
+	ip, ok := linkPayload(p.LinkType, p.Data)
+	if !ok || len(ip) < 1 {
+		return Segment{}, false
+	}
+
+	var seg Segment
+	var tcp []byte
+	switch ip[0] >> 4 {
+	case 4:
+		ihl := int(ip[0]&0x0F) * 4
+		if len(ip) < 20 || ihl < 20 || len(ip) < ihl || ip[9] != 6 {
+			return Segment{}, false
+		}
+		// ข้าม fragment (DCN ส่งผ่าน TCP จึงไม่ควรมี)
+		if binary.BigEndian.Uint16(ip[6:8])&0x3FFF != 0 {
+			return Segment{}, false
+		}
+		total := int(binary.BigEndian.Uint16(ip[2:4]))
+		if total >= ihl && total < len(ip) {
+			ip = ip[:total] // ตัด padding ของ Ethernet
+		}
+		seg.Src.IP = net.IP(append([]byte(nil), ip[12:16]...))
+		seg.Dst.IP = net.IP(append([]byte(nil), ip[16:20]...))
+		tcp = ip[ihl:]
+	case 6:
+		if len(ip) < 40 {
+			return Segment{}, false
+		}
+		payloadLen := int(binary.BigEndian.Uint16(ip[4:6]))
+		if 40+payloadLen < len(ip) {
+			ip = ip[:40+payloadLen]
+		}
+		seg.Src.IP = net.IP(append([]byte(nil), ip[8:24]...))
+		seg.Dst.IP = net.IP(append([]byte(nil), ip[24:40]...))
+		next, rest := ip[6], ip[40:]
+		// ข้าม extension headers
+		for next == 0 || next == 43 || next == 60 {
+			if len(rest) < 8 {
+				return Segment{}, false
+			}
+			size := (int(rest[1]) + 1) * 8
+			if len(rest) < size {
+				return Segment{}, false
+			}
+			next, rest = rest[0], rest[size:]
+		}
+		if next != 6 {
+			return Segment{}, false
+		}
+		tcp = rest
+	default:
+		return Segment{}, false
+	}
+
+	if len(tcp) < 20 {
+		return Segment{}, false
+	}
+	offset := int(tcp[12]>>4) * 4
+	if offset < 20 || len(tcp) < offset {
+		return Segment{}, false
+	}
+	seg.Src.Port = int(binary.BigEndian.Uint16(tcp[0:2]))
+	seg.Dst.Port = int(binary.BigEndian.Uint16(tcp[2:4]))
+	seg.Seq = binary.BigEndian.Uint32(tcp[4:8])
+	seg.Flags = tcp[13]
+	seg.Payload = tcp[offset:]
+	return seg, true
+}
+
+// ตัด header ของ link layer ออกเหลือ IP packet
+func linkPayload(linkType uint32, data []byte) ([]byte, bool) {
+	switch linkType {
+	case linkTypeRaw, linkTypeIPv4, linkTypeIPv6, 12, 14: // 12, 14 = raw IP บน OpenBSD
+		return data, true
+	case linkTypeNull, linkTypeLoop:
+		if len(data) < 4 {
+			return nil, false
+		}
+		return data[4:], true
+	case linkTypeEthernet:
+		if len(data) < 14 {
+			return nil, false
+		}
+		etherType, rest := binary.BigEndian.Uint16(data[12:14]), data[14:]
+		// ข้าม VLAN tag
+		for (etherType == 0x8100 || etherType == 0x88A8) && len(rest) >= 4 {
+			etherType, rest = binary.BigEndian.Uint16(rest[2:4]), rest[4:]
+		}
+		return rest, etherType == 0x0800 || etherType == 0x86DD
+	case linkTypeLinuxSLL:
+		if len(data) < 16 {
+			return nil, false
+		}
+		protocol := binary.BigEndian.Uint16(data[14:16])
+		return data[16:], protocol == 0x0800 || protocol == 0x86DD
+	case linkTypeSLL2:
+		if len(data) < 20 {
+			return nil, false
+		}
+		protocol := binary.BigEndian.Uint16(data[0:2])
+		return data[20:], protocol == 0x0800 || protocol == 0x86DD
+	}
+	return nil, false
+}
@@ -0,0 +1,308 @@
+package pcap
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// รูปแบบไฟล์ pcapng (https://www.ietf.org/archive/id/draft-ietf-opsawg-pcapng-02.html)
+//
+// ไฟล์มี Section Header Block หนึ่งบล็อก, Interface Description Block หนึ่งบล็อก (LINKTYPE_RAW
+// ความละเอียดเวลา nanosecond) และ Enhanced Packet Block หนึ่งบล็อกต่อ packet
+// packet แต่ละตัวเป็น IPv4 หรือ IPv6 + TCP ที่สร้างขึ้นจาก endpoint จริงของการเชื่อมต่อ
+// พร้อม handshake, sequence/ack number และ FIN เพื่อให้ Wireshark ประกอบ stream ได้
+const (
+	pcapngBlockSHB     = 0x0A0D0D0A
+	pcapngBlockIDB     = 0x00000001
+	pcapngBlockEPB     = 0x00000006
+	pcapngByteOrder    = 0x1A2B3C4D
+	pcapngLinkTypeRaw  = 101
+	pcapngOptEnd       = 0
+	pcapngOptUserAppl  = 4
+	pcapngOptIfName    = 2
+	pcapngOptIfTsresol = 9
+
+	// payload สูงสุดต่อ packet ให้ total length ของ IPv4 ไม่เกิน 65535
+	pcapngMaxSegment = 65535 - 20 - 20
+)
+
+// สถานะ sequence number ของการเชื่อมต่อ TCP หนึ่งเส้น
+type pcapngFlow struct {
+	addrs [2]net.Addr       // ฝั่งที่เปิดการเชื่อมต่อ (ส่ง SYN) และฝั่งที่รับ
+	seq   map[string]uint32 // sequence number ถัดไปของแต่ละฝั่ง
+}
+
+// Writer เขียน traffic ของ proxy เป็นไฟล์ pcapng ที่เปิดด้วย Wireshark ได้
+type Writer struct {
+	mu    sync.Mutex
+	file  *os.File
+	w     *bufio.Writer
+	flows map[string]*pcapngFlow
+	ipID  uint16
+}
+
+// สร้างไฟล์ pcapng ใหม่ (เขียนทับถ้ามีอยู่แล้ว)
+func Create(path string) (*Writer, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Writer{file: f, w: bufio.NewWriter(f), flows: make(map[string]*pcapngFlow)}
+
+	// Section Header Block: byte order, version 1.0, ไม่ระบุความยาว section
+	shb := binary.LittleEndian.AppendUint32(nil, pcapngByteOrder)
+	shb = binary.LittleEndian.AppendUint16(shb, 1)
+	shb = binary.LittleEndian.AppendUint16(shb, 0)
+	shb = binary.LittleEndian.AppendUint64(shb, 0xFFFFFFFFFFFFFFFF)
+	shb = appendPcapngOption(shb, pcapngOptUserAppl, []byte("phi-DCN proxy"))
+	shb = appendPcapngOption(shb, pcapngOptEnd, nil)
+
+	// Interface Description Block: raw IP, ไม่จำกัด snaplen, เวลาเป็น nanosecond
+	idb := binary.LittleEndian.AppendUint16(nil, pcapngLinkTypeRaw)
+	idb = binary.LittleEndian.AppendUint16(idb, 0)
+	idb = binary.LittleEndian.AppendUint32(idb, 0)
+	idb = appendPcapngOption(idb, pcapngOptIfName, []byte("dcn-proxy"))
+	idb = appendPcapngOption(idb, pcapngOptIfTsresol, []byte{9})
+	idb = appendPcapngOption(idb, pcapngOptEnd, nil)
+
+	p.writeBlock(pcapngBlockSHB, shb)
+	p.writeBlock(pcapngBlockIDB, idb)
+	if err := p.w.Flush(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return p, nil
+}
+
+// บันทึกข้อมูลที่ส่งจาก src ไปยัง dst ถ้ายังไม่เคยเห็นการเชื่อมต่อนี้จะสร้าง handshake ให้ก่อน โดยถือว่า src เป็นฝั่งที่เปิดการเชื่อมต่อ
+func (p *Writer) Write(src, dst net.Addr, payload []byte, t time.Time) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	flow := p.flowLocked(src, dst, t)
+	for len(payload) > 0 {
+		n := min(len(payload), pcapngMaxSegment)
+		p.segmentLocked(flow, src, dst, TCP_PSH|TCP_ACK, payload[:n], t)
+		payload = payload[n:]
+	}
+	return p.w.Flush()
+}
+
+// เริ่มการเชื่อมต่อจาก initiator ไปยัง responder (เขียน SYN, SYN/ACK, ACK)
+func (p *Writer) OpenFlow(initiator, responder net.Addr, t time.Time) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.flowLocked(initiator, responder, t)
+	return p.w.Flush()
+}
+
+// ปิดการเชื่อมต่อ โดย src เป็นฝั่งที่ปิดก่อน (เขียน FIN ทั้งสองทิศทาง)
+func (p *Writer) CloseFlow(src, dst net.Addr, t time.Time) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := pcapngFlowKey(src, dst)
+	flow, ok := p.flows[key]
+	if !ok {
+		return nil
+	}
+	p.finLocked(flow, src, dst, t)
+	delete(p.flows, key)
+	return p.w.Flush()
+}
+
+// ปิดการเชื่อมต่อที่ยังค้างอยู่ทั้งหมดแล้วปิดไฟล์
+func (p *Writer) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	for key, flow := range p.flows {
+		p.finLocked(flow, flow.addrs[0], flow.addrs[1], now)
+		delete(p.flows, key)
+	}
+
+	if err := p.w.Flush(); err != nil {
+		p.file.Close()
+		return err
+	}
+	return p.file.Close()
+}
+
+// หา flow ของ src/dst หรือสร้างใหม่พร้อม handshake (ต้องถือ mu อยู่)
+func (p *Writer) flowLocked(src, dst net.Addr, t time.Time) *pcapngFlow {
+	key := pcapngFlowKey(src, dst)
+	if flow, ok := p.flows[key]; ok {
+		return flow
+	}
+
+	flow := &pcapngFlow{
+		addrs: [2]net.Addr{src, dst},
+		seq:   map[string]uint32{src.String(): rand.Uint32(), dst.String(): rand.Uint32()},
+	}
+	p.flows[key] = flow
+
+	p.segmentLocked(flow, src, dst, TCP_SYN, nil, t)
+	flow.seq[src.String()]++
+	p.segmentLocked(flow, dst, src, TCP_SYN|TCP_ACK, nil, t)
+	flow.seq[dst.String()]++
+	p.segmentLocked(flow, src, dst, TCP_ACK, nil, t)
+	return flow
+}
+
+// เขียน FIN, FIN/ACK, ACK โดย src เป็นฝั่งที่ปิดก่อน (ต้องถือ mu อยู่)
+func (p *Writer) finLocked(flow *pcapngFlow, src, dst net.Addr, t time.Time) {
+	p.segmentLocked(flow, src, dst, TCP_FIN|TCP_ACK, nil, t)
+	flow.seq[src.String()]++
+	p.segmentLocked(flow, dst, src, TCP_FIN|TCP_ACK, nil, t)
+	flow.seq[dst.String()]++
+	p.segmentLocked(flow, src, dst, TCP_ACK, nil, t)
+}
+
+// เขียน TCP segment หนึ่งตัวและเลื่อน sequence number ของผู้ส่ง (ต้องถือ mu อยู่)
+func (p *Writer) segmentLocked(flow *pcapngFlow, src, dst net.Addr, flags byte, payload []byte, t time.Time) {
+	srcIP, srcPort := tcpEndpoint(src)
+	dstIP, dstPort := tcpEndpoint(dst)
+
+	ack := uint32(0)
+	if flags&TCP_ACK != 0 {
+		ack = flow.seq[dst.String()]
+	}
+
+	tcp := make([]byte, 20, 20+len(payload))
+	binary.BigEndian.PutUint16(tcp[0:2], srcPort)
+	binary.BigEndian.PutUint16(tcp[2:4], dstPort)
+	binary.BigEndian.PutUint32(tcp[4:8], flow.seq[src.String()])
+	binary.BigEndian.PutUint32(tcp[8:12], ack)
+	tcp[12] = 5 << 4
+	tcp[13] = flags
+	binary.BigEndian.PutUint16(tcp[14:16], 0xFFFF)
+	tcp = append(tcp, payload...)
+
+	var packet []byte
+	if src4, dst4 := srcIP.To4(), dstIP.To4(); src4 != nil && dst4 != nil {
+		p.ipID++
+		ip := make([]byte, 20)
+		ip[0] = 0x45
+		binary.BigEndian.PutUint16(ip[2:4], uint16(20+len(tcp)))
+		binary.BigEndian.PutUint16(ip[4:6], p.ipID)
+		binary.BigEndian.PutUint16(ip[6:8], 0x4000) // don't fragment
+		ip[8] = 64
+		ip[9] = 6
+		copy(ip[12:16], src4)
+		copy(ip[16:20], dst4)
+		binary.BigEndian.PutUint16(ip[10:12], internetChecksum(ip, 0))
+
+		pseudo := append(append([]byte(nil), src4...), dst4...)
+		pseudo = append(pseudo, 0, 6, byte(len(tcp)>>8), byte(len(tcp)))
+		binary.BigEndian.PutUint16(tcp[16:18], internetChecksum(tcp, pseudoSum(pseudo)))
+		packet = append(ip, tcp...)
+	} else {
+		src16, dst16 := srcIP.To16(), dstIP.To16()
+		ip := make([]byte, 40)
+		ip[0] = 0x60
+		binary.BigEndian.PutUint16(ip[4:6], uint16(len(tcp)))
+		ip[6] = 6
+		ip[7] = 64
+		copy(ip[8:24], src16)
+		copy(ip[24:40], dst16)
+
+		pseudo := append(append([]byte(nil), src16...), dst16...)
+		pseudo = binary.BigEndian.AppendUint32(pseudo, uint32(len(tcp)))
+		pseudo = append(pseudo, 0, 0, 0, 6)
+		binary.BigEndian.PutUint16(tcp[16:18], internetChecksum(tcp, pseudoSum(pseudo)))
+		packet = append(ip, tcp...)
+	}
+
+	flow.seq[src.String()] += uint32(len(payload))
+
+	// Enhanced Packet Block
+	ts := uint64(t.UnixNano())
+	epb := binary.LittleEndian.AppendUint32(nil, 0)
+	epb = binary.LittleEndian.AppendUint32(epb, uint32(ts>>32))
+	epb = binary.LittleEndian.AppendUint32(epb, uint32(ts))
+	epb = binary.LittleEndian.AppendUint32(epb, uint32(len(packet)))
+	epb = binary.LittleEndian.AppendUint32(epb, uint32(len(packet)))
+	epb = append(epb, packet...)
+	epb = append(epb, make([]byte, pcapngPad(len(packet)))...)
+	p.writeBlock(pcapngBlockEPB, epb)
+}
+
+// เขียน block พร้อมความยาวทั้งด้านหน้าและด้านหลัง (body ต้องยาวเป็นพหุคูณของ 4)
+func (p *Writer) writeBlock(blockType uint32, body []byte) {
+	length := uint32(12 + len(body))
+	var head [8]byte
+	binary.LittleEndian.PutUint32(head[0:4], blockType)
+	binary.LittleEndian.PutUint32(head[4:8], length)
+	p.w.Write(head[:])
+	p.w.Write(body)
+	p.w.Write(binary.LittleEndian.AppendUint32(nil, length))
+}
+
+func appendPcapngOption(b []byte, code uint16, value []byte) []byte {
+	b = binary.LittleEndian.AppendUint16(b, code)
+	b = binary.LittleEndian.AppendUint16(b, uint16(len(value)))
+	b = append(b, value...)
+	return append(b, make([]byte, pcapngPad(len(value)))...)
+}
+
+// จำนวน bytes ที่ต้องเติมให้ยาวเป็นพหุคูณของ 4
+func pcapngPad(n int) int {
+	return (4 - n%4) % 4
+}
+
+// key ของการเชื่อมต่อที่ไม่ขึ้นกับทิศทาง
+func pcapngFlowKey(a, b net.Addr) string {
+	x, y := a.String(), b.String()
+	if x > y {
+		x, y = y, x
+	}
+	return fmt.Sprintf("%s-%s", x, y)
+}
+
+// IP และ port ของ endpoint (net.Addr ที่ไม่ใช่ TCP ใช้ 0.0.0.0:0)
+func tcpEndpoint(addr net.Addr) (net.IP, uint16) {
+	if tcp, ok := addr.(*net.TCPAddr); ok {
+		return tcp.IP, uint16(tcp.Port)
+	}
+	if host, port, err := net.SplitHostPort(addr.String()); err == nil {
+		var portNum uint16
+		fmt.Sscan(port, &portNum)
+		if ip := net.ParseIP(host); ip != nil {
+			return ip, portNum
+		}
+	}
+	return net.IPv4zero, 0
+}
+
+// ผลรวมแบบ one's complement ของ pseudo header
+func pseudoSum(b []byte) uint32 {
+	var sum uint32
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(b[i])<<8 | uint32(b[i+1])
+	}
+	return sum
+}
+
+// checksum แบบ RFC 1071 โดยเริ่มจากผลรวม initial
+func internetChecksum(b []byte, initial uint32) uint16 {
+	sum := initial
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(b[i])<<8 | uint32(b[i+1])
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	for sum>>16 != 0 {
+		sum = sum&0xFFFF + sum>>16
+	}
+	return ^uint16(sum)
+}
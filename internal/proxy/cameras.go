@@ -0,0 +1,139 @@
+package proxy
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ampol-me/phi-DCN/internal/camera"
+)
+
+// สถานะของกล้องแต่ละตัวใน GET /director
+type CameraStatus struct {
+	Name   string     `json:"name"`
+	Type   string     `json:"type"`
+	Addr   string     `json:"addr"`
+	Preset int        `json:"preset,omitempty"` // preset ล่าสุดที่กล้องตอบรับ
+	Time   *time.Time `json:"time,omitempty"`   // เวลาที่ส่งคำสั่งล่าสุด
+	Error  string     `json:"error,omitempty"`  // error ของคำสั่งล่าสุด
+}
+
+// ส่งภาพที่ director เลือกไปยังกล้อง กล้องแต่ละตัวมี goroutine ของตัวเอง
+// และทำเฉพาะภาพล่าสุด (ภาพที่ยังไม่ได้ส่งถูกแทนที่เมื่อมีภาพใหม่ของกล้องเดียวกัน)
+type cameraSwitcher struct {
+	cameras map[string]*cameraWorker
+}
+
+type cameraWorker struct {
+	driver camera.Driver
+	shots  chan Shot // ภาพล่าสุดที่ยังไม่ได้ส่ง (ขนาด 1)
+
+	mu     sync.Mutex
+	status CameraStatus
+}
+
+// สร้าง driver ของกล้องทุกตัว
+func newCameraSwitcher(configs map[string]CameraConfig) (*cameraSwitcher, error) {
+	s := &cameraSwitcher{cameras: make(map[string]*cameraWorker)}
+	for name, cfg := range configs {
+		driver, err := camera.Open(camera.Config{
+			Type:     cfg.Type,
+			Addr:     cfg.Addr,
+			Username: cfg.Username,
+			Password: cfg.Password,
+			Timeout:  time.Duration(cfg.Timeout),
+		})
+		if err != nil {
+			s.close()
+			return nil, err
+		}
+		s.cameras[name] = &cameraWorker{
+			driver: driver,
+			shots:  make(chan Shot, 1),
+			status: CameraStatus{Name: name, Type: cfg.Type, Addr: cfg.Addr},
+		}
+	}
+	return s, nil
+}
+
+// รับภาพจาก director (เรียกจาก goroutine ของ director เท่านั้น)
+func (s *cameraSwitcher) handleShot(shot Shot) {
+	w := s.cameras[shot.Camera]
+	if w == nil {
+		return
+	}
+	select {
+	case stale := <-w.shots:
+		logCamera.Debug("camera.superseded", "camera", shot.Camera, "preset", stale.Preset)
+	default:
+	}
+	w.shots <- shot
+}
+
+// ส่งคำสั่งไปยังกล้องจนกว่า ctx จะถูกยกเลิก แล้วปิด driver ทั้งหมด
+func (s *cameraSwitcher) run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for name, w := range s.cameras {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.run(ctx, name)
+		}()
+	}
+	wg.Wait()
+	s.close()
+}
+
+func (s *cameraSwitcher) close() {
+	for _, w := range s.cameras {
+		w.driver.Close()
+	}
+}
+
+func (s *cameraSwitcher) status() []CameraStatus {
+	statuses := make([]CameraStatus, 0, len(s.cameras))
+	for _, w := range s.cameras {
+		w.mu.Lock()
+		statuses = append(statuses, w.status)
+		w.mu.Unlock()
+	}
+	slices.SortFunc(statuses, func(a, b CameraStatus) int { return strings.Compare(a.Name, b.Name) })
+	return statuses
+}
+
+func (w *cameraWorker) run(ctx context.Context, name string) {
+	for {
+		var shot Shot
+		select {
+		case shot = <-w.shots:
+		case <-ctx.Done():
+			return
+		}
+
+		start := time.Now()
+		err := w.driver.RecallPreset(ctx, shot.Preset)
+		if ctx.Err() != nil {
+			return
+		}
+
+		w.mu.Lock()
+		w.status.Time = &start
+		if err != nil {
+			w.status.Error = err.Error()
+		} else {
+			w.status.Preset = shot.Preset
+			w.status.Error = ""
+		}
+		w.mu.Unlock()
+
+		if err != nil {
+			logCamera.Warn("camera.recall_failed", "camera", name, "preset", shot.Preset, "error", err)
+			cameraCommands.Inc(name, "error")
+			continue
+		}
+		logCamera.Info("camera.recalled", "camera", name, "preset", shot.Preset, "seat", shot.Seat, "duration", time.Since(start).Round(time.Millisecond).String())
+		cameraCommands.Inc(name, "ok")
+	}
+}
@@ -0,0 +1,297 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ampol-me/phi-DCN/protocol"
+)
+
+// ไฟล์ capture เป็น JSON Lines หนึ่ง record ต่อหนึ่ง frame ที่ได้รับจาก upstream
+// แต่ละการเชื่อมต่อกับ upstream (session) เขียนลงไฟล์ของตัวเอง
+// ชื่อไฟล์คือ capture-<เวลาเริ่ม session>-<ลำดับไฟล์>.jsonl และเริ่มไฟล์ใหม่เมื่อเกิน MaxFileSize
+const (
+	CAPTURE_PREFIX = "capture-"
+	CAPTURE_EXT    = ".jsonl"
+)
+
+// การตั้งค่า capture
+type CaptureOptions struct {
+	Dir          string
+	MaxFileSize  int64 // ขนาดไฟล์สูงสุดก่อนเริ่มไฟล์ใหม่ของ session เดิม (0 = ไม่จำกัด)
+	MaxTotalSize int64 // ขนาดรวมสูงสุดของทุกไฟล์ (0 = ไม่จำกัด)
+}
+
+// frame หนึ่ง frame ในไฟล์ capture
+type CaptureRecord struct {
+	Time     time.Time `json:"time"`
+	Session  string    `json:"session"`
+	Seq      uint64    `json:"seq"`
+	Upstream string    `json:"upstream"`
+	Topic    uint32    `json:"topic"`
+	Length   uint32    `json:"length"`
+	Header   string    `json:"header"`           // 8 bytes แรกของ frame ในรูป hex
+	Encoding string    `json:"encoding"`         // utf-16le หรือ utf-8
+	Prefix   string    `json:"prefix,omitempty"` // ข้อความก่อน <?xml ในรูป hex (ถ้ามี)
+	XML      string    `json:"xml"`
+}
+
+// สถานะของ capture ที่แสดงผ่าน HTTP API
+type CaptureStatus struct {
+	Enabled      bool   `json:"enabled"`
+	Dir          string `json:"dir"`
+	Session      string `json:"session,omitempty"`
+	File         string `json:"file,omitempty"`
+	FileSize     int64  `json:"fileSize"`
+	Records      uint64 `json:"records"`
+	MaxFileSize  int64  `json:"maxFileSize"`
+	MaxTotalSize int64  `json:"maxTotalSize"`
+}
+
+// Capture บันทึก frame ที่ได้รับจาก upstream เป็นไฟล์ JSON Lines เปิด/ปิดได้ระหว่างทำงาน
+type Capture struct {
+	opts CaptureOptions
+
+	mu       sync.Mutex
+	enabled  bool
+	session  string // เวลาเริ่มของการเชื่อมต่อปัจจุบัน ว่างเมื่อไม่ได้เชื่อมต่อ upstream
+	upstream string
+	part     int
+	file     *os.File
+	path     string
+	size     int64
+	seq      uint64
+}
+
+// สร้าง Capture ที่เขียนลงไดเรกทอรีที่กำหนด (ยังไม่สร้างไฟล์จนกว่าจะมี frame แรก)
+func NewCapture(opts CaptureOptions, enabled bool) *Capture {
+	return &Capture{opts: opts, enabled: enabled}
+}
+
+// เริ่ม session ใหม่เมื่อเชื่อมต่อ upstream
+func (c *Capture) StartSession(upstream string, t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closeFileLocked()
+	c.session = t.UTC().Format("20060102T150405.000")
+	c.upstream = upstream
+	c.part = 0
+	c.seq = 0
+}
+
+// จบ session เมื่อการเชื่อมต่อ upstream หลุด
+func (c *Capture) EndSession() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closeFileLocked()
+	c.session = ""
+	c.upstream = ""
+}
+
+// เปิดหรือปิดการบันทึก
+func (c *Capture) SetEnabled(enabled bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.enabled == enabled {
+		return
+	}
+	c.enabled = enabled
+	if !enabled {
+		c.closeFileLocked()
+	}
+	if enabled {
+		logCapture.Info("capture.enabled", "dir", c.opts.Dir)
+	} else {
+		logCapture.Info("capture.disabled")
+	}
+}
+
+// บันทึก frame (header + payload) หนึ่ง frame ถ้าเปิดอยู่และอยู่ใน session
+func (c *Capture) Record(frame []byte, t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.enabled || c.session == "" {
+		return nil
+	}
+
+	// ไม่ escape < และ > เพื่อให้อ่าน XML ในไฟล์ได้โดยตรง
+	c.seq++
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(newCaptureRecord(c.session, c.seq, c.upstream, frame, t)); err != nil {
+		return err
+	}
+	line := buf.Bytes()
+
+	if c.file != nil && c.opts.MaxFileSize > 0 && c.size > 0 && c.size+int64(len(line)) > c.opts.MaxFileSize {
+		c.closeFileLocked()
+	}
+	if c.file == nil {
+		if err := c.openFileLocked(); err != nil {
+			return err
+		}
+	}
+
+	n, err := c.file.Write(line)
+	c.size += int64(n)
+	return err
+}
+
+// สถานะปัจจุบัน
+func (c *Capture) Status() CaptureStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return CaptureStatus{
+		Enabled:      c.enabled,
+		Dir:          c.opts.Dir,
+		Session:      c.session,
+		File:         c.path,
+		FileSize:     c.size,
+		Records:      c.seq,
+		MaxFileSize:  c.opts.MaxFileSize,
+		MaxTotalSize: c.opts.MaxTotalSize,
+	}
+}
+
+// ปิดไฟล์ปัจจุบัน
+func (c *Capture) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closeFileLocked()
+}
+
+// เริ่มไฟล์ถัดไปของ session แล้วลบไฟล์เก่าที่ทำให้ขนาดรวมเกิน (ต้องถือ mu อยู่)
+func (c *Capture) openFileLocked() error {
+	if err := os.MkdirAll(c.opts.Dir, 0o755); err != nil {
+		return err
+	}
+
+	c.part++
+	name := filepath.Join(c.opts.Dir, fmt.Sprintf("%s%s-%03d%s", CAPTURE_PREFIX, c.session, c.part, CAPTURE_EXT))
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+
+	c.file = f
+	c.path = name
+	c.size = 0
+	logCapture.Info("capture.opened", "file", name, "upstream", c.upstream)
+
+	c.enforceRetention(name)
+	return nil
+}
+
+func (c *Capture) closeFileLocked() {
+	if c.file == nil {
+		return
+	}
+	if err := c.file.Close(); err != nil {
+		logCapture.Warn("capture.write_failed", "file", c.path, "error", err)
+	}
+	c.file = nil
+	c.path = ""
+	c.size = 0
+}
+
+// ลบไฟล์เก่าสุดจนขนาดรวมไม่เกิน MaxTotalSize โดยไม่ลบไฟล์ปัจจุบัน
+func (c *Capture) enforceRetention(current string) {
+	if c.opts.MaxTotalSize <= 0 {
+		return
+	}
+
+	entries, err := os.ReadDir(c.opts.Dir)
+	if err != nil {
+		logCapture.Warn("capture.retention_failed", "error", err)
+		return
+	}
+	var files []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() && strings.HasPrefix(name, CAPTURE_PREFIX) && strings.HasSuffix(name, CAPTURE_EXT) {
+			files = append(files, filepath.Join(c.opts.Dir, name))
+		}
+	}
+	sort.Strings(files)
+
+	sizes := make(map[string]int64, len(files))
+	var total int64
+	for _, path := range files {
+		if st, err := os.Stat(path); err == nil {
+			sizes[path] = st.Size()
+			total += st.Size()
+		}
+	}
+
+	// ไฟล์เรียงจากเก่าไปใหม่ ลบจากไฟล์เก่าสุดก่อน
+	for _, path := range files {
+		if total <= c.opts.MaxTotalSize || path == current {
+			break
+		}
+		if err := os.Remove(path); err != nil {
+			logCapture.Warn("capture.retention_failed", "file", path, "error", err)
+			continue
+		}
+		total -= sizes[path]
+		logCapture.Info("capture.removed", "file", path)
+	}
+}
+
+// แยกส่วนประกอบของ frame สำหรับบันทึก
+func newCaptureRecord(session string, seq uint64, upstream string, frame []byte, t time.Time) CaptureRecord {
+	payload := frame[protocol.HEADER_SIZE:]
+	record := CaptureRecord{
+		Time:     t,
+		Session:  session,
+		Seq:      seq,
+		Upstream: upstream,
+		Topic:    binary.LittleEndian.Uint32(frame[0:4]),
+		Length:   binary.LittleEndian.Uint32(frame[4:8]),
+		Header:   fmt.Sprintf("% x", frame[:protocol.HEADER_SIZE]),
+		Encoding: protocol.PayloadEncoding(payload),
+	}
+
+	// ข้อความที่อยู่ก่อน <?xml เก็บเป็น hex แยกไว้ ถ้าไม่พบ <?xml ให้เก็บทั้ง payload เป็น XML
+	text := protocol.DecodePayload(payload)
+	if start := strings.Index(text, "<?xml"); start > 0 {
+		record.Prefix = fmt.Sprintf("% x", []byte(text[:start]))
+		text = text[start:]
+	}
+	record.XML = text
+	return record
+}
+
+// handler ของ /capture สำหรับดูสถานะและเปิด/ปิดการบันทึก
+//
+//	GET  /capture          สถานะของ capture
+//	POST /capture/enable   เริ่มบันทึก
+//	POST /capture/disable  หยุดบันทึก
+func (c *Capture) register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /capture", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, c.Status())
+	})
+	mux.HandleFunc("POST /capture/enable", func(w http.ResponseWriter, r *http.Request) {
+		c.SetEnabled(true)
+		writeJSON(w, http.StatusOK, c.Status())
+	})
+	mux.HandleFunc("POST /capture/disable", func(w http.ResponseWriter, r *http.Request) {
+		c.SetEnabled(false)
+		writeJSON(w, http.StatusOK, c.Status())
+	})
+}
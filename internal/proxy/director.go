@@ -0,0 +1,429 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ampol-me/phi-DCN/dcn"
+	"github.com/ampol-me/phi-DCN/internal/camera"
+)
+
+// ไฟล์ตั้งค่าของ director เป็น JSON:
+//
+//	{
+//	    "priority": ["chairman", "last-opened"],
+//	    "minHold": "4s",
+//	    "wideAfter": "2s",
+//	    "wide": {"camera": "cam1", "preset": 1},
+//	    "chairmen": ["1"],
+//	    "seats": {
+//	        "7":  {"camera": "cam2", "preset": 3},
+//	        "12": {"camera": "cam2", "preset": 4}
+//	    },
+//	    "cameras": {
+//	        "cam1": {"type": "visca", "addr": "192.168.1.51:52381"},
+//	        "cam2": {"type": "panasonic", "addr": "192.168.1.52", "username": "admin", "password": "12345"}
+//	    }
+//	}
+//
+// priority คือลำดับกฎที่ใช้เลือกที่นั่งเมื่อเปิดไมค์พร้อมกันหลายที่ ใช้กฎถัดไปเมื่อกฎก่อนหน้าเสมอกัน
+//
+//	chairman      ประธาน (SeatType เป็น Chairman หรืออยู่ใน chairmen) มาก่อน
+//	last-opened   ที่นั่งที่เปิดไมค์ล่าสุดมาก่อน
+//	longest-open  ที่นั่งที่เปิดไมค์นานที่สุดมาก่อน
+//
+// minHold คือเวลาขั้นต่ำที่ต้องค้างภาพไว้ก่อนเปลี่ยนภาพถัดไป และ wideAfter คือเวลาที่ไม่มีใครพูด
+// ก่อนเปลี่ยนเป็นภาพกว้าง (wide ว่าง = ค้างภาพสุดท้ายไว้) ที่นั่งที่ไม่อยู่ใน seats จะไม่ถูกเลือก
+//
+// cameras กำหนดชนิดและที่อยู่ของกล้องแต่ละตัว (visca หรือ panasonic) ถ้าไม่กำหนด director จะเลือกภาพ
+// และแสดงใน log และ /director เท่านั้น preset นับจาก 1 ตามที่แสดงบนกล้อง
+const (
+	PRIORITY_CHAIRMAN     = "chairman"
+	PRIORITY_LAST_OPENED  = "last-opened"
+	PRIORITY_LONGEST_OPEN = "longest-open"
+
+	DIRECTOR_QUEUE_SIZE = 16 // จำนวนภาพสูงสุดที่รอส่งให้ผู้รับ
+)
+
+// ตำแหน่งกล้องที่บันทึกไว้
+type CameraShot struct {
+	Camera string `json:"camera"`
+	Preset int    `json:"preset"` // นับจาก 1
+}
+
+// ชนิดและที่อยู่ของกล้องหนึ่งตัว
+type CameraConfig struct {
+	Type     string         `json:"type"` // visca หรือ panasonic
+	Addr     string         `json:"addr"`
+	Username string         `json:"username"`
+	Password string         `json:"password"`
+	Timeout  configDuration `json:"timeout"` // เวลารอจนกล้องทำคำสั่งเสร็จ (0 = ค่าเริ่มต้นของ driver)
+}
+
+// ระยะเวลาในไฟล์ตั้งค่า เขียนเป็น string เช่น "4s" หรือตัวเลขวินาที
+type configDuration time.Duration
+
+func (d *configDuration) UnmarshalJSON(data []byte) error {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	switch v := v.(type) {
+	case float64:
+		*d = configDuration(v * float64(time.Second))
+	case string:
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		*d = configDuration(parsed)
+	default:
+		return fmt.Errorf("ระยะเวลา %s ไม่ถูกต้อง", data)
+	}
+	if *d < 0 {
+		return fmt.Errorf("ระยะเวลา %s ต้องไม่ติดลบ", data)
+	}
+	return nil
+}
+
+// การตั้งค่าของ director
+type DirectorConfig struct {
+	Priority  []string                `json:"priority"`
+	MinHold   configDuration          `json:"minHold"`
+	WideAfter configDuration          `json:"wideAfter"`
+	Wide      *CameraShot             `json:"wide"`
+	Chairmen  []string                `json:"chairmen"`
+	Seats     map[string]CameraShot   `json:"seats"`
+	Cameras   map[string]CameraConfig `json:"cameras"`
+}
+
+// อ่านและตรวจสอบไฟล์ตั้งค่าของ director
+func LoadDirector(path string) (DirectorConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return DirectorConfig{}, err
+	}
+	var cfg DirectorConfig
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&cfg); err != nil {
+		return DirectorConfig{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if len(cfg.Priority) == 0 {
+		cfg.Priority = []string{PRIORITY_CHAIRMAN, PRIORITY_LAST_OPENED}
+	}
+	for _, rule := range cfg.Priority {
+		switch rule {
+		case PRIORITY_CHAIRMAN, PRIORITY_LAST_OPENED, PRIORITY_LONGEST_OPEN:
+		default:
+			return DirectorConfig{}, fmt.Errorf("%s: ไม่รู้จัก priority %q (ใช้ chairman, last-opened หรือ longest-open)", path, rule)
+		}
+	}
+	if len(cfg.Seats) == 0 && cfg.Wide == nil {
+		return DirectorConfig{}, fmt.Errorf("%s: ต้องกำหนด seats หรือ wide อย่างน้อยหนึ่งอย่าง", path)
+	}
+	for name, cam := range cfg.Cameras {
+		if cam.Type != camera.TYPE_VISCA && cam.Type != camera.TYPE_PANASONIC {
+			return DirectorConfig{}, fmt.Errorf("%s: กล้อง %s: ไม่รู้จัก type %q (ใช้ visca หรือ panasonic)", path, name, cam.Type)
+		}
+		if cam.Addr == "" {
+			return DirectorConfig{}, fmt.Errorf("%s: กล้อง %s ไม่ได้กำหนด addr", path, name)
+		}
+	}
+	checkShot := func(owner string, shot CameraShot) error {
+		if shot.Camera == "" {
+			return fmt.Errorf("%s: %s ไม่ได้กำหนด camera", path, owner)
+		}
+		if shot.Preset < 1 {
+			return fmt.Errorf("%s: %s: preset ต้องนับจาก 1", path, owner)
+		}
+		if len(cfg.Cameras) == 0 {
+			return nil
+		}
+		cam, ok := cfg.Cameras[shot.Camera]
+		if !ok {
+			return fmt.Errorf("%s: %s ใช้กล้อง %s ที่ไม่ได้กำหนดใน cameras", path, owner, shot.Camera)
+		}
+		if max := camera.MaxPreset(cam.Type); shot.Preset > max {
+			return fmt.Errorf("%s: %s: preset %d เกิน %d ซึ่งเป็นค่าสูงสุดของกล้อง %s (%s)", path, owner, shot.Preset, max, shot.Camera, cam.Type)
+		}
+		return nil
+	}
+	for id, shot := range cfg.Seats {
+		if err := checkShot("ที่นั่ง "+id, shot); err != nil {
+			return DirectorConfig{}, err
+		}
+	}
+	if cfg.Wide != nil {
+		if err := checkShot("wide", *cfg.Wide); err != nil {
+			return DirectorConfig{}, err
+		}
+	}
+	return cfg, nil
+}
+
+// ตรวจสอบว่ามีที่นั่งหรือภาพกว้างที่ใช้กล้องนี้
+func (cfg *DirectorConfig) UsesCamera(name string) bool {
+	if cfg.Wide != nil && cfg.Wide.Camera == name {
+		return true
+	}
+	for _, shot := range cfg.Seats {
+		if shot.Camera == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ภาพที่ director เลือก
+type Shot struct {
+	CameraShot
+	Reason string    `json:"reason"`           // speaker หรือ wide
+	SeatID string    `json:"seatId,omitempty"` // ที่นั่งที่ถูกเลือก (ว่างสำหรับภาพกว้าง)
+	Seat   string    `json:"seat,omitempty"`
+	Time   time.Time `json:"time"`
+}
+
+// ที่นั่งที่เปิดไมค์อยู่
+type openMic struct {
+	Seat   dcn.Seat  `json:"seat"`
+	Since  time.Time `json:"since"`
+	Mapped bool      `json:"mapped"` // มีตำแหน่งกล้องของที่นั่งนี้หรือไม่
+}
+
+// สถานะของ director สำหรับ GET /director
+type DirectorStatus struct {
+	Current  *Shot          `json:"current"`
+	Open     []openMic      `json:"open"`
+	Priority []string       `json:"priority"`
+	MinHold  string         `json:"minHold"`
+	Pending  *Shot          `json:"pending,omitempty"` // ภาพที่รอ minHold หรือ wideAfter ก่อนเปลี่ยน
+	Cameras  []CameraStatus `json:"cameras,omitempty"`
+}
+
+// director เลือกตำแหน่งกล้องของผู้พูดจากเหตุการณ์ไมค์ และส่งภาพที่เลือกให้ผู้รับตามลำดับ
+type director struct {
+	cfg      DirectorConfig
+	chairmen map[string]bool
+
+	mu          sync.Mutex
+	open        map[string]*openMic
+	current     *Shot
+	pending     *Shot
+	silentSince time.Time // เวลาที่ไม่มีที่นั่งที่มีตำแหน่งกล้องเปิดไมค์ (zero = มีคนพูด)
+	timer       *time.Timer
+
+	shots     chan Shot
+	listeners []func(Shot)
+	cameras   *cameraSwitcher // nil เมื่อไม่ได้กำหนด cameras
+}
+
+func newDirector(cfg DirectorConfig, now time.Time) *director {
+	d := &director{
+		cfg:         cfg,
+		chairmen:    make(map[string]bool),
+		open:        make(map[string]*openMic),
+		silentSince: now,
+		shots:       make(chan Shot, DIRECTOR_QUEUE_SIZE),
+	}
+	for _, id := range cfg.Chairmen {
+		d.chairmen[id] = true
+	}
+	return d
+}
+
+// ลงทะเบียนฟังก์ชันที่รับภาพทุกครั้งที่ director เปลี่ยนภาพ (ต้องเรียกก่อน run)
+//
+// ฟังก์ชันถูกเรียกตามลำดับจาก goroutine ของ director จึงทำงานช้าได้ แต่ภาพถัดไปจะรอ
+func (d *director) OnShot(fn func(Shot)) {
+	d.listeners = append(d.listeners, fn)
+}
+
+// ส่งภาพให้ผู้รับจนกว่า ctx จะถูกยกเลิก
+func (d *director) run(ctx context.Context) {
+	// เริ่มจากภาพกว้างจนกว่าจะมีคนพูด
+	d.mu.Lock()
+	d.evaluate(time.Now())
+	d.mu.Unlock()
+
+	for {
+		select {
+		case shot := <-d.shots:
+			for _, fn := range d.listeners {
+				fn(shot)
+			}
+		case <-ctx.Done():
+			d.mu.Lock()
+			if d.timer != nil {
+				d.timer.Stop()
+			}
+			d.mu.Unlock()
+			return
+		}
+	}
+}
+
+// รับเหตุการณ์จาก seat state
+func (d *director) handleEvent(ev dcn.Event) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	switch ev.Type {
+	case dcn.EVENT_MIC_ON:
+		_, mapped := d.cfg.Seats[ev.Seat.ID]
+		d.open[ev.Seat.ID] = &openMic{Seat: ev.Seat, Since: ev.Time, Mapped: mapped}
+		if !mapped {
+			logDirector.Debug("director.unmapped", "seat_id", ev.Seat.ID, "seat", ev.Seat.Name)
+		}
+	case dcn.EVENT_MIC_OFF:
+		delete(d.open, ev.Seat.ID)
+	case dcn.EVENT_SEAT_UPDATED:
+		if mic := d.open[ev.Seat.ID]; mic != nil {
+			mic.Seat = ev.Seat
+		}
+		return
+	default:
+		return
+	}
+	d.evaluate(time.Now())
+}
+
+// เลือกภาพจากสถานะปัจจุบัน และเปลี่ยนภาพหรือตั้งเวลาประเมินใหม่ (ต้องถือ mu อยู่)
+func (d *director) evaluate(now time.Time) {
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+	d.pending = nil
+
+	var target Shot
+	var ready time.Time // เวลาที่เปลี่ยนเป็น target ได้
+	if speaker := d.pick(); speaker != nil {
+		d.silentSince = time.Time{}
+		target = Shot{CameraShot: d.cfg.Seats[speaker.Seat.ID], Reason: "speaker", SeatID: speaker.Seat.ID, Seat: speaker.Seat.Name}
+	} else {
+		if d.silentSince.IsZero() {
+			d.silentSince = now
+		}
+		if d.cfg.Wide == nil {
+			return
+		}
+		target = Shot{CameraShot: *d.cfg.Wide, Reason: "wide"}
+		ready = d.silentSince.Add(time.Duration(d.cfg.WideAfter))
+	}
+
+	if d.current != nil && d.current.CameraShot == target.CameraShot {
+		return
+	}
+	if d.current != nil {
+		if held := d.current.Time.Add(time.Duration(d.cfg.MinHold)); held.After(ready) {
+			ready = held
+		}
+	}
+	if wait := ready.Sub(now); wait > 0 {
+		d.pending = &target
+		d.timer = time.AfterFunc(wait, func() {
+			d.mu.Lock()
+			defer d.mu.Unlock()
+			d.evaluate(time.Now())
+		})
+		logDirector.Debug("director.held", "camera", target.Camera, "preset", target.Preset, "seat", target.Seat, "wait", wait.String())
+		return
+	}
+
+	target.Time = now
+	d.current = &target
+	logDirector.Info("director.shot", "camera", target.Camera, "preset", target.Preset, "reason", target.Reason, "seat_id", target.SeatID, "seat", target.Seat)
+	directorShots.Inc(target.Camera, target.Reason)
+	select {
+	case d.shots <- target:
+	default:
+		logDirector.Warn("director.queue_full", "camera", target.Camera, "preset", target.Preset)
+	}
+}
+
+// ที่นั่งที่ควรได้ภาพตาม priority (nil ถ้าไม่มีที่นั่งที่มีตำแหน่งกล้องเปิดไมค์) ต้องถือ mu อยู่
+func (d *director) pick() *openMic {
+	var candidates []*openMic
+	for _, mic := range d.open {
+		if mic.Mapped {
+			candidates = append(candidates, mic)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	slices.SortFunc(candidates, func(a, b *openMic) int {
+		for _, rule := range d.cfg.Priority {
+			switch rule {
+			case PRIORITY_CHAIRMAN:
+				ca, cb := d.isChairman(a.Seat), d.isChairman(b.Seat)
+				if ca != cb {
+					if ca {
+						return -1
+					}
+					return 1
+				}
+			case PRIORITY_LAST_OPENED:
+				if c := b.Since.Compare(a.Since); c != 0 {
+					return c
+				}
+			case PRIORITY_LONGEST_OPEN:
+				if c := a.Since.Compare(b.Since); c != 0 {
+					return c
+				}
+			}
+		}
+		return strings.Compare(a.Seat.ID, b.Seat.ID)
+	})
+	return candidates[0]
+}
+
+func (d *director) isChairman(seat dcn.Seat) bool {
+	return d.chairmen[seat.ID] || strings.EqualFold(seat.SeatType, "Chairman")
+}
+
+// สถานะปัจจุบันของ director
+func (d *director) status() DirectorStatus {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	status := DirectorStatus{
+		Open:     []openMic{},
+		Priority: d.cfg.Priority,
+		MinHold:  time.Duration(d.cfg.MinHold).String(),
+	}
+	if d.current != nil {
+		current := *d.current
+		status.Current = &current
+	}
+	if d.pending != nil {
+		pending := *d.pending
+		status.Pending = &pending
+	}
+	for _, mic := range d.open {
+		status.Open = append(status.Open, *mic)
+	}
+	slices.SortFunc(status.Open, func(a, b openMic) int { return a.Since.Compare(b.Since) })
+	if d.cameras != nil {
+		status.Cameras = d.cameras.status()
+	}
+	return status
+}
+
+// เพิ่ม endpoint สำหรับอ่านสถานะของ director
+func (d *director) register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /director", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, d.status())
+	})
+}
@@ -0,0 +1,324 @@
+package proxy
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ampol-me/phi-DCN/dcn"
+	"github.com/ampol-me/phi-DCN/internal/metrics"
+	"github.com/ampol-me/phi-DCN/protocol"
+)
+
+const (
+	EVENT_QUEUE_SIZE   = 256 // จำนวนเหตุการณ์สูงสุดที่รอส่งต่อ subscriber แต่ละราย
+	EVENT_HISTORY_SIZE = 128 // จำนวนเหตุการณ์ล่าสุดที่เก็บไว้ให้ SSE client ส่ง Last-Event-ID กลับมาขอต่อได้
+)
+
+// เหตุการณ์ของ seat state ที่แปลงเป็น JSON แล้ว พร้อมข้อมูลสำหรับกรองตาม seat/topic
+type streamEvent struct {
+	ID     uint64 // ลำดับเหตุการณ์ที่ hub กำหนดให้ (0 สำหรับข้อความที่ส่งถึง subscriber รายเดียว)
+	Type   string // ชนิดเหตุการณ์ (ตาม dcn.EventType)
+	Topic  uint32 // topic ที่เหตุการณ์นี้จัดอยู่ (protocol.TOPIC_SEAT หรือ protocol.TOPIC_DISCUSSION)
+	SeatID string // ที่นั่งของเหตุการณ์ ("" ถ้าไม่ผูกกับที่นั่ง)
+	Data   []byte // JSON ของเหตุการณ์
+}
+
+// JSON ของเหตุการณ์ที่ผูกกับที่นั่ง (mic_on, mic_off, seat_updated, inconsistent)
+type seatEventJSON struct {
+	Type   string    `json:"type"`
+	Time   time.Time `json:"time"`
+	Seat   dcn.Seat  `json:"seat"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+// JSON ของเหตุการณ์ ActiveList เปลี่ยน
+type activeEventJSON struct {
+	Type   string     `json:"type"`
+	Time   time.Time  `json:"time"`
+	Names  []string   `json:"names"`
+	Active []dcn.Seat `json:"active"`
+}
+
+// JSON ของสถานะทั้งหมด ส่งเป็นข้อความแรกหลังเชื่อมต่อ
+type snapshotJSON struct {
+	Type       string         `json:"type"`
+	Time       time.Time      `json:"time"`
+	Seats      []dcn.Seat     `json:"seats"`
+	Discussion dcn.Discussion `json:"discussion"`
+}
+
+// แปลงเหตุการณ์ของ seat state เป็น streamEvent (คืนค่า false สำหรับเหตุการณ์ที่ไม่ส่งต่อ)
+func newStreamEvent(ev dcn.Event) (streamEvent, bool) {
+	var v any
+	se := streamEvent{Type: ev.Type.String(), Topic: protocol.TOPIC_SEAT, SeatID: ev.Seat.ID}
+	switch ev.Type {
+	case dcn.EVENT_MIC_ON, dcn.EVENT_MIC_OFF, dcn.EVENT_SEAT_UPDATED, dcn.EVENT_INCONSISTENT:
+		v = seatEventJSON{Type: se.Type, Time: ev.Time, Seat: ev.Seat, Reason: ev.Detail}
+	case dcn.EVENT_ACTIVE_LIST_CHANGED:
+		names := make([]string, 0, len(ev.Active))
+		for _, seat := range ev.Active {
+			names = append(names, seat.Name)
+		}
+		se.Topic = protocol.TOPIC_DISCUSSION
+		se.SeatID = ""
+		v = activeEventJSON{Type: se.Type, Time: ev.Time, Names: names, Active: ev.Active}
+	default:
+		return streamEvent{}, false
+	}
+	se.Data, _ = json.Marshal(v)
+	return se, true
+}
+
+// เงื่อนไขการกรองเหตุการณ์ของ subscriber แต่ละราย
+type eventFilter struct {
+	seats  map[string]bool // nil = ทุกที่นั่ง
+	topics map[uint32]bool // nil = ทุก topic
+}
+
+// สร้าง filter จากรายการ ID ที่นั่งและ topic (ชื่อ seat/discussion หรือหมายเลข 5/3) รายการว่าง = ไม่กรอง
+func newEventFilter(seats, topics []string) (eventFilter, error) {
+	var f eventFilter
+	for _, id := range seats {
+		if f.seats == nil {
+			f.seats = make(map[string]bool)
+		}
+		f.seats[id] = true
+	}
+	for _, name := range topics {
+		topic, err := protocol.ParseTopic(name)
+		if err != nil {
+			return eventFilter{}, err
+		}
+		if f.topics == nil {
+			f.topics = make(map[uint32]bool)
+		}
+		f.topics[topic] = true
+	}
+	return f, nil
+}
+
+// แยกรายการที่คั่นด้วย comma และตัดช่องว่าง
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// ตรวจว่าเหตุการณ์ผ่าน filter หรือไม่ เหตุการณ์ที่ไม่ผูกกับที่นั่ง (ActiveList) กรองด้วย topic เท่านั้น
+func (f eventFilter) match(ev streamEvent) bool {
+	if f.topics != nil && !f.topics[ev.Topic] {
+		return false
+	}
+	if f.seats != nil && ev.SeatID != "" && !f.seats[ev.SeatID] {
+		return false
+	}
+	return true
+}
+
+// กรองรายการที่นั่งตาม filter สำหรับ snapshot
+func (f eventFilter) filterSeats(seats []dcn.Seat) []dcn.Seat {
+	if f.seats == nil {
+		return seats
+	}
+	filtered := []dcn.Seat{}
+	for _, seat := range seats {
+		if f.seats[seat.ID] {
+			filtered = append(filtered, seat)
+		}
+	}
+	return filtered
+}
+
+// ผู้รับเหตุการณ์หนึ่งราย (WebSocket หรือ SSE)
+type subscriber struct {
+	transport string           // ชนิดการเชื่อมต่อสำหรับ metrics และ log
+	addr      string           // ที่อยู่ของ client
+	filter    eventFilter      // ป้องกันด้วย eventHub.mu
+	send      chan streamEvent // คิวข้อความที่รอส่ง ถูกปิดเมื่อ subscriber ถูกลบออกจาก hub
+	reason    string           // สาเหตุที่ถูกลบ (กำหนดก่อนปิด send)
+}
+
+// eventHub ส่งเหตุการณ์ของ seat state ไปยัง subscribers ทุกราย
+// และเก็บเหตุการณ์ล่าสุดไว้ใน ring buffer สำหรับ client ที่เชื่อมต่อกลับมาใหม่
+type eventHub struct {
+	mu      sync.Mutex
+	subs    map[*subscriber]bool
+	closed  bool
+	lastID  uint64
+	history []streamEvent // เหตุการณ์ล่าสุดไม่เกิน EVENT_HISTORY_SIZE รายการ เรียงตาม ID
+}
+
+// ลำดับเหตุการณ์เริ่มจากเวลาที่สร้าง hub (microseconds) เพื่อให้ ID หลังรีสตาร์ทมากกว่า ID เดิมเสมอ
+// client ที่ส่ง Last-Event-ID จาก proxy ตัวก่อนจึงได้ snapshot ใหม่แทนการข้ามเหตุการณ์
+func newEventHub() *eventHub {
+	return &eventHub{
+		subs:   make(map[*subscriber]bool),
+		lastID: uint64(time.Now().UnixMicro()),
+	}
+}
+
+// เพิ่ม subscriber (คืนค่า nil ถ้า hub ถูกปิดแล้ว)
+//
+// ถ้า resume ไม่เป็น nil และเหตุการณ์หลัง ID นั้นยังอยู่ใน ring buffer ครบ จะส่งเหตุการณ์ที่ตกหล่นต่อจากเดิม
+// ไม่เช่นนั้นจะส่ง snapshot เป็นข้อความแรก ทั้งสองกรณีทำขณะถือ lock ของ hub จึงไม่มีเหตุการณ์ตกหล่น
+func (h *eventHub) subscribe(transport, addr string, filter eventFilter, snapshot func(eventFilter) []byte, resume *uint64) *subscriber {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil
+	}
+	sub := &subscriber{
+		transport: transport,
+		addr:      addr,
+		filter:    filter,
+		send:      make(chan streamEvent, EVENT_QUEUE_SIZE),
+	}
+	if missed, ok := h.since(resume); ok {
+		for _, ev := range missed {
+			if filter.match(ev) {
+				sub.send <- ev
+			}
+		}
+	} else {
+		sub.send <- streamEvent{ID: h.lastID, Type: "snapshot", Data: snapshot(filter)}
+	}
+	h.subs[sub] = true
+	return sub
+}
+
+// เหตุการณ์หลัง ID ที่กำหนด คืนค่า false ถ้าไม่มี ID หรือเหตุการณ์บางส่วนหลุดจาก ring buffer ไปแล้ว (ต้องถือ mu อยู่)
+func (h *eventHub) since(resume *uint64) ([]streamEvent, bool) {
+	if resume == nil || *resume > h.lastID {
+		return nil, false
+	}
+	if *resume == h.lastID {
+		return nil, true
+	}
+	i := sort.Search(len(h.history), func(i int) bool { return h.history[i].ID > *resume })
+	if i == len(h.history) || h.history[i].ID != *resume+1 {
+		return nil, false
+	}
+	return h.history[i:], true
+}
+
+// กำหนด ID ให้เหตุการณ์ เก็บลง ring buffer และส่งไปยัง subscribers ที่ filter ตรงกัน ผู้ที่คิวเต็มจะถูกตัดออก
+func (h *eventHub) publish(ev streamEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastID++
+	ev.ID = h.lastID
+	if len(h.history) == EVENT_HISTORY_SIZE {
+		copy(h.history, h.history[1:])
+		h.history = h.history[:EVENT_HISTORY_SIZE-1]
+	}
+	h.history = append(h.history, ev)
+
+	for sub := range h.subs {
+		if sub.filter.match(ev) {
+			h.enqueueLocked(sub, ev)
+		}
+	}
+}
+
+// ส่งข้อความถึง subscriber รายเดียว (เช่นคำตอบของคำสั่งจาก client)
+func (h *eventHub) sendTo(sub *subscriber, ev streamEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.subs[sub] {
+		h.enqueueLocked(sub, ev)
+	}
+}
+
+// เปลี่ยน filter ของ subscriber แล้วส่ง snapshot ใหม่ตาม filter
+func (h *eventHub) setFilter(sub *subscriber, filter eventFilter, snapshot func(eventFilter) []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.subs[sub] {
+		sub.filter = filter
+		h.enqueueLocked(sub, streamEvent{ID: h.lastID, Type: "snapshot", Data: snapshot(filter)})
+	}
+}
+
+// ใส่ข้อความในคิว ถ้าคิวเต็มให้ตัด subscriber ออก (ต้องถือ mu อยู่)
+func (h *eventHub) enqueueLocked(sub *subscriber, ev streamEvent) {
+	select {
+	case sub.send <- ev:
+	default:
+		logStream.Warn("stream.queue_full", "transport", sub.transport, "addr", sub.addr)
+		streamEvictions.Inc(sub.transport, "queue_full")
+		h.removeLocked(sub, "queue_full")
+	}
+}
+
+// ลบ subscriber ออกจาก hub
+func (h *eventHub) remove(sub *subscriber, reason string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.removeLocked(sub, reason)
+}
+
+func (h *eventHub) removeLocked(sub *subscriber, reason string) {
+	if !h.subs[sub] {
+		return
+	}
+	delete(h.subs, sub)
+	sub.reason = reason
+	close(sub.send)
+}
+
+// ปิด hub: ลบ subscribers ทั้งหมด ข้อความที่อยู่ในคิวแล้วยังถูกส่งก่อนปิดการเชื่อมต่อ
+func (h *eventHub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for sub := range h.subs {
+		h.removeLocked(sub, "shutdown")
+	}
+}
+
+// จำนวน subscribers แยกตามชนิดการเชื่อมต่อ สำหรับ metrics
+func (h *eventHub) samples() []metrics.Sample {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	counts := make(map[string]int)
+	for sub := range h.subs {
+		counts[sub.transport]++
+	}
+	samples := make([]metrics.Sample, 0, len(counts))
+	for transport, n := range counts {
+		samples = append(samples, metrics.Sample{Labels: []string{transport}, Value: float64(n)})
+	}
+	return samples
+}
+
+// สร้าง snapshot ของ seat state ตาม filter เป็น JSON
+func (p *ProxyServer) snapshotJSON(filter eventFilter) []byte {
+	discussion := p.seats.Discussion()
+	if filter.topics != nil && !filter.topics[protocol.TOPIC_DISCUSSION] {
+		discussion.Active = []dcn.Seat{}
+	}
+	seats := []dcn.Seat{}
+	if filter.topics == nil || filter.topics[protocol.TOPIC_SEAT] {
+		seats = filter.filterSeats(p.seats.Seats())
+	}
+	data, _ := json.Marshal(snapshotJSON{
+		Type:       "snapshot",
+		Time:       time.Now(),
+		Seats:      seats,
+		Discussion: discussion,
+	})
+	return data
+}
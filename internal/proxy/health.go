@@ -0,0 +1,184 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// สถานะของ health check
+const (
+	HEALTH_OK       = "ok"
+	HEALTH_DEGRADED = "degraded"
+	HEALTH_FAIL     = "fail"
+)
+
+// เกณฑ์เวลาที่ใช้ตัดสินสถานะของ upstream
+type HealthThresholds struct {
+	FailAfter          time.Duration // ขาดการเชื่อมต่อนานเกินนี้ถือว่า fail (ก่อนหน้านั้นเป็น degraded)
+	FrameDegradedAfter time.Duration // ไม่ได้รับ frame นานเกินนี้ถือว่า degraded (0 = ปิด)
+	FrameFailAfter     time.Duration // ไม่ได้รับ frame นานเกินนี้ถือว่า fail (0 = ปิด)
+}
+
+// ผลการตรวจสอบส่วนประกอบหนึ่ง
+type CheckResult struct {
+	Status  string         `json:"status"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+// รายงานสุขภาพของ proxy
+type HealthReport struct {
+	Status        string                 `json:"status"`
+	Checks        map[string]CheckResult `json:"checks"`
+	Clients       int                    `json:"clients"`
+	UptimeSeconds float64                `json:"uptimeSeconds"`
+}
+
+// สถานะการเชื่อมต่อกับ upstream DCN server
+type upstreamStatus struct {
+	mu          sync.Mutex
+	connected   bool
+	addr        string
+	changedAt   time.Time // เวลาที่เชื่อมต่อหรือขาดการเชื่อมต่อล่าสุด
+	lastFrameAt time.Time
+}
+
+func (u *upstreamStatus) setConnected(connected bool, addr string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.connected = connected
+	u.addr = addr
+	u.changedAt = time.Now()
+}
+
+func (u *upstreamStatus) frameReceived() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.lastFrameAt = time.Now()
+}
+
+// สถานะที่แย่กว่าระหว่างสองค่า
+func worseStatus(a, b string) string {
+	rank := map[string]int{HEALTH_OK: 0, HEALTH_DEGRADED: 1, HEALTH_FAIL: 2}
+	if rank[b] > rank[a] {
+		return b
+	}
+	return a
+}
+
+// ข้อมูลสุขภาพของ proxy
+type proxyHealth struct {
+	startedAt  time.Time
+	listenAddr string
+	listening  atomic.Bool
+	upstream   upstreamStatus
+	thresholds HealthThresholds
+}
+
+// ตรวจสอบ listener
+func (p *ProxyServer) checkListener() CheckResult {
+	result := CheckResult{Status: HEALTH_OK, Details: map[string]any{"addr": p.health.listenAddr}}
+	if !p.health.listening.Load() {
+		result.Status = HEALTH_FAIL
+	}
+	return result
+}
+
+// ตรวจสอบการเชื่อมต่อและการรับ frame จาก upstream
+func (p *ProxyServer) checkUpstream() CheckResult {
+	u := &p.health.upstream
+	u.mu.Lock()
+	connected, addr, changedAt, lastFrameAt := u.connected, u.addr, u.changedAt, u.lastFrameAt
+	u.mu.Unlock()
+
+	t := p.health.thresholds
+	result := CheckResult{Status: HEALTH_OK, Details: map[string]any{"connected": connected}}
+	if addr != "" {
+		result.Details["addr"] = addr
+	}
+	if !lastFrameAt.IsZero() {
+		result.Details["lastFrame"] = lastFrameAt
+		result.Details["sinceLastFrameSeconds"] = time.Since(lastFrameAt).Seconds()
+	}
+
+	if !connected {
+		// ยังไม่เคยเชื่อมต่อ นับเวลาจากตอนเริ่ม proxy
+		if changedAt.IsZero() {
+			changedAt = p.health.startedAt
+		}
+		disconnectedFor := time.Since(changedAt)
+		result.Details["disconnectedSeconds"] = disconnectedFor.Seconds()
+		result.Status = HEALTH_DEGRADED
+		if t.FailAfter > 0 && disconnectedFor > t.FailAfter {
+			result.Status = HEALTH_FAIL
+		}
+		return result
+	}
+
+	result.Details["connectedSince"] = changedAt
+
+	// ยังไม่ได้รับ frame ในการเชื่อมต่อนี้ นับเวลาจากตอนเชื่อมต่อ
+	since := lastFrameAt
+	if since.Before(changedAt) {
+		since = changedAt
+	}
+	idle := time.Since(since)
+	switch {
+	case t.FrameFailAfter > 0 && idle > t.FrameFailAfter:
+		result.Status = HEALTH_FAIL
+	case t.FrameDegradedAfter > 0 && idle > t.FrameDegradedAfter:
+		result.Status = HEALTH_DEGRADED
+	}
+	return result
+}
+
+// สร้างรายงานสุขภาพ live = true ตรวจเฉพาะ listener (liveness) ไม่เช่นนั้นตรวจ upstream ด้วย (readiness)
+func (p *ProxyServer) Health(live bool) HealthReport {
+	p.clientLock.Lock()
+	clients := len(p.clients)
+	p.clientLock.Unlock()
+
+	report := HealthReport{
+		Status:        HEALTH_OK,
+		Checks:        map[string]CheckResult{"listener": p.checkListener()},
+		Clients:       clients,
+		UptimeSeconds: time.Since(p.health.startedAt).Seconds(),
+	}
+	if !live {
+		report.Checks["upstream"] = p.checkUpstream()
+	}
+	for _, check := range report.Checks {
+		report.Status = worseStatus(report.Status, check.Status)
+	}
+	return report
+}
+
+// handler ของ /healthz และ /readyz ตอบ 503 เมื่อสถานะเป็น fail
+func (p *ProxyServer) healthHandler(live bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report := p.Health(live)
+		status := http.StatusOK
+		if report.Status == HEALTH_FAIL {
+			status = http.StatusServiceUnavailable
+		}
+		writeJSON(w, status, report)
+	}
+}
+
+// เขียน response เป็น JSON
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+// เขียน error response เป็น JSON
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,281 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ampol-me/phi-DCN/dcn"
+	"github.com/ampol-me/phi-DCN/internal/dmx"
+)
+
+// ไฟล์ตั้งค่าของไฟส่องผู้พูดเป็น JSON:
+//
+//	{
+//	    "protocol": "sacn",
+//	    "addr": "192.168.1.80",
+//	    "universe": 1,
+//	    "priority": 100,
+//	    "rate": 30,
+//	    "fadeIn": "1s",
+//	    "fadeOut": "2s",
+//	    "house": [{"address": 1, "values": [80, 80, 80, 80]}],
+//	    "seats": {
+//	        "7":  [{"address": 1, "values": [255, 180]}],
+//	        "12": [{"address": 3, "values": [255, 180]}, {"address": 20, "values": [255]}]
+//	    }
+//	}
+//
+// seats จับคู่ Seat Id กับ fixture ที่ส่องที่นั่งนั้น address คือ DMX channel แรกของ fixture (1-512)
+// และ values คือค่าของ channel ที่เรียงต่อกันจาก address ขณะที่นั่งอยู่ใน ActiveList
+// เมื่อมีหลายที่นั่งใน ActiveList channel เดียวกันใช้ค่าที่สูงที่สุด (HTP)
+// house คือค่าของ channel เมื่อไม่มีที่นั่งใน seats อยู่ใน ActiveList channel ที่ไม่ได้กำหนดมีค่า 0
+//
+// protocol เป็น artnet หรือ sacn (E1.31) addr ว่าง = broadcast ของ Art-Net หรือ multicast ของ universe ใน sACN
+// ค่าที่เพิ่มขึ้นค่อยๆ เปลี่ยนภายใน fadeIn และค่าที่ลดลงภายใน fadeOut
+// ส่ง universe ทุก 1/rate วินาทีขณะกำลัง fade และซ้ำทุก LIGHTING_KEEPALIVE เมื่อค่าคงที่
+const (
+	LIGHTING_RATE        = 30 // frame ต่อวินาที
+	LIGHTING_MAX_RATE    = 44 // อัตราสูงสุดของ DMX512
+	LIGHTING_FADE        = time.Second
+	LIGHTING_KEEPALIVE   = time.Second
+	LIGHTING_SOURCE_NAME = "phi-DCN"
+)
+
+// fixture หนึ่งตัว: ค่าของ channel ที่เรียงต่อกันจาก address
+type LightingFixtureConfig struct {
+	Address int   `json:"address"`
+	Values  []int `json:"values"`
+}
+
+// การตั้งค่าของไฟส่องผู้พูด
+type LightingConfig struct {
+	Protocol string                             `json:"protocol"`
+	Addr     string                             `json:"addr"`
+	Universe int                                `json:"universe"`
+	Priority int                                `json:"priority"` // sACN: 0-200 (0 = ค่าเริ่มต้น 100)
+	Rate     int                                `json:"rate"`     // ค่าเริ่มต้น LIGHTING_RATE
+	FadeIn   *configDuration                    `json:"fadeIn"`   // ค่าเริ่มต้น LIGHTING_FADE
+	FadeOut  *configDuration                    `json:"fadeOut"`  // ค่าเริ่มต้น LIGHTING_FADE
+	House    []LightingFixtureConfig            `json:"house"`
+	Seats    map[string][]LightingFixtureConfig `json:"seats"`
+}
+
+// อ่านและตรวจสอบไฟล์ตั้งค่าของไฟส่องผู้พูด
+func LoadLighting(path string) (LightingConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return LightingConfig{}, err
+	}
+	var cfg LightingConfig
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&cfg); err != nil {
+		return LightingConfig{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if err := cfg.options().Validate(); err != nil {
+		return LightingConfig{}, fmt.Errorf("%s: %w", path, err)
+	}
+	switch {
+	case cfg.Rate == 0:
+		cfg.Rate = LIGHTING_RATE
+	case cfg.Rate < 1 || cfg.Rate > LIGHTING_MAX_RATE:
+		return LightingConfig{}, fmt.Errorf("%s: rate ต้องอยู่ระหว่าง 1-%d", path, LIGHTING_MAX_RATE)
+	}
+	if cfg.FadeIn == nil {
+		fade := configDuration(LIGHTING_FADE)
+		cfg.FadeIn = &fade
+	}
+	if cfg.FadeOut == nil {
+		fade := configDuration(LIGHTING_FADE)
+		cfg.FadeOut = &fade
+	}
+
+	if len(cfg.Seats) == 0 {
+		return LightingConfig{}, fmt.Errorf("%s: ต้องกำหนด seats อย่างน้อยหนึ่งที่", path)
+	}
+	checkFixtures := func(owner string, fixtures []LightingFixtureConfig) error {
+		for i, f := range fixtures {
+			if len(f.Values) == 0 {
+				return fmt.Errorf("%s: %s[%d]: ต้องกำหนด values", path, owner, i)
+			}
+			if f.Address < 1 || f.Address+len(f.Values)-1 > dmx.UNIVERSE_SIZE {
+				return fmt.Errorf("%s: %s[%d]: channel %d-%d อยู่นอกช่วง 1-%d", path, owner, i, f.Address, f.Address+len(f.Values)-1, dmx.UNIVERSE_SIZE)
+			}
+			for _, v := range f.Values {
+				if v < 0 || v > 255 {
+					return fmt.Errorf("%s: %s[%d]: ค่า %d อยู่นอกช่วง 0-255", path, owner, i, v)
+				}
+			}
+		}
+		return nil
+	}
+	if err := checkFixtures("house", cfg.House); err != nil {
+		return LightingConfig{}, err
+	}
+	for id, fixtures := range cfg.Seats {
+		if err := checkFixtures("ที่นั่ง "+id, fixtures); err != nil {
+			return LightingConfig{}, err
+		}
+	}
+	return cfg, nil
+}
+
+func (c LightingConfig) options() dmx.Options {
+	return dmx.Options{Protocol: c.Protocol, Addr: c.Addr, Universe: c.Universe, Name: LIGHTING_SOURCE_NAME, Priority: c.Priority}
+}
+
+// ค่าของทุก channel ใน universe
+type dmxFrame [dmx.UNIVERSE_SIZE]byte
+
+// รวมค่าของ fixture เข้ากับ frame โดยใช้ค่าที่สูงกว่า (HTP)
+func (f *dmxFrame) merge(fixtures []LightingFixtureConfig) {
+	for _, fixture := range fixtures {
+		for i, v := range fixture.Values {
+			f[fixture.Address-1+i] = max(f[fixture.Address-1+i], byte(v))
+		}
+	}
+}
+
+// ส่องไฟผู้พูดตาม ActiveList ด้วย Art-Net หรือ sACN
+type lightingController struct {
+	cfg    LightingConfig
+	state  *dcn.State
+	sender *dmx.Sender
+
+	mu     sync.Mutex
+	active []string  // ที่นั่งใน seats ที่อยู่ใน ActiveList ตามลำดับ
+	from   dmxFrame  // ค่าเมื่อเริ่ม cue ล่าสุด
+	target dmxFrame  // ค่าเป้าหมายของ cue ล่าสุด
+	cueAt  time.Time // เวลาที่เริ่ม cue ล่าสุด
+	failed bool      // ส่งไม่สำเร็จครั้งล่าสุด (log เฉพาะเมื่อสถานะเปลี่ยน)
+}
+
+func newLightingController(cfg LightingConfig, state *dcn.State) (*lightingController, error) {
+	sender, err := dmx.NewSender(cfg.options())
+	if err != nil {
+		return nil, err
+	}
+	return &lightingController{cfg: cfg, state: state, sender: sender}, nil
+}
+
+// รับเหตุการณ์จาก seat state
+func (l *lightingController) handleEvent(ev dcn.Event) {
+	if ev.Type != dcn.EVENT_ACTIVE_LIST_CHANGED {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.cue(ev.Active, ev.Time)
+}
+
+// เริ่ม fade ไปยังค่าของที่นั่งใน ActiveList หรือ house ถ้าไม่มี (ต้องถือ mu อยู่)
+func (l *lightingController) cue(seats []dcn.Seat, now time.Time) {
+	var active []string
+	for _, seat := range seats {
+		if _, ok := l.cfg.Seats[seat.ID]; ok {
+			active = append(active, seat.ID)
+		}
+	}
+	if !l.cueAt.IsZero() && slices.Equal(active, l.active) {
+		return
+	}
+
+	var target dmxFrame
+	for _, id := range active {
+		target.merge(l.cfg.Seats[id])
+	}
+	if len(active) == 0 {
+		target.merge(l.cfg.House)
+	}
+	if !l.cueAt.IsZero() {
+		l.from = l.frame(now)
+	}
+	l.active, l.target, l.cueAt = active, target, now
+
+	if len(active) == 0 {
+		logDMX.Info("dmx.cue", "cue", "house")
+	} else {
+		logDMX.Info("dmx.cue", "cue", "speaker", "seats", active)
+	}
+}
+
+// ค่าของทุก channel ณ เวลา now ระหว่าง fade (ต้องถือ mu อยู่)
+func (l *lightingController) frame(now time.Time) dmxFrame {
+	elapsed := now.Sub(l.cueAt)
+	var f dmxFrame
+	for i := range f {
+		from, to := int(l.from[i]), int(l.target[i])
+		fade := time.Duration(*l.cfg.FadeIn)
+		if to < from {
+			fade = time.Duration(*l.cfg.FadeOut)
+		}
+		if from == to || elapsed >= fade {
+			f[i] = byte(to)
+			continue
+		}
+		f[i] = byte(from + int(float64(to-from)*float64(elapsed)/float64(fade)))
+	}
+	return f
+}
+
+// ส่ง universe ตาม rate ขณะค่าเปลี่ยนและซ้ำทุก LIGHTING_KEEPALIVE จนกว่า ctx จะถูกยกเลิก
+//
+// เมื่อหยุดจะส่งค่าของ house ทันทีโดยไม่ fade แล้ว sACN จะแจ้ง receiver ว่าหยุดส่ง universe
+// ส่วน Art-Net node จะคงค่าของ house ไว้
+func (l *lightingController) run(ctx context.Context) {
+	l.mu.Lock()
+	l.cue(l.state.Active(), time.Now())
+	l.from = l.target
+	l.mu.Unlock()
+	logDMX.Info("dmx.started", "protocol", l.cfg.Protocol, "addr", l.sender.Addr(), "universe", l.cfg.Universe, "seats", len(l.cfg.Seats))
+
+	ticker := time.NewTicker(time.Second / time.Duration(l.cfg.Rate))
+	defer ticker.Stop()
+	var last dmxFrame
+	var lastSent time.Time
+	for {
+		l.mu.Lock()
+		frame := l.frame(time.Now())
+		l.mu.Unlock()
+		if lastSent.IsZero() || frame != last || time.Since(lastSent) >= LIGHTING_KEEPALIVE {
+			l.send(frame)
+			last, lastSent = frame, time.Now()
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			l.mu.Lock()
+			l.cue(nil, time.Now())
+			frame := l.target
+			l.mu.Unlock()
+			l.send(frame)
+			l.sender.Close(frame[:])
+			return
+		}
+	}
+}
+
+// ส่งหนึ่ง frame และนับผลลัพธ์
+func (l *lightingController) send(frame dmxFrame) {
+	err := l.sender.Send(frame[:])
+	switch {
+	case err != nil && !l.failed:
+		logDMX.Warn("dmx.send_failed", "addr", l.sender.Addr(), "error", err)
+	case err == nil && l.failed:
+		logDMX.Info("dmx.recovered", "addr", l.sender.Addr())
+	}
+	l.failed = err != nil
+	if err != nil {
+		dmxPackets.Inc("error")
+	} else {
+		dmxPackets.Inc("sent")
+	}
+}
@@ -0,0 +1,166 @@
+package proxy
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ampol-me/phi-DCN/internal/metrics"
+)
+
+// metrics ของ proxy
+var (
+	framesBroadcast = metrics.NewCounter("dcn_frames_broadcast_total", "Frames broadcast to clients by topic.", "topic")
+	bytesSent       = metrics.NewCounter("dcn_bytes_sent_total", "Bytes written to client connections.")
+	writeErrors     = metrics.NewCounter("dcn_write_errors_total", "Failed writes to client connections.")
+	clientEvictions = metrics.NewCounter("dcn_client_evictions_total", "Clients disconnected by the proxy by reason.", "reason")
+	upstreamFrames  = metrics.NewCounter("dcn_upstream_frames_received_total", "Frames received from the upstream DCN server by topic.", "topic")
+	upstreamBytes   = metrics.NewCounter("dcn_upstream_bytes_received_total", "Bytes received from the upstream DCN server.")
+	decodeErrors    = metrics.NewCounter("dcn_decode_errors_total", "Frames whose XML payload could not be decoded.", "topic")
+	resyncEvents    = metrics.NewCounter("dcn_resync_events_total", "Times the frame decoder lost sync and skipped bytes.")
+	resyncBytes     = metrics.NewCounter("dcn_resync_bytes_total", "Bytes skipped while searching for a valid frame header.")
+	journalErrors   = metrics.NewCounter("dcn_journal_write_errors_total", "Frames that could not be written to the journal.")
+	captureErrors   = metrics.NewCounter("dcn_capture_write_errors_total", "Frames that could not be written to the capture file.")
+	pcapngErrors    = metrics.NewCounter("dcn_pcapng_write_errors_total", "Packets that could not be written to the pcapng capture.")
+	inconsistencies = metrics.NewCounter("dcn_seat_inconsistencies_total", "Disagreements between SeatActivity and DiscussionActivity by reason.", "reason")
+	streamEvictions = metrics.NewCounter("dcn_stream_evictions_total", "Event stream subscribers disconnected by the proxy by transport and reason.", "transport", "reason")
+	mqttPublished   = metrics.NewCounter("dcn_mqtt_messages_published_total", "Messages published to the MQTT broker.")
+	mqttDropped     = metrics.NewCounter("dcn_mqtt_messages_dropped_total", "MQTT messages that were not published by reason.", "reason")
+
+	webhookDeliveries = metrics.NewCounter("dcn_webhook_deliveries_total", "Webhook delivery attempts by webhook and result.", "webhook", "result")
+	directorShots     = metrics.NewCounter("dcn_director_shots_total", "Camera shots taken by the director by camera and reason.", "camera", "reason")
+	cameraCommands    = metrics.NewCounter("dcn_camera_commands_total", "Preset recall commands sent to cameras by camera and result.", "camera", "result")
+	tallyPackets      = metrics.NewCounter("dcn_tally_packets_total", "TSL UMD display packets by result.", "result")
+	oscMessages       = metrics.NewCounter("dcn_osc_messages_total", "OSC messages for the mixing console by result.", "result")
+	dmxPackets        = metrics.NewCounter("dcn_dmx_packets_total", "Art-Net/sACN lighting packets by result.", "result")
+)
+
+// ติดตามสถานะไมค์แต่ละที่นั่งจากเหตุการณ์ของ seat state เพื่อคำนวณจำนวนไมค์ที่เปิดและเวลาพูดสะสม
+type micTracker struct {
+	mu    sync.Mutex
+	seats map[string]*seatTalk
+}
+
+type seatTalk struct {
+	name  string
+	on    bool
+	since time.Time
+	total time.Duration
+}
+
+func newMicTracker() *micTracker {
+	return &micTracker{seats: make(map[string]*seatTalk)}
+}
+
+// บันทึกสถานะไมค์ของที่นั่งจากเหตุการณ์ EVENT_MIC_ON/EVENT_MIC_OFF
+func (t *micTracker) Record(id, name string, on bool, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	seat, ok := t.seats[id]
+	if !ok {
+		seat = &seatTalk{}
+		t.seats[id] = seat
+	}
+	if name != "" {
+		seat.name = name
+	}
+	if seat.on == on {
+		return
+	}
+	if seat.on {
+		seat.total += now.Sub(seat.since)
+	}
+	seat.on = on
+	seat.since = now
+}
+
+func (t *micTracker) activeCount() float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	active := 0
+	for _, seat := range t.seats {
+		if seat.on {
+			active++
+		}
+	}
+	return float64(active)
+}
+
+func (t *micTracker) talkSamples() []metrics.Sample {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	samples := make([]metrics.Sample, 0, len(t.seats))
+	for id, seat := range t.seats {
+		total := seat.total
+		if seat.on {
+			total += time.Since(seat.since)
+		}
+		samples = append(samples, metrics.Sample{Labels: []string{id, seat.name}, Value: total.Seconds()})
+	}
+	return samples
+}
+
+// สร้าง registry พร้อม metrics ที่อ่านค่าจากสถานะของ proxy
+func (p *ProxyServer) newMetricsRegistry() *metrics.Registry {
+	r := &metrics.Registry{}
+	r.Register(
+		metrics.NewGaugeFunc("dcn_clients_connected", "Currently connected clients (TCP and WebSocket).", func() float64 {
+			p.clientLock.Lock()
+			defer p.clientLock.Unlock()
+			return float64(len(p.clients))
+		}),
+		framesBroadcast,
+		bytesSent,
+		writeErrors,
+		clientEvictions,
+		upstreamFrames,
+		upstreamBytes,
+		decodeErrors,
+		resyncEvents,
+		resyncBytes,
+		journalErrors,
+		captureErrors,
+		pcapngErrors,
+		inconsistencies,
+		streamEvictions,
+		mqttPublished,
+		mqttDropped,
+		metrics.NewGaugeFunc("dcn_mqtt_connected", "Whether the proxy is connected to the MQTT broker (1) or not (0).", func() float64 {
+			if p.mqtt != nil && p.mqtt.connected.Load() {
+				return 1
+			}
+			return 0
+		}),
+		webhookDeliveries,
+		&metrics.FuncMetric{
+			Name:   "dcn_webhook_queue_length",
+			Help:   "Webhook deliveries waiting to be sent or retried by webhook.",
+			Kind:   "gauge",
+			Labels: []string{"webhook"},
+			Collect: func() []metrics.Sample {
+				if p.webhooks == nil {
+					return nil
+				}
+				return p.webhooks.samples()
+			},
+		},
+		&metrics.FuncMetric{
+			Name:    "dcn_stream_subscribers",
+			Help:    "Currently connected event stream subscribers by transport.",
+			Kind:    "gauge",
+			Labels:  []string{"transport"},
+			Collect: p.events.samples,
+		},
+		metrics.NewGaugeFunc("dcn_active_microphones", "Seats whose microphone is currently on.", p.mics.activeCount),
+		&metrics.FuncMetric{
+			Name:    "dcn_seat_talk_seconds_total",
+			Help:    "Cumulative time each seat's microphone has been on.",
+			Kind:    "counter",
+			Labels:  []string{"seat_id", "seat"},
+			Collect: p.mics.talkSamples,
+		},
+	)
+	return r
+}
@@ -0,0 +1,352 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ampol-me/phi-DCN/dcn"
+	"github.com/ampol-me/phi-DCN/internal/mqtt"
+)
+
+// topic ที่ publish (base = <prefix>/<room>)
+//
+//	<base>/status              online/offline (retained, offline ส่งโดย broker ผ่าน will เมื่อ proxy หลุด)
+//	<base>/seat/<id>/mic       ON/OFF (retained)
+//	<base>/seat/<id>           JSON ของที่นั่ง (retained)
+//	<base>/active              JSON ของ ActiveList (retained)
+//	<base>/events              JSON ของเหตุการณ์ mic_on, mic_off, active_list_changed, inconsistent
+//
+// เมื่อเปิด discovery จะ publish config ของ Home Assistant ที่ <discovery prefix>/binary_sensor/... และ sensor/...
+const (
+	MQTT_PREFIX           = "dcn"
+	MQTT_ROOM             = "main"
+	MQTT_DISCOVERY_PREFIX = "homeassistant"
+	MQTT_QUEUE_SIZE       = 1024 // จำนวนข้อความสูงสุดที่รอ publish
+)
+
+// การตั้งค่าการ publish ไปยัง MQTT broker
+type MQTTOptions struct {
+	Addr            string // host:port ของ broker (ว่าง = ไม่เปิด)
+	ClientID        string
+	Username        string
+	Password        string
+	Prefix          string
+	Room            string
+	QoS             byte
+	KeepAlive       time.Duration
+	Discovery       bool   // publish config สำหรับ Home Assistant MQTT discovery
+	DiscoveryPrefix string // prefix ของ discovery topic
+}
+
+// mqttPublisher แปลงเหตุการณ์ของ seat state เป็นข้อความ MQTT และ publish ไปยัง broker
+// พร้อมเชื่อมต่อใหม่อัตโนมัติ เมื่อเชื่อมต่อได้จะ publish สถานะปัจจุบันทั้งหมดใหม่แทนข้อความ retained ที่ค้างในคิว
+type mqttPublisher struct {
+	opts      MQTTOptions
+	base      string
+	state     *dcn.State
+	queue     chan mqtt.Message
+	connected atomic.Bool
+	dropping  atomic.Bool // เขียน log คิวเต็มครั้งเดียวจนกว่าจะเชื่อมต่อได้อีกครั้ง
+
+	mu        sync.Mutex
+	announced map[string]string // Seat Id -> ชื่อที่นั่งที่ publish discovery และสถานะไมค์ไปแล้ว
+}
+
+func newMQTTPublisher(opts MQTTOptions, state *dcn.State) *mqttPublisher {
+	return &mqttPublisher{
+		opts:      opts,
+		base:      opts.Prefix + "/" + opts.Room,
+		state:     state,
+		queue:     make(chan mqtt.Message, MQTT_QUEUE_SIZE),
+		announced: make(map[string]string),
+	}
+}
+
+// รับเหตุการณ์จาก seat state (เรียกจาก goroutine ที่อ่าน upstream จึงแค่ใส่คิว)
+func (m *mqttPublisher) handleEvent(ev dcn.Event) {
+	switch ev.Type {
+	case dcn.EVENT_SEAT_UPDATED:
+		if m.announce(ev.Seat) {
+			m.enqueue(m.micMessage(ev.Seat))
+		}
+		m.enqueue(m.seatMessage(ev.Seat))
+		return
+	case dcn.EVENT_MIC_ON, dcn.EVENT_MIC_OFF:
+		m.announce(ev.Seat)
+		m.enqueue(m.micMessage(ev.Seat))
+		m.enqueue(m.seatMessage(ev.Seat))
+	case dcn.EVENT_ACTIVE_LIST_CHANGED:
+		m.enqueue(m.activeMessage(ev.Active, ev.Time))
+	case dcn.EVENT_INCONSISTENT:
+	default:
+		return
+	}
+
+	if se, ok := newStreamEvent(ev); ok {
+		m.enqueue(m.message("events", se.Data, false))
+	}
+}
+
+// publish discovery ของที่นั่งที่ยังไม่เคยประกาศหรือเปลี่ยนชื่อ คืนค่า true ถ้าเป็นที่นั่งใหม่
+func (m *mqttPublisher) announce(seat dcn.Seat) bool {
+	m.mu.Lock()
+	name, known := m.announced[seat.ID]
+	m.announced[seat.ID] = seat.Name
+	m.mu.Unlock()
+
+	if m.opts.Discovery && (!known || name != seat.Name) {
+		m.enqueue(m.seatDiscovery(seat))
+	}
+	return !known
+}
+
+// ใส่ข้อความในคิว ถ้าคิวเต็มจะทิ้งข้อความ
+func (m *mqttPublisher) enqueue(msg mqtt.Message) {
+	select {
+	case m.queue <- msg:
+	default:
+		mqttDropped.Inc("queue_full")
+		if !m.dropping.Swap(true) {
+			logMQTT.Warn("mqtt.queue_full", "topic", msg.Topic)
+		}
+	}
+}
+
+func (m *mqttPublisher) message(topic string, payload []byte, retain bool) mqtt.Message {
+	return mqtt.Message{Topic: m.base + "/" + topic, Payload: payload, QoS: m.opts.QoS, Retain: retain}
+}
+
+func (m *mqttPublisher) micMessage(seat dcn.Seat) mqtt.Message {
+	payload := "OFF"
+	if seat.MicOn {
+		payload = "ON"
+	}
+	return m.message("seat/"+seat.ID+"/mic", []byte(payload), true)
+}
+
+func (m *mqttPublisher) seatMessage(seat dcn.Seat) mqtt.Message {
+	data, _ := json.Marshal(seat)
+	return m.message("seat/"+seat.ID, data, true)
+}
+
+func (m *mqttPublisher) activeMessage(active []dcn.Seat, at time.Time) mqtt.Message {
+	names := make([]string, 0, len(active))
+	for _, seat := range active {
+		names = append(names, seat.Name)
+	}
+	data, _ := json.Marshal(activeEventJSON{Type: dcn.EVENT_ACTIVE_LIST_CHANGED.String(), Time: at, Names: names, Active: active})
+	return m.message("active", data, true)
+}
+
+func (m *mqttPublisher) statusMessage(online bool) mqtt.Message {
+	payload := "offline"
+	if online {
+		payload = "online"
+	}
+	return m.message("status", []byte(payload), true)
+}
+
+// ข้อมูลอุปกรณ์ที่ใช้ร่วมกันในทุก discovery config
+type haDevice struct {
+	Identifiers  []string `json:"identifiers"`
+	Name         string   `json:"name"`
+	Manufacturer string   `json:"manufacturer"`
+	Model        string   `json:"model"`
+}
+
+// discovery config ของ Home Assistant
+type haConfig struct {
+	Name                string   `json:"name"`
+	UniqueID            string   `json:"unique_id"`
+	ObjectID            string   `json:"object_id"`
+	StateTopic          string   `json:"state_topic"`
+	PayloadOn           string   `json:"payload_on,omitempty"`
+	PayloadOff          string   `json:"payload_off,omitempty"`
+	ValueTemplate       string   `json:"value_template,omitempty"`
+	JSONAttributesTopic string   `json:"json_attributes_topic,omitempty"`
+	AvailabilityTopic   string   `json:"availability_topic"`
+	Icon                string   `json:"icon"`
+	Device              haDevice `json:"device"`
+}
+
+// id ของอุปกรณ์ใน Home Assistant (ใช้ได้เฉพาะตัวอักษร ตัวเลข _ และ -)
+func (m *mqttPublisher) nodeID() string {
+	return "dcn_" + strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' {
+			return r
+		}
+		return '_'
+	}, m.opts.Room)
+}
+
+func (m *mqttPublisher) discovery(component, object string, cfg haConfig) mqtt.Message {
+	node := m.nodeID()
+	cfg.UniqueID = node + "_" + object
+	cfg.ObjectID = cfg.UniqueID
+	cfg.AvailabilityTopic = m.base + "/status"
+	cfg.Device = haDevice{
+		Identifiers:  []string{node},
+		Name:         "DCN " + m.opts.Room,
+		Manufacturer: "Bosch",
+		Model:        "DCN conference system",
+	}
+	data, _ := json.Marshal(cfg)
+	return mqtt.Message{
+		Topic:   m.opts.DiscoveryPrefix + "/" + component + "/" + node + "/" + object + "/config",
+		Payload: data,
+		QoS:     m.opts.QoS,
+		Retain:  true,
+	}
+}
+
+// binary_sensor ของไมค์ที่นั่งหนึ่งที่
+func (m *mqttPublisher) seatDiscovery(seat dcn.Seat) mqtt.Message {
+	name := seat.Name
+	if name == "" {
+		name = "Seat " + seat.ID
+	}
+	return m.discovery("binary_sensor", "seat_"+seat.ID+"_mic", haConfig{
+		Name:                name + " microphone",
+		StateTopic:          m.base + "/seat/" + seat.ID + "/mic",
+		PayloadOn:           "ON",
+		PayloadOff:          "OFF",
+		JSONAttributesTopic: m.base + "/seat/" + seat.ID,
+		Icon:                "mdi:microphone",
+	})
+}
+
+// sensor จำนวนไมค์ที่เปิดอยู่ พร้อมรายชื่อใน attributes
+func (m *mqttPublisher) activeDiscovery() mqtt.Message {
+	return m.discovery("sensor", "active_microphones", haConfig{
+		Name:                "Active microphones",
+		StateTopic:          m.base + "/active",
+		ValueTemplate:       "{{ value_json.active | count }}",
+		JSONAttributesTopic: m.base + "/active",
+		Icon:                "mdi:account-voice",
+	})
+}
+
+// ข้อความที่แทนสถานะปัจจุบันทั้งหมด publish ทุกครั้งที่เชื่อมต่อ broker ได้
+func (m *mqttPublisher) snapshot() []mqtt.Message {
+	msgs := []mqtt.Message{m.statusMessage(true)}
+	if m.opts.Discovery {
+		msgs = append(msgs, m.activeDiscovery())
+	}
+
+	seats := m.state.Seats()
+	m.mu.Lock()
+	for _, seat := range seats {
+		m.announced[seat.ID] = seat.Name
+	}
+	m.mu.Unlock()
+
+	for _, seat := range seats {
+		if m.opts.Discovery {
+			msgs = append(msgs, m.seatDiscovery(seat))
+		}
+		msgs = append(msgs, m.micMessage(seat), m.seatMessage(seat))
+	}
+	return append(msgs, m.activeMessage(m.state.Active(), m.state.Discussion().Changed))
+}
+
+// เชื่อมต่อ broker และ publish ข้อความในคิวจนกว่า ctx จะถูกยกเลิก แล้วส่งข้อความที่เหลือและสถานะ offline
+func (m *mqttPublisher) run(ctx context.Context) {
+	opts := mqtt.Options{
+		ClientID:  m.opts.ClientID,
+		Username:  m.opts.Username,
+		Password:  m.opts.Password,
+		KeepAlive: m.opts.KeepAlive,
+		Will:      &mqtt.Message{Topic: m.base + "/status", Payload: []byte("offline"), QoS: m.opts.QoS, Retain: true},
+	}
+
+	delay := time.Second
+	for {
+		logMQTT.Info("mqtt.connecting", "addr", m.opts.Addr)
+		client, err := mqtt.Dial(ctx, m.opts.Addr, opts)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			logMQTT.Warn("mqtt.connect_failed", "addr", m.opts.Addr, "error", err, "retry_in", delay.String())
+			if !sleepContext(ctx, delay) {
+				return
+			}
+			delay = min(delay*2, RECONNECT_MAX_DELAY*time.Second)
+			continue
+		}
+		delay = time.Second
+
+		m.connected.Store(true)
+		m.dropping.Store(false)
+		logMQTT.Info("mqtt.connected", "addr", m.opts.Addr, "topic", m.base)
+		err = m.publishLoop(ctx, client)
+		m.connected.Store(false)
+		if ctx.Err() != nil {
+			client.Close()
+			return
+		}
+		client.Close()
+		logMQTT.Warn("mqtt.disconnected", "addr", m.opts.Addr, "error", err)
+		if !sleepContext(ctx, delay) {
+			return
+		}
+	}
+}
+
+// publish สถานะปัจจุบันแล้วตามด้วยข้อความในคิว จนกว่าการเชื่อมต่อหลุดหรือ ctx ถูกยกเลิก
+func (m *mqttPublisher) publishLoop(ctx context.Context, client *mqtt.Client) error {
+	// ข้อความ retained ที่ค้างในคิวเก่ากว่า snapshot เก็บไว้เฉพาะเหตุการณ์
+	var pending []mqtt.Message
+	for drained := false; !drained; {
+		select {
+		case msg := <-m.queue:
+			if !msg.Retain {
+				pending = append(pending, msg)
+			}
+		default:
+			drained = true
+		}
+	}
+	for _, msg := range append(m.snapshot(), pending...) {
+		if err := m.publish(client, msg); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case msg := <-m.queue:
+			if err := m.publish(client, msg); err != nil {
+				return err
+			}
+		case <-client.Done():
+			return client.Err()
+		case <-ctx.Done():
+			// ส่งข้อความที่เหลือ (เช่นสถานะปิดไมค์ตอนปิด proxy) ก่อนประกาศ offline
+			for {
+				select {
+				case msg := <-m.queue:
+					if err := m.publish(client, msg); err != nil {
+						return err
+					}
+				default:
+					return m.publish(client, m.statusMessage(false))
+				}
+			}
+		}
+	}
+}
+
+func (m *mqttPublisher) publish(client *mqtt.Client, msg mqtt.Message) error {
+	if err := client.Publish(msg); err != nil {
+		mqttDropped.Inc("publish_error")
+		logMQTT.Warn("mqtt.publish_failed", "topic", msg.Topic, "error", err)
+		return err
+	}
+	mqttPublished.Inc()
+	return nil
+}
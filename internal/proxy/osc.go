@@ -0,0 +1,381 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/ampol-me/phi-DCN/dcn"
+	"github.com/ampol-me/phi-DCN/internal/osc"
+)
+
+// ไฟล์ตั้งค่าของ OSC เป็น JSON:
+//
+//	{
+//	    "addr": "192.168.1.70:10023",
+//	    "debounce": "150ms",
+//	    "seats": {"7": 1, "12": 2, "13": 2},
+//	    "on":  [{"address": "/ch/{{printf \"%02d\" .Channel}}/mix/on", "args": [1]}],
+//	    "off": [{"address": "/ch/{{printf \"%02d\" .Channel}}/mix/on", "args": [0]}]
+//	}
+//
+// seats จับคู่ Seat Id กับหมายเลขช่องของ console หลายที่นั่งใช้ช่องเดียวกันได้ (ช่องเปิดเมื่อมีที่นั่งใดเปิดไมค์)
+// on และ off คือข้อความที่ส่งเมื่อช่องเปิดและปิด address และ argument ที่เป็น string เป็น text/template
+// ที่ใช้ .Channel, .SeatID และ .Seat (ชื่อที่นั่ง) ได้ ชนิดของ argument ดูจากค่าใน JSON:
+// ตัวเลขที่ไม่มีจุดทศนิยมเป็น int32 (i), ตัวเลขที่มีจุดทศนิยมเป็น float32 (f เช่น 0.75 หรือ 1.0),
+// string เป็น s และ true/false เป็น T/F
+//
+// preset กำหนด on และ off สำเร็จรูปแทนได้: x32 = /ch/NN/mix/on 1/0 ของ Behringer X32 และ Midas M32
+//
+// debounce คือเวลาที่สถานะของช่องต้องคงที่ก่อนส่ง (ไมค์ที่เปิดแล้วปิดภายในเวลานี้จะไม่ถูกส่ง)
+// dryRun แสดงข้อความใน log แทนการส่ง
+const (
+	OSC_PRESET_X32 = "x32"
+	OSC_DEBOUNCE   = 100 * time.Millisecond
+)
+
+// ข้อความ OSC หนึ่งข้อความในไฟล์ตั้งค่า
+type OSCMessageConfig struct {
+	Address string `json:"address"`
+	Args    []any  `json:"args"`
+}
+
+// การตั้งค่าของ OSC
+type OSCConfig struct {
+	Addr     string             `json:"addr"` // host:port ของ console (ไม่ต้องกำหนดเมื่อ dryRun)
+	DryRun   bool               `json:"dryRun"`
+	Preset   string             `json:"preset"`
+	Debounce *configDuration    `json:"debounce"` // ค่าเริ่มต้น OSC_DEBOUNCE
+	Seats    map[string]int     `json:"seats"`
+	On       []OSCMessageConfig `json:"on"`
+	Off      []OSCMessageConfig `json:"off"`
+}
+
+// ข้อมูลที่ใช้ใน template ของข้อความ
+type oscData struct {
+	Channel int
+	SeatID  string // ที่นั่งที่ทำให้สถานะของช่องเปลี่ยน
+	Seat    string
+}
+
+// ข้อความที่ compile แล้ว argument เป็น int32, float32, bool หรือ *template.Template
+type oscTemplate struct {
+	address *template.Template
+	args    []any
+}
+
+var oscPresets = map[string][2][]OSCMessageConfig{
+	OSC_PRESET_X32: {
+		{{Address: `/ch/{{printf "%02d" .Channel}}/mix/on`, Args: []any{json.Number("1")}}},
+		{{Address: `/ch/{{printf "%02d" .Channel}}/mix/on`, Args: []any{json.Number("0")}}},
+	},
+}
+
+// อ่านและตรวจสอบไฟล์ตั้งค่าของ OSC
+func LoadOSC(path string) (OSCConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return OSCConfig{}, err
+	}
+	var cfg OSCConfig
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	decoder.UseNumber()
+	if err := decoder.Decode(&cfg); err != nil {
+		return OSCConfig{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if cfg.Addr != "" {
+		if _, _, err := net.SplitHostPort(cfg.Addr); err != nil {
+			return OSCConfig{}, fmt.Errorf("%s: addr %q ต้องเป็น host:port", path, cfg.Addr)
+		}
+	}
+	if cfg.Preset != "" {
+		preset, ok := oscPresets[cfg.Preset]
+		if !ok {
+			return OSCConfig{}, fmt.Errorf("%s: ไม่รู้จัก preset %q (ใช้ x32)", path, cfg.Preset)
+		}
+		if cfg.On == nil {
+			cfg.On = preset[0]
+		}
+		if cfg.Off == nil {
+			cfg.Off = preset[1]
+		}
+	}
+	if len(cfg.On) == 0 && len(cfg.Off) == 0 {
+		return OSCConfig{}, fmt.Errorf("%s: ต้องกำหนด on, off หรือ preset", path)
+	}
+	if len(cfg.Seats) == 0 {
+		return OSCConfig{}, fmt.Errorf("%s: ต้องกำหนด seats อย่างน้อยหนึ่งที่", path)
+	}
+	for id, channel := range cfg.Seats {
+		if channel < 0 {
+			return OSCConfig{}, fmt.Errorf("%s: ที่นั่ง %s: หมายเลขช่องต้องไม่ติดลบ", path, id)
+		}
+	}
+	if cfg.Debounce == nil {
+		debounce := configDuration(OSC_DEBOUNCE)
+		cfg.Debounce = &debounce
+	}
+
+	// ตรวจสอบ template ด้วยข้อมูลตัวอย่าง
+	for name, messages := range map[string][]OSCMessageConfig{"on": cfg.On, "off": cfg.Off} {
+		templates, err := compileOSC(messages)
+		if err != nil {
+			return OSCConfig{}, fmt.Errorf("%s: %s: %w", path, name, err)
+		}
+		for _, t := range templates {
+			if _, err := t.render(oscData{Channel: 1, SeatID: "1", Seat: "1"}); err != nil {
+				return OSCConfig{}, fmt.Errorf("%s: %s: %w", path, name, err)
+			}
+		}
+	}
+	return cfg, nil
+}
+
+func compileOSC(messages []OSCMessageConfig) ([]oscTemplate, error) {
+	var templates []oscTemplate
+	for _, m := range messages {
+		address, err := template.New("address").Option("missingkey=error").Parse(m.Address)
+		if err != nil {
+			return nil, err
+		}
+		t := oscTemplate{address: address}
+		for _, arg := range m.Args {
+			switch v := arg.(type) {
+			case json.Number:
+				if strings.ContainsAny(v.String(), ".eE") {
+					f, err := v.Float64()
+					if err != nil {
+						return nil, err
+					}
+					t.args = append(t.args, float32(f))
+				} else {
+					i, err := v.Int64()
+					if err != nil || i < math.MinInt32 || i > math.MaxInt32 {
+						return nil, fmt.Errorf("argument %s อยู่นอกช่วงของ int32", v)
+					}
+					t.args = append(t.args, int32(i))
+				}
+			case string:
+				arg, err := template.New("arg").Option("missingkey=error").Parse(v)
+				if err != nil {
+					return nil, err
+				}
+				t.args = append(t.args, arg)
+			case bool:
+				t.args = append(t.args, v)
+			default:
+				return nil, fmt.Errorf("ไม่รองรับ argument %v (ใช้ตัวเลข, string หรือ true/false)", arg)
+			}
+		}
+		templates = append(templates, t)
+	}
+	return templates, nil
+}
+
+func (t oscTemplate) render(data oscData) (osc.Message, error) {
+	var buf strings.Builder
+	if err := t.address.Execute(&buf, data); err != nil {
+		return osc.Message{}, err
+	}
+	m := osc.Message{Address: buf.String()}
+	for _, arg := range t.args {
+		if tmpl, ok := arg.(*template.Template); ok {
+			buf.Reset()
+			if err := tmpl.Execute(&buf, data); err != nil {
+				return osc.Message{}, err
+			}
+			arg = buf.String()
+		}
+		m.Args = append(m.Args, arg)
+	}
+	if _, err := osc.Encode(m); err != nil {
+		return osc.Message{}, err
+	}
+	return m, nil
+}
+
+// สถานะของช่องหนึ่งช่อง
+type oscChannel struct {
+	sent  bool // สถานะล่าสุดที่ส่งไปแล้ว
+	known bool // ส่งสถานะของช่องนี้แล้วอย่างน้อยหนึ่งครั้ง
+	timer *time.Timer
+}
+
+// เปิดและปิดช่องของ mixing console ตามสถานะไมค์ด้วย OSC
+type oscPublisher struct {
+	cfg     OSCConfig
+	on, off []oscTemplate
+	state   *dcn.State
+	client  *osc.Client // nil เมื่อ dryRun
+
+	mu       sync.Mutex
+	open     map[string]bool // ที่นั่งที่เปิดไมค์อยู่ (เฉพาะที่นั่งใน seats)
+	channels map[int]*oscChannel
+	closed   bool
+}
+
+func newOSCPublisher(cfg OSCConfig, state *dcn.State) (*oscPublisher, error) {
+	o := &oscPublisher{
+		cfg:      cfg,
+		state:    state,
+		open:     make(map[string]bool),
+		channels: make(map[int]*oscChannel),
+	}
+	// compile ไม่ผิดพลาดเพราะตรวจสอบแล้วใน LoadOSC
+	o.on, _ = compileOSC(cfg.On)
+	o.off, _ = compileOSC(cfg.Off)
+	for _, channel := range cfg.Seats {
+		o.channels[channel] = &oscChannel{}
+	}
+	if !cfg.DryRun {
+		client, err := osc.Dial(cfg.Addr)
+		if err != nil {
+			return nil, err
+		}
+		o.client = client
+	}
+	return o, nil
+}
+
+// รับเหตุการณ์จาก seat state
+func (o *oscPublisher) handleEvent(ev dcn.Event) {
+	if ev.Type != dcn.EVENT_MIC_ON && ev.Type != dcn.EVENT_MIC_OFF {
+		return
+	}
+	channel, ok := o.cfg.Seats[ev.Seat.ID]
+	if !ok {
+		return
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if ev.Seat.MicOn {
+		o.open[ev.Seat.ID] = true
+	} else {
+		delete(o.open, ev.Seat.ID)
+	}
+	o.schedule(channel, oscData{Channel: channel, SeatID: ev.Seat.ID, Seat: ev.Seat.Name})
+}
+
+// ช่องควรเปิดหรือไม่ (ต้องถือ mu อยู่)
+func (o *oscPublisher) wanted(channel int) bool {
+	for id := range o.open {
+		if o.cfg.Seats[id] == channel {
+			return true
+		}
+	}
+	return false
+}
+
+// ส่งสถานะของช่องเมื่อคงที่ครบ debounce (ต้องถือ mu อยู่)
+func (o *oscPublisher) schedule(channel int, data oscData) {
+	c := o.channels[channel]
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	if o.closed || c.known && c.sent == o.wanted(channel) {
+		return
+	}
+	debounce := time.Duration(*o.cfg.Debounce)
+	if debounce == 0 {
+		o.send(c, data)
+		return
+	}
+	c.timer = time.AfterFunc(debounce, func() {
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		if o.closed || c.known && c.sent == o.wanted(channel) {
+			return
+		}
+		o.send(c, data)
+	})
+}
+
+// ส่งข้อความ on หรือ off ของช่องตามสถานะปัจจุบัน (ต้องถือ mu อยู่)
+func (o *oscPublisher) send(c *oscChannel, data oscData) {
+	on := o.wanted(data.Channel)
+	templates := o.off
+	if on {
+		templates = o.on
+	}
+	c.sent, c.known = on, true
+
+	for _, t := range templates {
+		m, err := t.render(data)
+		if err != nil {
+			logOSC.Warn("osc.template_failed", "channel", data.Channel, "error", err)
+			oscMessages.Inc("error")
+			continue
+		}
+		if o.client == nil {
+			logOSC.Info("osc.dry_run", "message", m.String(), "channel", data.Channel, "seat_id", data.SeatID)
+			oscMessages.Inc("dry_run")
+			continue
+		}
+		if err := o.client.Send(m); err != nil {
+			logOSC.Warn("osc.send_failed", "message", m.String(), "addr", o.cfg.Addr, "error", err)
+			oscMessages.Inc("error")
+			continue
+		}
+		logOSC.Debug("osc.sent", "message", m.String(), "channel", data.Channel, "seat_id", data.SeatID)
+		oscMessages.Inc("sent")
+	}
+}
+
+// ส่งสถานะของทุกช่องตามสถานะไมค์ปัจจุบัน แล้วรอจนกว่า ctx จะถูกยกเลิก
+//
+// เมื่อหยุดจะส่งช่องที่ยังรอ debounce ทันที (ไมค์ถูกปิดหมดแล้วก่อนยกเลิก ctx ช่องจึงถูกปิด)
+// หลังจากนั้นไม่ส่งข้อความเพิ่ม ช่องของ console จึงคงสถานะสุดท้ายไว้
+func (o *oscPublisher) run(ctx context.Context) {
+	mode := "send"
+	if o.client == nil {
+		mode = "dry-run"
+	}
+	logOSC.Info("osc.started", "addr", o.cfg.Addr, "channels", len(o.channels), "mode", mode)
+
+	o.mu.Lock()
+	for _, seat := range o.state.Seats() {
+		if _, ok := o.cfg.Seats[seat.ID]; ok && seat.MicOn {
+			o.open[seat.ID] = true
+		}
+	}
+	channels := make([]int, 0, len(o.channels))
+	for channel := range o.channels {
+		channels = append(channels, channel)
+	}
+	slices.Sort(channels)
+	for _, channel := range channels {
+		o.send(o.channels[channel], oscData{Channel: channel})
+	}
+	o.mu.Unlock()
+
+	<-ctx.Done()
+	o.mu.Lock()
+	for _, channel := range channels {
+		c := o.channels[channel]
+		if c.timer != nil {
+			c.timer.Stop()
+			c.timer = nil
+		}
+		if !c.known || c.sent != o.wanted(channel) {
+			o.send(c, oscData{Channel: channel})
+		}
+	}
+	o.closed = true
+	o.mu.Unlock()
+	if o.client != nil {
+		o.client.Close()
+	}
+}
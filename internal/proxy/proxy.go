@@ -0,0 +1,513 @@
+// Package proxy เชื่อมต่อกับ Bosch DCN server แล้วส่งต่อทุก frame ไปยัง clients ที่เชื่อมต่อเข้ามา
+// พร้อมติดตามสถานะไมค์ บันทึก journal/capture/pcapng เปิด HTTP endpoint สำหรับ metrics และ health check
+// และ publish สถานะที่นั่งไปยัง MQTT broker
+package proxy
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ampol-me/phi-DCN/dcn"
+	"github.com/ampol-me/phi-DCN/internal/journal"
+	"github.com/ampol-me/phi-DCN/internal/logging"
+	"github.com/ampol-me/phi-DCN/internal/pcap"
+	"github.com/ampol-me/phi-DCN/internal/websocket"
+	"github.com/ampol-me/phi-DCN/protocol"
+)
+
+// logger ของแต่ละ subsystem
+var (
+	logMain     = logging.New("main")
+	logUpstream = logging.New("upstream")
+	logClients  = logging.New("clients")
+	logFraming  = logging.New("framing")
+	logHTTP     = logging.New("http")
+	logJournal  = logging.New("journal")
+	logCapture  = logging.New("capture")
+	logSeats    = logging.New("seats")
+	logStream   = logging.New("stream")
+	logMQTT     = logging.New("mqtt")
+	logWebhook  = logging.New("webhook")
+	logDirector = logging.New("director")
+	logCamera   = logging.New("camera")
+	logTally    = logging.New("tally")
+	logOSC      = logging.New("osc")
+	logDMX      = logging.New("dmx")
+)
+
+const (
+	UPSTREAM_ADDR   = "localhost:20000" // ที่อยู่ของ Bosch DCN server
+	LISTEN_ADDR     = ":20001"          // ที่อยู่ที่รอ clients เชื่อมต่อ
+	CONNECT_TIMEOUT = 5                 // timeout การเชื่อมต่อ (วินาที)
+	READ_TIMEOUT    = 10                // timeout การรับข้อมูล (วินาที)
+	SEND_QUEUE_SIZE = 64                // จำนวน frame สูงสุดที่รอส่งต่อ client
+	DRAIN_TIMEOUT   = 5                 // เวลารอส่งข้อมูลที่ค้างในคิวตอนปิด proxy (วินาที)
+	HTTP_ADDR       = ":20081"          // ที่อยู่ของ HTTP endpoint (/metrics, /healthz, /readyz)
+
+	RECONNECT_MAX_DELAY = 30 // ระยะเวลารอสูงสุดก่อนเชื่อมต่อ server ใหม่ (วินาที)
+)
+
+// สร้าง frame DiscussionActivity ที่ไม่มีไมค์เปิดอยู่ สำหรับส่งก่อนปิด proxy
+func allMicsOffFrame() []byte {
+	payload := protocol.EncodeUTF16LE(protocol.DiscussionActivityXML(nil, time.Now()))
+	return protocol.BuildFrame(protocol.TOPIC_DISCUSSION, payload)
+}
+
+// ชนิดการเชื่อมต่อของ client
+const (
+	TRANSPORT_TCP       = "tcp"
+	TRANSPORT_WEBSOCKET = "websocket"
+)
+
+// โครงสร้างสำหรับเก็บข้อมูล client
+type Client struct {
+	conn      net.Conn // การเชื่อมต่อ TCP หรือ *websocket.Conn ที่ส่ง frame ละหนึ่งข้อความ
+	id        int
+	transport string
+	filter    protocol.FrameFilter // frame ที่ client ต้องการรับ (ว่าง = ทุก frame)
+	send      chan []byte          // คิวข้อมูลที่รอส่ง
+	done      chan struct{}        // ปิดเมื่อ writeLoop ทำงานเสร็จ
+}
+
+// ฟังก์ชันสำหรับส่งข้อมูลไปยัง client
+func (c *Client) Send(data []byte) error {
+	_, err := c.conn.Write(data)
+	return err
+}
+
+// ProxyServer จัดการการเชื่อมต่อของ clients
+type ProxyServer struct {
+	clients    map[int]*Client
+	nextID     int
+	closed     bool
+	clientLock sync.Mutex
+
+	// frame ล่าสุดของแต่ละที่นั่งและ DiscussionActivity ล่าสุด สำหรับ snapshot ของ client ใหม่ (ป้องกันด้วย clientLock)
+	lastSeats      map[string][]byte
+	lastDiscussion []byte
+
+	seats    *dcn.State  // สถานะที่นั่งที่รวมจาก SeatActivity และ DiscussionActivity
+	mics     *micTracker // เวลาพูดสะสมของแต่ละที่นั่งสำหรับ metrics
+	health   *proxyHealth
+	journal  *journal.Journal   // nil เมื่อไม่ได้เปิดการบันทึก journal
+	pcapng   *pcap.Writer       // nil เมื่อไม่ได้เปิดการบันทึก pcapng
+	captures *Capture           // บันทึก frame ที่ได้รับเป็นไฟล์ JSON Lines
+	events   *eventHub          // ส่งเหตุการณ์ของ seat state ไปยัง WebSocket และ SSE clients
+	mqtt     *mqttPublisher     // nil เมื่อไม่ได้เปิดการ publish ไปยัง MQTT broker
+	webhooks *webhookDispatcher // nil เมื่อไม่ได้กำหนด webhook
+	director *director          // nil เมื่อไม่ได้เปิด camera director
+
+	listeners    []func(dcn.Event) // ฟังก์ชันที่รับเหตุการณ์ของ seat state
+	listenerLock sync.Mutex
+}
+
+// สร้าง ProxyServer ใหม่
+func NewProxyServer(listenAddr string, thresholds HealthThresholds) *ProxyServer {
+	p := &ProxyServer{
+		clients:   make(map[int]*Client),
+		nextID:    1,
+		lastSeats: make(map[string][]byte),
+		seats:     dcn.NewState(),
+		mics:      newMicTracker(),
+		health:    &proxyHealth{startedAt: time.Now(), listenAddr: listenAddr, thresholds: thresholds},
+		events:    newEventHub(),
+	}
+	p.OnSeatEvent(func(ev dcn.Event) {
+		if se, ok := newStreamEvent(ev); ok {
+			p.events.publish(se)
+		}
+	})
+	return p
+}
+
+// เพิ่ม client ใหม่ (คืนค่า nil ถ้า proxy กำลังปิด)
+func (p *ProxyServer) AddClient(conn net.Conn, transport string, filter protocol.FrameFilter) *Client {
+	p.clientLock.Lock()
+	defer p.clientLock.Unlock()
+
+	if p.closed {
+		conn.Close()
+		return nil
+	}
+
+	client := &Client{
+		conn:      conn,
+		id:        p.nextID,
+		transport: transport,
+		filter:    filter,
+		send:      make(chan []byte, SEND_QUEUE_SIZE),
+		done:      make(chan struct{}),
+	}
+	p.clients[p.nextID] = client
+	p.nextID++
+
+	// pcapng บันทึกเฉพาะ TCP เพราะ frame ใน WebSocket ถูกห่อด้วย header ของ WebSocket อีกชั้น
+	if transport == TRANSPORT_TCP {
+		p.pcapngOpen(conn.RemoteAddr(), conn.LocalAddr())
+	}
+
+	go p.writeLoop(client)
+
+	logClients.Info("client.connected", "id", client.id, "addr", conn.RemoteAddr().String(), "transport", transport)
+	return client
+}
+
+// ส่ง frame ล่าสุดของแต่ละที่นั่งและ DiscussionActivity ล่าสุดให้ client ที่เพิ่งเชื่อมต่อ
+func (p *ProxyServer) sendSnapshot(client *Client) {
+	p.clientLock.Lock()
+	defer p.clientLock.Unlock()
+
+	// client อาจถูกลบไปแล้วระหว่างนี้
+	if p.clients[client.id] != client {
+		return
+	}
+
+	ids := make([]string, 0, len(p.lastSeats))
+	for id := range p.lastSeats {
+		ids = append(ids, id)
+	}
+	slices.SortFunc(ids, func(a, b string) int {
+		x, errX := strconv.Atoi(a)
+		y, errY := strconv.Atoi(b)
+		if errX == nil && errY == nil {
+			return x - y
+		}
+		return strings.Compare(a, b)
+	})
+
+	frames := make([][]byte, 0, len(ids)+1)
+	for _, id := range ids {
+		frames = append(frames, p.lastSeats[id])
+	}
+	if p.lastDiscussion != nil {
+		frames = append(frames, p.lastDiscussion)
+	}
+	for _, frame := range frames {
+		if !client.filter.Match(frame, "") {
+			continue
+		}
+		select {
+		case client.send <- frame:
+		default:
+		}
+	}
+}
+
+// ลืม frame SeatActivity ที่เก็บไว้ เมื่อสถานะที่นั่งจาก upstream ไม่เป็นจริงแล้ว
+func (p *ProxyServer) forgetSeatFrames() {
+	p.clientLock.Lock()
+	defer p.clientLock.Unlock()
+
+	clear(p.lastSeats)
+}
+
+// ลบ client
+func (p *ProxyServer) RemoveClient(id int) {
+	p.clientLock.Lock()
+	defer p.clientLock.Unlock()
+
+	if client, exists := p.clients[id]; exists {
+		logClients.Info("client.disconnected", "id", id, "addr", client.conn.RemoteAddr().String())
+		close(client.send)
+		client.conn.Close()
+		delete(p.clients, id)
+	}
+}
+
+// ส่งข้อมูลในคิวไปยัง client ทีละ frame จนกว่าคิวจะถูกปิด
+func (p *ProxyServer) writeLoop(client *Client) {
+	defer close(client.done)
+	if client.transport == TRANSPORT_TCP {
+		defer p.pcapngClose(client.conn.LocalAddr(), client.conn.RemoteAddr())
+	}
+
+	for data := range client.send {
+		err := client.Send(data)
+		if err != nil {
+			logClients.Warn("client.write_failed", "id", client.id, "error", err)
+			writeErrors.Inc()
+			clientEvictions.Inc("write_error")
+			// ถ้าส่งไม่ได้ให้ลบ client ออก
+			go p.RemoveClient(client.id)
+			// อ่านคิวให้หมดเพื่อไม่ให้ Broadcast ค้าง
+			for range client.send {
+			}
+			return
+		}
+		bytesSent.Add(float64(len(data)))
+		if client.transport == TRANSPORT_TCP {
+			p.pcapngWrite(client.conn.LocalAddr(), client.conn.RemoteAddr(), data)
+		}
+	}
+}
+
+// ส่งข้อมูลไปยังทุก clients
+func (p *ProxyServer) Broadcast(data []byte) {
+	p.clientLock.Lock()
+	defer p.clientLock.Unlock()
+
+	topic := protocol.FrameTopic(data)
+	framesBroadcast.Inc(strconv.Itoa(int(topic)))
+
+	var seatID string
+	switch topic {
+	case protocol.TOPIC_DISCUSSION:
+		p.lastDiscussion = data
+	case protocol.TOPIC_SEAT:
+		if id, err := protocol.SeatIDFromPayload(data[protocol.HEADER_SIZE:]); err == nil {
+			seatID = id
+			p.lastSeats[id] = data
+		}
+	}
+
+	for id, client := range p.clients {
+		if !client.filter.Match(data, seatID) {
+			continue
+		}
+		select {
+		case client.send <- data:
+		default:
+			logClients.Warn("client.queue_full", "id", id)
+			clientEvictions.Inc("queue_full")
+			go p.RemoveClient(id)
+		}
+	}
+}
+
+// บันทึก frame (header + payload) ลง journal ถ้าเปิดไว้
+func (p *ProxyServer) journalFrame(dir journal.Direction, frame []byte) {
+	if p.journal == nil {
+		return
+	}
+	topic := protocol.FrameTopic(frame)
+	if _, err := p.journal.Append(dir, topic, frame[8:], time.Now()); err != nil {
+		logJournal.Warn("journal.write_failed", "topic", topic, "error", err)
+		journalErrors.Inc()
+	}
+}
+
+// บันทึกข้อมูลที่ส่งจาก src ไปยัง dst ลงไฟล์ pcapng ถ้าเปิดไว้
+func (p *ProxyServer) pcapngWrite(src, dst net.Addr, data []byte) {
+	if p.pcapng == nil {
+		return
+	}
+	if err := p.pcapng.Write(src, dst, data, time.Now()); err != nil {
+		logCapture.Warn("pcapng.write_failed", "src", src.String(), "dst", dst.String(), "error", err)
+		pcapngErrors.Inc()
+	}
+}
+
+// บันทึก handshake ของการเชื่อมต่อใหม่ลงไฟล์ pcapng ถ้าเปิดไว้
+func (p *ProxyServer) pcapngOpen(initiator, responder net.Addr) {
+	if p.pcapng == nil {
+		return
+	}
+	if err := p.pcapng.OpenFlow(initiator, responder, time.Now()); err != nil {
+		logCapture.Warn("pcapng.write_failed", "src", initiator.String(), "dst", responder.String(), "error", err)
+		pcapngErrors.Inc()
+	}
+}
+
+// บันทึกการปิดการเชื่อมต่อลงไฟล์ pcapng ถ้าเปิดไว้
+func (p *ProxyServer) pcapngClose(src, dst net.Addr) {
+	if p.pcapng == nil {
+		return
+	}
+	if err := p.pcapng.CloseFlow(src, dst, time.Now()); err != nil {
+		logCapture.Warn("pcapng.write_failed", "src", src.String(), "dst", dst.String(), "error", err)
+		pcapngErrors.Inc()
+	}
+}
+
+// ส่ง frame ที่ proxy สร้างขึ้นเองไปยังทุก clients และบันทึกลง journal
+func (p *ProxyServer) BroadcastGenerated(frame []byte) {
+	p.journalFrame(journal.DIR_SENT, frame)
+	p.Broadcast(frame)
+}
+
+// ปิด proxy: รอส่งข้อมูลที่ค้างในคิวจนกว่า ctx จะหมดเวลา แล้วปิดการเชื่อมต่อทั้งหมด
+func (p *ProxyServer) Shutdown(ctx context.Context) {
+	p.clientLock.Lock()
+	p.closed = true
+	clients := make([]*Client, 0, len(p.clients))
+	for id, client := range p.clients {
+		close(client.send)
+		clients = append(clients, client)
+		delete(p.clients, id)
+	}
+	p.clientLock.Unlock()
+
+	for _, client := range clients {
+		select {
+		case <-client.done:
+		case <-ctx.Done():
+			logClients.Warn("client.drain_timeout", "id", client.id, "queued", len(client.send))
+		}
+		if ws, ok := client.conn.(*websocket.Conn); ok {
+			ws.CloseWithStatus(websocket.CLOSE_GOING_AWAY, "shutting down")
+		} else {
+			client.conn.Close()
+		}
+	}
+}
+
+// จัดการการเชื่อมต่อจาก client
+func handleClientConnection(proxy *ProxyServer, conn net.Conn) {
+	client := proxy.AddClient(conn, TRANSPORT_TCP, protocol.FrameFilter{})
+	if client == nil {
+		return
+	}
+	defer proxy.RemoveClient(client.id)
+	proxy.sendSnapshot(client)
+
+	// รอรับข้อมูลจาก client (ถ้าต้องการในอนาคต)
+	buffer := make([]byte, 4096)
+	for {
+		_, err := conn.Read(buffer)
+		if err != nil {
+			return
+		}
+	}
+}
+
+func handleConnection(ctx context.Context, conn net.Conn, proxy *ProxyServer) {
+	defer conn.Close()
+
+	// ปิดการเชื่อมต่อเมื่อ ctx ถูกยกเลิก เพื่อให้ Read คืนค่า
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	logUpstream.Info("upstream.connected", "addr", conn.RemoteAddr().String())
+	proxy.health.upstream.setConnected(true, conn.RemoteAddr().String())
+	defer proxy.health.upstream.setConnected(false, "")
+
+	proxy.pcapngOpen(conn.LocalAddr(), conn.RemoteAddr())
+	defer proxy.pcapngClose(conn.LocalAddr(), conn.RemoteAddr())
+
+	proxy.captures.StartSession(conn.RemoteAddr().String(), time.Now())
+	defer proxy.captures.EndSession()
+
+	// แยก frame จากข้อมูลที่ได้รับ
+	decoder := &protocol.FrameDecoder{}
+
+	for {
+		// อ่านข้อมูลใหม่เข้ามาในบัฟเฟอร์
+		buffer := make([]byte, 4096)
+		n, err := conn.Read(buffer)
+		if err != nil {
+			if ctx.Err() != nil {
+				logUpstream.Info("upstream.shutdown")
+				return
+			}
+			logUpstream.Warn("upstream.closed", "error", err)
+			return
+		}
+
+		upstreamBytes.Add(float64(n))
+		proxy.pcapngWrite(conn.RemoteAddr(), conn.LocalAddr(), buffer[:n])
+
+		events, skipped := decoder.ResyncEvents, decoder.ResyncBytes
+		frames := decoder.Feed(buffer[:n])
+		resyncEvents.Add(float64(decoder.ResyncEvents - events))
+		resyncBytes.Add(float64(decoder.ResyncBytes - skipped))
+
+		for _, messageData := range frames {
+			// แสดงข้อมูลดิบ 16 bytes แรกเพื่อดีบัก
+			logFraming.Debug("frame.raw", "hex", fmt.Sprintf("% x", messageData[:min(len(messageData), 16)]))
+
+			// บันทึกลงไฟล์ capture ถ้าเปิดไว้
+			if err := proxy.captures.Record(messageData, time.Now()); err != nil {
+				logCapture.Warn("capture.write_failed", "error", err)
+				captureErrors.Inc()
+			}
+
+			// อ่าน header
+			topic := binary.LittleEndian.Uint32(messageData[0:4])
+			length := binary.LittleEndian.Uint32(messageData[4:8])
+			logFraming.Debug("frame.header", "topic", topic, "length", length)
+
+			upstreamFrames.Inc(strconv.Itoa(int(topic)))
+			proxy.health.upstream.frameReceived()
+
+			// ส่งข้อมูลทั้ง header และ XML ไปยัง clients
+			proxy.journalFrame(journal.DIR_RECEIVED, messageData)
+			proxy.Broadcast(messageData)
+
+			// แปลง UTF-16LE เป็น UTF-8 ถ้าจำเป็น
+			xmlStr := protocol.DecodePayload(messageData[protocol.HEADER_SIZE:])
+
+			if err := proxy.applySeatFrame(topic, xmlStr, time.Now()); err != nil {
+				logFraming.Warn("frame.decode_failed", "topic", topic, "error", err)
+				decodeErrors.Inc(strconv.Itoa(int(topic)))
+			}
+
+			// แยกและจัดรูปแบบ XML (เฉพาะเมื่อเปิด debug ของ framing เพราะมีค่าใช้จ่ายสูง)
+			if logFraming.Enabled(slog.LevelDebug) {
+				for _, xml := range protocol.PrettyXML(xmlStr) {
+					logFraming.Debug("frame.xml", "topic", topic, "topic_name", protocol.TopicName(topic), "xml", xml)
+				}
+			}
+		}
+	}
+}
+
+// เชื่อมต่อ upstream ซ้ำด้วย backoff จนกว่า ctx จะถูกยกเลิก
+func connectUpstream(ctx context.Context, serverAddr string, proxy *ProxyServer) {
+	// สร้าง dialer พร้อม timeout
+	dialer := net.Dialer{
+		Timeout: time.Duration(CONNECT_TIMEOUT) * time.Second,
+	}
+
+	delay := time.Second
+	for {
+		logUpstream.Info("upstream.connecting", "addr", serverAddr)
+		conn, err := dialer.DialContext(ctx, "tcp", serverAddr)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			logUpstream.Warn("upstream.connect_failed", "addr", serverAddr, "error", err, "retry_in", delay.String())
+			if !sleepContext(ctx, delay) {
+				return
+			}
+			delay = min(delay*2, RECONNECT_MAX_DELAY*time.Second)
+			continue
+		}
+		delay = time.Second
+
+		// จัดการการเชื่อมต่อกับ Bosch DCN server
+		handleConnection(ctx, conn, proxy)
+		if ctx.Err() != nil {
+			return
+		}
+
+		// ไม่รู้สถานะจริงระหว่างรอเชื่อมต่อใหม่ แจ้ง clients ว่าไม่มีไมค์เปิดอยู่
+		proxy.forgetSeatFrames()
+		proxy.BroadcastGenerated(allMicsOffFrame())
+		proxy.resetSeats(time.Now())
+		if !sleepContext(ctx, delay) {
+			return
+		}
+	}
+}
+
+// รอตามเวลาที่กำหนด คืนค่า false ถ้า ctx ถูกยกเลิกก่อน
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,325 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/ampol-me/phi-DCN/internal/journal"
+	"github.com/ampol-me/phi-DCN/internal/pcap"
+)
+
+// การตั้งค่าของ proxy
+type Config struct {
+	UpstreamAddr string           // ที่อยู่ของ Bosch DCN server
+	ListenAddr   string           // ที่อยู่ที่รอ clients เชื่อมต่อ
+	HTTPAddr     string           // ที่อยู่ของ HTTP endpoint (/metrics, /healthz, /readyz, /capture, /seats, /ws/events, /events, /director) ว่าง = ไม่เปิด
+	WSAddr       string           // ที่อยู่ HTTP ที่ส่ง frame ผ่าน WebSocket ที่ /ws/frames (ว่าง = ไม่เปิด)
+	Health       HealthThresholds // เกณฑ์ของ /readyz
+
+	Journal        journal.Options // Journal.Dir ว่าง = ไม่บันทึก journal
+	Capture        CaptureOptions  // ไดเรกทอรีและขนาดของไฟล์ capture
+	CaptureEnabled bool            // เริ่มบันทึก capture ทันที (เปิด/ปิดภายหลังได้ผ่าน /capture)
+	PcapngPath     string          // ไฟล์ pcapng สำหรับบันทึก traffic (ว่าง = ไม่บันทึก)
+
+	MQTT     MQTTOptions     // MQTT.Addr ว่าง = ไม่ publish ไปยัง MQTT broker
+	Webhooks WebhookOptions  // Webhooks.Hooks ว่าง = ไม่ส่ง webhook
+	Director *DirectorConfig // nil = ไม่เปิด camera director
+	Tally    *TallyConfig    // nil = ไม่ส่ง TSL UMD
+	OSC      *OSCConfig      // nil = ไม่ส่ง OSC
+	Lighting *LightingConfig // nil = ไม่ส่ง Art-Net/sACN
+}
+
+// เริ่ม proxy และทำงานจนกว่า ctx จะถูกยกเลิก
+// คืนค่า error เมื่อเริ่มทำงานไม่สำเร็จ (เปิด journal, สร้างไฟล์ pcapng หรือ listen ไม่ได้)
+func Run(ctx context.Context, cfg Config) error {
+	// เปิด journal ถ้ากำหนดไดเรกทอรีไว้
+	var j *journal.Journal
+	if cfg.Journal.Dir != "" {
+		var err error
+		j, err = journal.Open(cfg.Journal)
+		if err != nil {
+			logJournal.Error("journal.open_failed", "dir", cfg.Journal.Dir, "error", err)
+			return err
+		}
+		defer j.Close()
+	}
+
+	// เปิดไฟล์ pcapng ถ้ากำหนดไว้
+	var pcapng *pcap.Writer
+	if cfg.PcapngPath != "" {
+		var err error
+		pcapng, err = pcap.Create(cfg.PcapngPath)
+		if err != nil {
+			logCapture.Error("pcapng.open_failed", "file", cfg.PcapngPath, "error", err)
+			return err
+		}
+		logCapture.Info("pcapng.opened", "file", cfg.PcapngPath)
+		defer func() {
+			if err := pcapng.Close(); err != nil {
+				logCapture.Warn("pcapng.close_failed", "file", cfg.PcapngPath, "error", err)
+			}
+		}()
+	}
+
+	// สร้าง proxy server
+	proxy := NewProxyServer(cfg.ListenAddr, cfg.Health)
+	proxy.journal = j
+	proxy.pcapng = pcapng
+	proxy.captures = NewCapture(cfg.Capture, cfg.CaptureEnabled)
+	defer proxy.captures.Close()
+
+	// publish สถานะที่นั่งไปยัง MQTT broker ถ้ากำหนดไว้
+	// ใช้ context แยกเพื่อให้ส่งสถานะสุดท้ายตอนปิด proxy ได้ก่อนหยุด
+	mqttCtx, stopMQTT := context.WithCancel(context.Background())
+	defer stopMQTT()
+	mqttDone := make(chan struct{})
+	if cfg.MQTT.Addr != "" {
+		proxy.mqtt = newMQTTPublisher(cfg.MQTT, proxy.seats)
+		proxy.OnSeatEvent(proxy.mqtt.handleEvent)
+		go func() {
+			defer close(mqttDone)
+			proxy.mqtt.run(mqttCtx)
+		}()
+	} else {
+		close(mqttDone)
+	}
+
+	// ส่งเหตุการณ์ไปยัง webhook ถ้ากำหนดไว้ (ใช้ context แยกเช่นเดียวกับ MQTT)
+	webhookCtx, stopWebhooks := context.WithCancel(context.Background())
+	defer stopWebhooks()
+	webhooksDone := make(chan struct{})
+	if len(cfg.Webhooks.Hooks) > 0 {
+		dispatcher, err := newWebhookDispatcher(cfg.Webhooks)
+		if err != nil {
+			logWebhook.Error("webhook.open_failed", "dir", cfg.Webhooks.Dir, "error", err)
+			return err
+		}
+		proxy.webhooks = dispatcher
+		proxy.OnSeatEvent(dispatcher.handleEvent)
+		go func() {
+			defer close(webhooksDone)
+			dispatcher.run(webhookCtx)
+		}()
+	} else {
+		close(webhooksDone)
+	}
+
+	// เลือกตำแหน่งกล้องของผู้พูดถ้ากำหนดไว้
+	camerasDone := make(chan struct{})
+	if cfg.Director != nil {
+		proxy.director = newDirector(*cfg.Director, time.Now())
+		if len(cfg.Director.Cameras) > 0 {
+			cameras, err := newCameraSwitcher(cfg.Director.Cameras)
+			if err != nil {
+				logCamera.Error("camera.open_failed", "error", err)
+				return err
+			}
+			proxy.director.cameras = cameras
+			proxy.director.OnShot(cameras.handleShot)
+			go func() {
+				defer close(camerasDone)
+				cameras.run(ctx)
+			}()
+		} else {
+			close(camerasDone)
+		}
+		proxy.OnSeatEvent(proxy.director.handleEvent)
+	} else {
+		close(camerasDone)
+	}
+
+	// ส่งป้ายชื่อและ tally ด้วย TSL UMD ถ้ากำหนดไว้
+	tallyDone := make(chan struct{})
+	if cfg.Tally != nil {
+		tally := newTallyPublisher(*cfg.Tally, proxy.seats)
+		proxy.OnSeatEvent(tally.handleEvent)
+		if proxy.director != nil {
+			proxy.director.OnShot(tally.handleShot)
+		}
+		go func() {
+			defer close(tallyDone)
+			tally.run(ctx)
+		}()
+	} else {
+		close(tallyDone)
+	}
+
+	// เปิดและปิดช่องของ mixing console ด้วย OSC ถ้ากำหนดไว้
+	// ใช้ context แยกเพื่อให้ปิดช่องของไมค์ที่ยังเปิดอยู่ตอนปิด proxy ได้ก่อนหยุด
+	oscCtx, stopOSC := context.WithCancel(context.Background())
+	defer stopOSC()
+	oscDone := make(chan struct{})
+	if cfg.OSC != nil {
+		publisher, err := newOSCPublisher(*cfg.OSC, proxy.seats)
+		if err != nil {
+			logOSC.Error("osc.open_failed", "addr", cfg.OSC.Addr, "error", err)
+			return err
+		}
+		proxy.OnSeatEvent(publisher.handleEvent)
+		go func() {
+			defer close(oscDone)
+			publisher.run(oscCtx)
+		}()
+	} else {
+		close(oscDone)
+	}
+
+	// ส่องไฟผู้พูดด้วย Art-Net หรือ sACN ถ้ากำหนดไว้
+	// ใช้ context แยกเพื่อให้ส่งค่าของ house ตอนปิด proxy ได้ก่อนหยุด
+	lightingCtx, stopLighting := context.WithCancel(context.Background())
+	defer stopLighting()
+	lightingDone := make(chan struct{})
+	if cfg.Lighting != nil {
+		lighting, err := newLightingController(*cfg.Lighting, proxy.seats)
+		if err != nil {
+			logDMX.Error("dmx.open_failed", "addr", cfg.Lighting.Addr, "error", err)
+			return err
+		}
+		proxy.OnSeatEvent(lighting.handleEvent)
+		go func() {
+			defer close(lightingDone)
+			lighting.run(lightingCtx)
+		}()
+	} else {
+		close(lightingDone)
+	}
+
+	// เริ่ม director หลังลงทะเบียนผู้รับภาพครบแล้ว
+	directorDone := make(chan struct{})
+	if proxy.director != nil {
+		go func() {
+			defer close(directorDone)
+			proxy.director.run(ctx)
+		}()
+		logDirector.Info("director.started", "seats", len(cfg.Director.Seats), "priority", cfg.Director.Priority)
+	} else {
+		close(directorDone)
+	}
+
+	// เริ่ม proxy server
+	proxyListener, err := net.Listen("tcp", cfg.ListenAddr)
+	if err != nil {
+		logMain.Error("proxy.start_failed", "error", err)
+		return err
+	}
+	defer proxyListener.Close()
+	proxy.health.listening.Store(true)
+
+	logMain.Info("proxy.listening", "addr", proxyListener.Addr().String())
+
+	// เริ่ม HTTP endpoint สำหรับ metrics และ health check
+	var httpServer *http.Server
+	if cfg.HTTPAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("GET /metrics", proxy.newMetricsRegistry())
+		mux.HandleFunc("GET /healthz", proxy.healthHandler(true))
+		mux.HandleFunc("GET /readyz", proxy.healthHandler(false))
+		proxy.captures.register(mux)
+		proxy.registerSeats(mux)
+		mux.HandleFunc("GET /ws/events", proxy.wsEventsHandler)
+		mux.HandleFunc("GET /events", proxy.sseHandler)
+		if proxy.director != nil {
+			proxy.director.register(mux)
+		}
+		httpServer = &http.Server{Addr: cfg.HTTPAddr, Handler: mux}
+		go func() {
+			logHTTP.Info("http.listening", "addr", cfg.HTTPAddr)
+			if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logHTTP.Error("http.start_failed", "error", err)
+			}
+		}()
+	}
+
+	// เริ่ม WebSocket listener ที่ใช้ client registry เดียวกับ TCP listener
+	var wsServer *http.Server
+	if cfg.WSAddr != "" {
+		mux := http.NewServeMux()
+		mux.HandleFunc("GET /ws/frames", proxy.wsFramesHandler)
+		wsServer = &http.Server{Addr: cfg.WSAddr, Handler: mux}
+		go func() {
+			logClients.Info("ws.listening", "addr", cfg.WSAddr, "path", "/ws/frames")
+			if err := wsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logClients.Error("ws.start_failed", "error", err)
+			}
+		}()
+	}
+
+	// รับการเชื่อมต่อจาก clients ในพื้นหลัง
+	go func() {
+		for {
+			clientConn, err := proxyListener.Accept()
+			if err != nil {
+				if errors.Is(err, net.ErrClosed) {
+					proxy.health.listening.Store(false)
+					return
+				}
+				logMain.Warn("proxy.accept_failed", "error", err)
+				continue
+			}
+			go handleClientConnection(proxy, clientConn)
+		}
+	}()
+
+	// เชื่อมต่อไปยัง Bosch DCN server และเชื่อมต่อใหม่เมื่อการเชื่อมต่อหลุด จนกว่าจะได้รับสัญญาณหยุด
+	connectUpstream(ctx, cfg.UpstreamAddr, proxy)
+
+	// ปิด listener และแจ้ง clients ว่าไม่มีไมค์เปิดอยู่
+	proxyListener.Close()
+	if wsServer != nil {
+		// การเชื่อมต่อ WebSocket ถูก hijack ไปแล้วและปิดโดย Shutdown ด้านล่าง
+		wsServer.Close()
+	}
+	logMain.Info("proxy.final_state")
+	proxy.BroadcastGenerated(allMicsOffFrame())
+	proxy.resetSeats(time.Now())
+	proxy.events.close()
+	stopMQTT()
+	stopWebhooks()
+	stopOSC()
+	stopLighting()
+	// ทุกขั้นตอนตั้งแต่ส่งคิวของ clients จนปิด HTTP server ใช้ deadline เดียวกัน
+	// (channel ของ context ปิดแล้วคงปิด ต่างจาก time.After ที่ส่งค่าครั้งเดียว)
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), DRAIN_TIMEOUT*time.Second)
+	defer cancelDrain()
+	proxy.Shutdown(drainCtx)
+	select {
+	case <-mqttDone:
+	case <-drainCtx.Done():
+		logMQTT.Warn("mqtt.drain_timeout")
+	}
+	select {
+	case <-webhooksDone:
+	case <-drainCtx.Done():
+		logWebhook.Warn("webhook.drain_timeout")
+	}
+	select {
+	case <-directorDone:
+	case <-drainCtx.Done():
+	}
+	select {
+	case <-camerasDone:
+	case <-drainCtx.Done():
+	}
+	select {
+	case <-tallyDone:
+	case <-drainCtx.Done():
+	}
+	select {
+	case <-oscDone:
+	case <-drainCtx.Done():
+	}
+	select {
+	case <-lightingDone:
+	case <-drainCtx.Done():
+	}
+
+	if httpServer != nil {
+		httpServer.Shutdown(drainCtx)
+	}
+
+	logMain.Info("proxy.stopped")
+	return nil
+}
@@ -0,0 +1,93 @@
+package proxy
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/ampol-me/phi-DCN/dcn"
+)
+
+// สถานะที่นั่งปัจจุบันของ proxy สำหรับ feature อื่นที่ต้องอ่านสถานะ
+func (p *ProxyServer) SeatState() *dcn.State {
+	return p.seats
+}
+
+// ลงทะเบียนฟังก์ชันที่รับเหตุการณ์ทุกครั้งที่ seat state เปลี่ยน
+//
+// ฟังก์ชันถูกเรียกจาก goroutine ที่อ่านข้อมูล upstream ตามลำดับเหตุการณ์ จึงต้องทำงานเสร็จเร็ว
+func (p *ProxyServer) OnSeatEvent(fn func(dcn.Event)) {
+	p.listenerLock.Lock()
+	defer p.listenerLock.Unlock()
+	p.listeners = append(p.listeners, fn)
+}
+
+// อัปเดต seat state จาก XML ของ frame ที่ได้รับจาก upstream
+func (p *ProxyServer) applySeatFrame(topic uint32, xmlStr string, at time.Time) error {
+	events, err := p.seats.Apply(topic, xmlStr, at)
+	if err != nil {
+		return err
+	}
+	for _, ev := range events {
+		p.seatEvent(ev)
+	}
+	return nil
+}
+
+// ถือว่าไมค์ทุกตัวปิดเมื่อไม่รู้สถานะจริง เช่นระหว่างรอเชื่อมต่อ upstream ใหม่
+func (p *ProxyServer) resetSeats(at time.Time) {
+	for _, ev := range p.seats.Reset(at) {
+		p.seatEvent(ev)
+	}
+}
+
+// เขียน log อัปเดต metrics และส่งเหตุการณ์ต่อให้ feature อื่น
+func (p *ProxyServer) seatEvent(ev dcn.Event) {
+	switch ev.Type {
+	case dcn.EVENT_MIC_ON, dcn.EVENT_MIC_OFF:
+		p.mics.Record(ev.Seat.ID, ev.Seat.Name, ev.Seat.MicOn, ev.Time)
+		logSeats.Info("seat.mic_changed", "seat_id", ev.Seat.ID, "seat", ev.Seat.Name, "mic_on", ev.Seat.MicOn)
+	case dcn.EVENT_ACTIVE_LIST_CHANGED:
+		names := make([]string, 0, len(ev.Active))
+		for _, seat := range ev.Active {
+			names = append(names, seat.Name)
+		}
+		logSeats.Info("discussion.active_list", "active", names)
+	case dcn.EVENT_SEAT_UPDATED:
+		if logSeats.Enabled(slog.LevelDebug) {
+			logSeats.Debug("seat.updated", "seat_id", ev.Seat.ID, "seat", ev.Seat.Name, "mic_on", ev.Seat.MicOn)
+		}
+	case dcn.EVENT_INCONSISTENT:
+		logSeats.Warn("seat.inconsistent", "seat_id", ev.Seat.ID, "seat", ev.Seat.Name, "reason", ev.Detail)
+		inconsistencies.Inc(ev.Detail)
+	}
+
+	p.listenerLock.Lock()
+	listeners := p.listeners
+	p.listenerLock.Unlock()
+	for _, fn := range listeners {
+		fn(ev)
+	}
+}
+
+// เพิ่ม endpoint สำหรับอ่าน seat state เป็น JSON
+func (p *ProxyServer) registerSeats(mux *http.ServeMux) {
+	mux.HandleFunc("GET /seats", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, p.seats.Seats())
+	})
+	mux.HandleFunc("GET /seats/{id}", func(w http.ResponseWriter, r *http.Request) {
+		seat, ok := p.seats.Seat(r.PathValue("id"))
+		if !ok {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("ไม่พบที่นั่ง %s", r.PathValue("id")))
+			return
+		}
+		writeJSON(w, http.StatusOK, seat)
+	})
+	mux.HandleFunc("GET /active", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, p.seats.Active())
+	})
+	mux.HandleFunc("GET /discussion", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, p.seats.Discussion())
+	})
+}
@@ -0,0 +1,85 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	SSE_KEEPALIVE_INTERVAL = 15   // ระยะห่างการส่ง comment เพื่อไม่ให้ proxy/browser ตัดการเชื่อมต่อ (วินาที)
+	SSE_RETRY              = 3000 // เวลาที่ให้ EventSource รอก่อนเชื่อมต่อใหม่ (มิลลิวินาที)
+)
+
+// GET /events: ส่งเหตุการณ์ของ seat state แบบ Server-Sent Events
+//
+// แต่ละเหตุการณ์มี id, event (mic_on, mic_off, seat_updated, active_list_changed, inconsistent หรือ snapshot)
+// และ data เป็น JSON รูปแบบเดียวกับ /ws/events ใช้ filter ?seats=&topics= ได้เหมือนกัน
+// client ที่เชื่อมต่อใหม่พร้อม Last-Event-ID (หรือ ?lastEventId=) จะได้เหตุการณ์ที่ตกหล่นต่อจากเดิม
+// ถ้ายังอยู่ใน ring buffer ไม่เช่นนั้นจะได้ snapshot ใหม่
+func (p *ProxyServer) sseHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter, err := newEventFilter(splitList(query.Get("seats")), splitList(query.Get("topics")))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var resume *uint64
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = query.Get("lastEventId")
+	}
+	if id, err := strconv.ParseUint(lastEventID, 10, 64); err == nil {
+		resume = &id
+	}
+
+	sub := p.events.subscribe("sse", r.RemoteAddr, filter, p.snapshotJSON, resume)
+	if sub == nil {
+		writeError(w, http.StatusServiceUnavailable, "proxy กำลังปิด")
+		return
+	}
+	logStream.Info("stream.connected", "transport", sub.transport, "addr", sub.addr, "resume", lastEventID)
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // ปิด buffering ของ nginx
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", SSE_RETRY)
+	rc.Flush()
+
+	ticker := time.NewTicker(SSE_KEEPALIVE_INTERVAL * time.Second)
+	defer ticker.Stop()
+
+	for {
+		// ตั้ง deadline เฉพาะตอนเขียนเพราะการรอ keepalive นานกว่า WS_WRITE_TIMEOUT
+		var err error
+		select {
+		case ev, ok := <-sub.send:
+			if !ok {
+				logStream.Info("stream.disconnected", "transport", sub.transport, "addr", sub.addr, "reason", sub.reason)
+				return
+			}
+			rc.SetWriteDeadline(time.Now().Add(WS_WRITE_TIMEOUT * time.Second))
+			_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, ev.Data)
+		case <-ticker.C:
+			rc.SetWriteDeadline(time.Now().Add(WS_WRITE_TIMEOUT * time.Second))
+			_, err = fmt.Fprint(w, ": keepalive\n\n")
+		case <-r.Context().Done():
+			p.events.remove(sub, "closed")
+			logStream.Info("stream.disconnected", "transport", sub.transport, "addr", sub.addr, "reason", sub.reason)
+			return
+		}
+		if err == nil {
+			err = rc.Flush()
+		}
+		if err != nil {
+			p.events.remove(sub, "write_error")
+			streamEvictions.Inc(sub.transport, "write_error")
+			logStream.Info("stream.disconnected", "transport", sub.transport, "addr", sub.addr, "reason", sub.reason)
+			return
+		}
+	}
+}
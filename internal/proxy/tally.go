@@ -0,0 +1,319 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ampol-me/phi-DCN/dcn"
+	"github.com/ampol-me/phi-DCN/internal/tsl"
+)
+
+// ไฟล์ตั้งค่าของ TSL UMD เป็น JSON:
+//
+//	{
+//	    "outputs": [
+//	        {"version": "v5", "transport": "udp", "addr": "192.168.1.60:8900", "screen": 0},
+//	        {"version": "v3.1", "transport": "tcp", "addr": "192.168.1.61:5727"}
+//	    ],
+//	    "text": "participant",
+//	    "brightness": 3,
+//	    "refresh": "2s",
+//	    "seats": {"7": 1, "12": 2},
+//	    "cameras": {"cam1": 10, "cam2": 11}
+//	}
+//
+// display ของที่นั่ง (seats: Seat Id -> index) แสดงชื่อผู้พูดและ tally สีแดงเมื่อเปิดไมค์
+// display ของกล้อง (cameras: ชื่อกล้องใน -director -> index) มี tally สีแดงเมื่อ director ใช้ภาพจากกล้องนั้น
+// และแสดงชื่อผู้พูดที่กล้องจับอยู่ (ชื่อกล้องเมื่อเป็นภาพกว้างหรือไม่ได้ใช้ภาพ)
+//
+// text เลือกที่มาของข้อความ: participant = คำนำหน้าและชื่อผู้เข้าร่วมประชุมจาก roster ของ DCN
+// (ใช้ชื่อที่นั่งถ้าไม่มี) หรือ seat = SeatData Name
+// ทุก display ถูกส่งซ้ำทุก refresh เพื่อให้อุปกรณ์ที่เพิ่งเปิดได้รับสถานะ
+const (
+	TALLY_TEXT_PARTICIPANT = "participant"
+	TALLY_TEXT_SEAT        = "seat"
+
+	TALLY_REFRESH = 2 * time.Second
+)
+
+// ปลายทางของ TSL UMD หนึ่งแห่ง
+type TallyOutputConfig struct {
+	Version   string `json:"version"`   // v3.1 หรือ v5
+	Transport string `json:"transport"` // udp หรือ tcp (ค่าเริ่มต้น udp)
+	Addr      string `json:"addr"`
+	Screen    int    `json:"screen"` // v5: screen index
+}
+
+// การตั้งค่าของ TSL UMD
+type TallyConfig struct {
+	Outputs    []TallyOutputConfig `json:"outputs"`
+	Text       string              `json:"text"`
+	Brightness *int                `json:"brightness"` // 0-3 (ค่าเริ่มต้น 3)
+	Refresh    configDuration      `json:"refresh"`
+	Seats      map[string]int      `json:"seats"`
+	Cameras    map[string]int      `json:"cameras"`
+}
+
+// อ่านและตรวจสอบไฟล์ตั้งค่าของ TSL UMD
+func LoadTally(path string) (TallyConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return TallyConfig{}, err
+	}
+	var cfg TallyConfig
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&cfg); err != nil {
+		return TallyConfig{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if len(cfg.Outputs) == 0 {
+		return TallyConfig{}, fmt.Errorf("%s: ต้องกำหนด outputs อย่างน้อยหนึ่งแห่ง", path)
+	}
+	maxIndex := tsl.V50_MAX_INDEX
+	for i := range cfg.Outputs {
+		output := &cfg.Outputs[i]
+		if output.Transport == "" {
+			output.Transport = tsl.TRANSPORT_UDP
+		}
+		opts := output.options()
+		if err := opts.Validate(); err != nil {
+			return TallyConfig{}, fmt.Errorf("%s: outputs[%d]: %w", path, i, err)
+		}
+		maxIndex = min(maxIndex, opts.MaxIndex())
+	}
+
+	switch cfg.Text {
+	case "":
+		cfg.Text = TALLY_TEXT_PARTICIPANT
+	case TALLY_TEXT_PARTICIPANT, TALLY_TEXT_SEAT:
+	default:
+		return TallyConfig{}, fmt.Errorf("%s: ไม่รู้จัก text %q (ใช้ participant หรือ seat)", path, cfg.Text)
+	}
+	if cfg.Brightness == nil {
+		brightness := 3
+		cfg.Brightness = &brightness
+	} else if *cfg.Brightness < 0 || *cfg.Brightness > 3 {
+		return TallyConfig{}, fmt.Errorf("%s: brightness ต้องอยู่ระหว่าง 0-3", path)
+	}
+	if cfg.Refresh == 0 {
+		cfg.Refresh = configDuration(TALLY_REFRESH)
+	}
+
+	if len(cfg.Seats) == 0 && len(cfg.Cameras) == 0 {
+		return TallyConfig{}, fmt.Errorf("%s: ต้องกำหนด seats หรือ cameras อย่างน้อยหนึ่งอย่าง", path)
+	}
+	used := make(map[int]string)
+	checkIndex := func(owner string, index int) error {
+		if index < 0 || index > maxIndex {
+			return fmt.Errorf("%s: %s: index %d อยู่นอกช่วง 0-%d", path, owner, index, maxIndex)
+		}
+		if other, ok := used[index]; ok {
+			return fmt.Errorf("%s: %s ใช้ index %d ซ้ำกับ%s", path, owner, index, other)
+		}
+		used[index] = owner
+		return nil
+	}
+	for id, index := range cfg.Seats {
+		if err := checkIndex("ที่นั่ง "+id, index); err != nil {
+			return TallyConfig{}, err
+		}
+	}
+	for name, index := range cfg.Cameras {
+		if err := checkIndex("กล้อง "+name, index); err != nil {
+			return TallyConfig{}, err
+		}
+	}
+	return cfg, nil
+}
+
+func (o TallyOutputConfig) options() tsl.Options {
+	return tsl.Options{Version: o.Version, Transport: o.Transport, Addr: o.Addr, Screen: o.Screen}
+}
+
+// ส่งป้ายชื่อและ tally ไปยัง multiviewer และ switcher ด้วย TSL UMD
+type tallyPublisher struct {
+	cfg     TallyConfig
+	state   *dcn.State
+	senders []*tsl.Sender
+
+	mu       sync.Mutex
+	displays map[int]tsl.Display // สถานะล่าสุดของแต่ละ index
+	changed  map[int]bool        // index ที่ยังไม่ได้ส่งหลังเปลี่ยน
+	shot     *Shot               // ภาพล่าสุดของ director
+	wake     chan struct{}
+	failed   []bool // ปลายทางที่ส่งไม่สำเร็จครั้งล่าสุด (log เฉพาะเมื่อสถานะเปลี่ยน)
+}
+
+func newTallyPublisher(cfg TallyConfig, state *dcn.State) *tallyPublisher {
+	t := &tallyPublisher{
+		cfg:      cfg,
+		state:    state,
+		displays: make(map[int]tsl.Display),
+		changed:  make(map[int]bool),
+		wake:     make(chan struct{}, 1),
+		failed:   make([]bool, len(cfg.Outputs)),
+	}
+	for _, output := range cfg.Outputs {
+		t.senders = append(t.senders, tsl.NewSender(output.options()))
+	}
+	return t
+}
+
+// ข้อความของที่นั่งตาม text
+func (t *tallyPublisher) seatText(seat dcn.Seat) string {
+	if t.cfg.Text == TALLY_TEXT_PARTICIPANT && seat.Participant != nil {
+		p := seat.Participant
+		if name := strings.Join(strings.Fields(strings.Join([]string{p.Title, p.FirstName, p.MiddleName, p.LastName}, " ")), " "); name != "" {
+			return name
+		}
+	}
+	if seat.Name != "" {
+		return seat.Name
+	}
+	return "Seat " + seat.ID
+}
+
+// อัปเดต display และตั้งให้ส่งถ้าสถานะเปลี่ยน (ต้องถือ mu อยู่)
+func (t *tallyPublisher) set(index int, text string, on bool) {
+	d := tsl.Display{Index: index, Text: text, Brightness: *t.cfg.Brightness}
+	if on {
+		d.Tally = tsl.TALLY_RED
+	}
+	if old, ok := t.displays[index]; ok && old == d {
+		return
+	}
+	t.displays[index] = d
+	t.changed[index] = true
+}
+
+// คำนวณ display ของกล้องทั้งหมดจากภาพล่าสุดของ director (ต้องถือ mu อยู่)
+func (t *tallyPublisher) updateCameras() {
+	for name, index := range t.cfg.Cameras {
+		live := t.shot != nil && t.shot.Camera == name
+		text := name
+		if live && t.shot.SeatID != "" {
+			text = t.shot.Seat
+			if seat, ok := t.state.Seat(t.shot.SeatID); ok {
+				text = t.seatText(seat)
+			}
+		}
+		t.set(index, text, live)
+	}
+}
+
+// ปลุก goroutine ที่ส่ง packet
+func (t *tallyPublisher) notify() {
+	select {
+	case t.wake <- struct{}{}:
+	default:
+	}
+}
+
+// รับเหตุการณ์จาก seat state
+func (t *tallyPublisher) handleEvent(ev dcn.Event) {
+	switch ev.Type {
+	case dcn.EVENT_SEAT_UPDATED, dcn.EVENT_MIC_ON, dcn.EVENT_MIC_OFF:
+	default:
+		return
+	}
+
+	t.mu.Lock()
+	if index, ok := t.cfg.Seats[ev.Seat.ID]; ok {
+		t.set(index, t.seatText(ev.Seat), ev.Seat.MicOn)
+	}
+	if t.shot != nil && t.shot.SeatID == ev.Seat.ID {
+		t.updateCameras()
+	}
+	t.mu.Unlock()
+	t.notify()
+}
+
+// รับภาพที่ director เลือก
+func (t *tallyPublisher) handleShot(shot Shot) {
+	t.mu.Lock()
+	t.shot = &shot
+	t.updateCameras()
+	t.mu.Unlock()
+	t.notify()
+}
+
+// ส่ง display ที่เปลี่ยนทันทีและส่งทั้งหมดทุก refresh จนกว่า ctx จะถูกยกเลิก
+// แล้วส่ง display ทั้งหมดโดยปิด tally ก่อนหยุด
+func (t *tallyPublisher) run(ctx context.Context) {
+	t.mu.Lock()
+	for id, index := range t.cfg.Seats {
+		seat, ok := t.state.Seat(id)
+		if !ok {
+			seat = dcn.Seat{ID: id}
+		}
+		t.set(index, t.seatText(seat), seat.MicOn)
+	}
+	t.updateCameras()
+	t.mu.Unlock()
+	logTally.Info("tally.started", "outputs", len(t.senders), "displays", len(t.cfg.Seats)+len(t.cfg.Cameras))
+
+	ticker := time.NewTicker(time.Duration(t.cfg.Refresh))
+	defer ticker.Stop()
+	t.flush(true)
+	for {
+		select {
+		case <-t.wake:
+			t.flush(false)
+		case <-ticker.C:
+			t.flush(true)
+		case <-ctx.Done():
+			t.mu.Lock()
+			for index, d := range t.displays {
+				d.Tally = tsl.TALLY_OFF
+				t.displays[index] = d
+			}
+			t.mu.Unlock()
+			t.flush(true)
+			for _, sender := range t.senders {
+				sender.Close()
+			}
+			return
+		}
+	}
+}
+
+// ส่ง display ที่เปลี่ยน (หรือทั้งหมดถ้า all) ไปยังทุกปลายทาง เรียงตาม index
+func (t *tallyPublisher) flush(all bool) {
+	t.mu.Lock()
+	var displays []tsl.Display
+	for index, d := range t.displays {
+		if all || t.changed[index] {
+			displays = append(displays, d)
+		}
+	}
+	clear(t.changed)
+	t.mu.Unlock()
+	if len(displays) == 0 {
+		return
+	}
+	slices.SortFunc(displays, func(a, b tsl.Display) int { return a.Index - b.Index })
+
+	for i, sender := range t.senders {
+		err := sender.Send(displays)
+		switch {
+		case err != nil && !t.failed[i]:
+			logTally.Warn("tally.send_failed", "addr", sender.Addr(), "error", err)
+		case err == nil && t.failed[i]:
+			logTally.Info("tally.recovered", "addr", sender.Addr())
+		}
+		t.failed[i] = err != nil
+		if err != nil {
+			tallyPackets.Inc("error")
+		} else {
+			tallyPackets.Add(float64(len(displays)), "sent")
+		}
+	}
+}
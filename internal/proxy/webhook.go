@@ -0,0 +1,554 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/template"
+	"time"
+
+	"github.com/ampol-me/phi-DCN/dcn"
+	"github.com/ampol-me/phi-DCN/internal/metrics"
+)
+
+// ไฟล์ตั้งค่า webhook เป็น JSON:
+//
+//	{"webhooks": [{
+//	    "name": "recorder",
+//	    "url": "https://example.com/hooks/dcn",
+//	    "events": ["mic_on", "mic_off"],
+//	    "seats": ["7", "12"],
+//	    "secret": "shared-secret",
+//	    "headers": {"Authorization": "Bearer token"},
+//	    "template": "{\"seat\": {{json .Seat.Name}}, \"on\": {{json .Seat.MicOn}}, \"at\": {{json .Timestamp}}}"
+//	}]}
+//
+// events ว่าง = mic_on และ mic_off, seats ว่าง = ทุกที่นั่ง, template ว่าง = JSON เดียวกับ /ws/events
+// seats ไม่กรอง active_list_changed เพราะ ActiveList เป็นของทั้งห้อง ไม่ใช่ของที่นั่งใดที่นั่งหนึ่ง
+// template เป็น text/template ที่ต้องได้ผลลัพธ์เป็น JSON ข้อมูลที่ใช้ได้ดู webhookData และมีฟังก์ชัน json
+//
+// ทุก request มี header X-DCN-Event, X-DCN-Delivery และ X-DCN-Timestamp (unix วินาที)
+// ถ้ากำหนด secret จะมี X-DCN-Signature: sha256=<hex ของ HMAC-SHA256(secret, timestamp + "." + body)>
+// headers จึงกำหนด Content-Type และ header ที่ขึ้นต้นด้วย X-DCN- ไม่ได้
+const (
+	WEBHOOK_DIR             = "webhooks" // ไดเรกทอรีของคิวที่รอส่งซ้ำและ dead-letter log
+	WEBHOOK_QUEUE_DIR       = "queue"
+	WEBHOOK_DEAD_LETTER     = "dead-letter.jsonl"
+	WEBHOOK_QUEUE_SIZE      = 1000 // จำนวน delivery สูงสุดที่รอส่งของแต่ละ webhook
+	WEBHOOK_MAX_ATTEMPTS    = 10
+	WEBHOOK_RETRY_DELAY     = 2   // ระยะรอก่อนส่งซ้ำครั้งแรก เพิ่มเป็นสองเท่าทุกครั้ง (วินาที)
+	WEBHOOK_RETRY_MAX_DELAY = 300 // ระยะรอสูงสุดก่อนส่งซ้ำ (วินาที)
+	WEBHOOK_TIMEOUT         = 10  // timeout ของ request หนึ่งครั้ง (วินาที)
+)
+
+// การตั้งค่า webhook หนึ่งรายการในไฟล์ตั้งค่า
+type WebhookConfig struct {
+	Name     string            `json:"name"`
+	URL      string            `json:"url"`
+	Events   []string          `json:"events"`
+	Seats    []string          `json:"seats"`
+	Secret   string            `json:"secret"`
+	Headers  map[string]string `json:"headers"`
+	Template string            `json:"template"`
+}
+
+// การตั้งค่าการส่ง webhook
+type WebhookOptions struct {
+	Hooks         []WebhookConfig
+	Dir           string // ไดเรกทอรีของคิวและ dead-letter log
+	MaxAttempts   int
+	RetryDelay    time.Duration
+	RetryMaxDelay time.Duration
+	Timeout       time.Duration
+}
+
+// อ่านและตรวจสอบไฟล์ตั้งค่า webhook
+func LoadWebhooks(path string) ([]WebhookConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Webhooks []WebhookConfig `json:"webhooks"`
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	names := make(map[string]bool)
+	for i := range file.Webhooks {
+		hook := &file.Webhooks[i]
+		if hook.Name == "" {
+			hook.Name = fmt.Sprintf("webhook-%d", i+1)
+		}
+		if names[hook.Name] {
+			return nil, fmt.Errorf("%s: ชื่อ webhook %q ซ้ำ", path, hook.Name)
+		}
+		names[hook.Name] = true
+		if _, err := compileWebhook(*hook); err != nil {
+			return nil, fmt.Errorf("%s: webhook %q: %w", path, hook.Name, err)
+		}
+	}
+	return file.Webhooks, nil
+}
+
+// ข้อมูลที่ใช้ใน template ของ payload
+type webhookData struct {
+	Event       string          // ชนิดเหตุการณ์ เช่น mic_on
+	Time        time.Time       // เวลาที่เกิดเหตุการณ์
+	Timestamp   string          // Time ในรูปแบบ RFC 3339
+	Seat        dcn.Seat        // ที่นั่งของเหตุการณ์ (ว่างสำหรับ active_list_changed)
+	Participant dcn.Participant // ผู้เข้าร่วมประชุมของที่นั่ง (ว่างถ้าไม่รู้)
+	Active      []dcn.Seat      // ActiveList สำหรับ active_list_changed
+	Names       []string        // ชื่อที่นั่งใน ActiveList
+	Reason      string          // สาเหตุของ inconsistent
+}
+
+// ฟังก์ชันที่ใช้ได้ใน template
+var webhookFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// webhook ที่ตรวจสอบและเตรียม template แล้ว
+type webhook struct {
+	WebhookConfig
+	events   map[string]bool
+	seats    map[string]bool // nil = ทุกที่นั่ง
+	template *template.Template
+
+	mu      sync.Mutex
+	queue   []*webhookDelivery // รอส่งตามลำดับ
+	wake    chan struct{}
+	dropped atomic.Bool // เขียน log คิวเต็มครั้งเดียวจนกว่าจะมีที่ว่าง
+}
+
+func compileWebhook(cfg WebhookConfig) (*webhook, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("url %q ต้องเป็น http หรือ https", cfg.URL)
+	}
+
+	hook := &webhook{WebhookConfig: cfg, events: make(map[string]bool), wake: make(chan struct{}, 1)}
+	if len(cfg.Events) == 0 {
+		cfg.Events = []string{dcn.EVENT_MIC_ON.String(), dcn.EVENT_MIC_OFF.String()}
+	}
+	for _, name := range cfg.Events {
+		switch name {
+		case dcn.EVENT_MIC_ON.String(), dcn.EVENT_MIC_OFF.String(), dcn.EVENT_SEAT_UPDATED.String(),
+			dcn.EVENT_ACTIVE_LIST_CHANGED.String(), dcn.EVENT_INCONSISTENT.String():
+			hook.events[name] = true
+		default:
+			return nil, fmt.Errorf("ไม่รู้จักเหตุการณ์ %q", name)
+		}
+	}
+	for name := range cfg.Headers {
+		canonical := http.CanonicalHeaderKey(name)
+		if canonical == "Content-Type" || strings.HasPrefix(canonical, "X-Dcn-") {
+			return nil, fmt.Errorf("header %q ถูกกำหนดโดย proxy แล้ว", name)
+		}
+	}
+	for _, id := range cfg.Seats {
+		if hook.seats == nil {
+			hook.seats = make(map[string]bool)
+		}
+		hook.seats[id] = true
+	}
+	if cfg.Template != "" {
+		hook.template, err = template.New(cfg.Name).Funcs(webhookFuncs).Option("missingkey=error").Parse(cfg.Template)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return hook, nil
+}
+
+// สร้าง payload ของเหตุการณ์ (คืนค่า false ถ้า webhook ไม่ได้เลือกเหตุการณ์นี้)
+func (h *webhook) payload(ev dcn.Event) ([]byte, bool, error) {
+	if !h.events[ev.Type.String()] {
+		return nil, false, nil
+	}
+	if h.seats != nil && ev.Type != dcn.EVENT_ACTIVE_LIST_CHANGED && !h.seats[ev.Seat.ID] {
+		return nil, false, nil
+	}
+
+	if h.template == nil {
+		se, ok := newStreamEvent(ev)
+		return se.Data, ok, nil
+	}
+
+	data := webhookData{
+		Event:     ev.Type.String(),
+		Time:      ev.Time,
+		Timestamp: ev.Time.Format(time.RFC3339Nano),
+		Seat:      ev.Seat,
+		Active:    ev.Active,
+		Names:     []string{},
+		Reason:    ev.Detail,
+	}
+	if ev.Seat.Participant != nil {
+		data.Participant = *ev.Seat.Participant
+	}
+	for _, seat := range ev.Active {
+		data.Names = append(data.Names, seat.Name)
+	}
+
+	var buf bytes.Buffer
+	if err := h.template.Execute(&buf, data); err != nil {
+		return nil, true, err
+	}
+	if !json.Valid(buf.Bytes()) {
+		return nil, true, errors.New("ผลลัพธ์ของ template ไม่ใช่ JSON")
+	}
+	return buf.Bytes(), true, nil
+}
+
+// การส่ง payload หนึ่งครั้งไปยัง webhook หนึ่งรายการ เก็บเป็นไฟล์ในคิวจนกว่าจะส่งสำเร็จหรือย้ายไป dead-letter
+type webhookDelivery struct {
+	ID          string          `json:"id"`
+	Webhook     string          `json:"webhook"`
+	URL         string          `json:"url"`
+	Event       string          `json:"event"`
+	Body        json.RawMessage `json:"body"`
+	Created     time.Time       `json:"created"`
+	Attempts    int             `json:"attempts"`
+	NextAttempt time.Time       `json:"nextAttempt"`
+	LastError   string          `json:"lastError,omitempty"`
+	FailedAt    *time.Time      `json:"failedAt,omitempty"` // เฉพาะใน dead-letter log
+}
+
+// webhookDispatcher ส่งเหตุการณ์ของ seat state ไปยัง webhook ทุกรายการ
+//
+// แต่ละ webhook ส่งตามลำดับเหตุการณ์ใน goroutine ของตัวเอง delivery ที่ส่งไม่สำเร็จจะรอส่งซ้ำแบบ backoff
+// โดยที่ webhook อื่นไม่ต้องรอ และ delivery ที่ยังไม่สำเร็จจะส่งต่อได้หลังเริ่ม proxy ใหม่
+type webhookDispatcher struct {
+	opts   WebhookOptions
+	hooks  []*webhook
+	client *http.Client
+	seq    atomic.Uint64
+
+	deadMu sync.Mutex
+}
+
+// สร้าง dispatcher และโหลด delivery ที่ค้างอยู่ในคิวจากครั้งก่อน
+func newWebhookDispatcher(opts WebhookOptions) (*webhookDispatcher, error) {
+	d := &webhookDispatcher{opts: opts, client: &http.Client{Timeout: opts.Timeout}}
+	byName := make(map[string]*webhook)
+	for _, cfg := range opts.Hooks {
+		hook, err := compileWebhook(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("webhook %q: %w", cfg.Name, err)
+		}
+		d.hooks = append(d.hooks, hook)
+		byName[hook.Name] = hook
+	}
+
+	if err := os.MkdirAll(filepath.Join(opts.Dir, WEBHOOK_QUEUE_DIR), 0o755); err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(filepath.Join(opts.Dir, WEBHOOK_QUEUE_DIR))
+	if err != nil {
+		return nil, err
+	}
+
+	var loaded []*webhookDelivery
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		path := filepath.Join(opts.Dir, WEBHOOK_QUEUE_DIR, entry.Name())
+		data, err := os.ReadFile(path)
+		var delivery webhookDelivery
+		if err == nil {
+			err = json.Unmarshal(data, &delivery)
+		}
+		if err != nil {
+			logWebhook.Warn("webhook.load_failed", "file", path, "error", err)
+			continue
+		}
+		loaded = append(loaded, &delivery)
+	}
+	slices.SortFunc(loaded, func(a, b *webhookDelivery) int { return a.Created.Compare(b.Created) })
+
+	for _, delivery := range loaded {
+		hook := byName[delivery.Webhook]
+		if hook == nil {
+			delivery.LastError = "webhook ไม่อยู่ในการตั้งค่าแล้ว"
+			d.deadLetter(delivery)
+			continue
+		}
+		hook.queue = append(hook.queue, delivery)
+	}
+	for _, hook := range d.hooks {
+		logWebhook.Info("webhook.loaded", "webhook", hook.Name, "url", hook.URL, "pending", len(hook.queue))
+	}
+	return d, nil
+}
+
+// รับเหตุการณ์จาก seat state (เรียกจาก goroutine ที่อ่าน upstream จึงแค่เขียนคิวบน disk และใส่คิว ไม่รอการส่ง)
+func (d *webhookDispatcher) handleEvent(ev dcn.Event) {
+	for _, hook := range d.hooks {
+		body, ok, err := hook.payload(ev)
+		if err != nil {
+			logWebhook.Warn("webhook.template_failed", "webhook", hook.Name, "event", ev.Type.String(), "error", err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		now := time.Now()
+		delivery := &webhookDelivery{
+			ID:          fmt.Sprintf("%d-%d", now.UnixNano(), d.seq.Add(1)),
+			Webhook:     hook.Name,
+			URL:         hook.URL,
+			Event:       ev.Type.String(),
+			Body:        body,
+			Created:     now,
+			NextAttempt: now,
+		}
+
+		hook.mu.Lock()
+		full := len(hook.queue) >= WEBHOOK_QUEUE_SIZE
+		if !full {
+			// เขียนไฟล์ก่อนใส่คิว runHook จึงเห็น delivery เมื่อไฟล์พร้อมแล้วเท่านั้น
+			// ไม่เช่นนั้นอาจส่งและลบไฟล์ก่อน แล้วไฟล์ที่เขียนทีหลังทำให้ส่งซ้ำหลังเริ่มใหม่
+			d.persist(delivery)
+			hook.queue = append(hook.queue, delivery)
+		}
+		hook.mu.Unlock()
+
+		if full {
+			delivery.LastError = "คิวเต็ม"
+			d.deadLetter(delivery)
+			webhookDeliveries.Inc(hook.Name, "dead_letter")
+			if !hook.dropped.Swap(true) {
+				logWebhook.Warn("webhook.queue_full", "webhook", hook.Name)
+			}
+			continue
+		}
+		select {
+		case hook.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// ส่ง delivery ของทุก webhook จนกว่า ctx จะถูกยกเลิก
+//
+// หลัง ctx ถูกยกเลิกจะพยายามส่ง delivery ที่ยังไม่เคยส่งอีกหนึ่งครั้ง (เช่นสถานะปิดไมค์ตอนปิด proxy)
+// ส่วนที่เหลือคงอยู่ในคิวบน disk
+func (d *webhookDispatcher) run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, hook := range d.hooks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.runHook(ctx, hook)
+		}()
+	}
+	wg.Wait()
+}
+
+func (d *webhookDispatcher) runHook(ctx context.Context, hook *webhook) {
+	for {
+		hook.mu.Lock()
+		var next *webhookDelivery
+		if len(hook.queue) > 0 {
+			next = hook.queue[0]
+		}
+		hook.mu.Unlock()
+
+		if ctx.Err() != nil {
+			if next == nil || next.Attempts > 0 || !d.attempt(context.Background(), hook, next) {
+				return
+			}
+			continue
+		}
+
+		if next == nil {
+			select {
+			case <-hook.wake:
+			case <-ctx.Done():
+			}
+			continue
+		}
+		if wait := time.Until(next.NextAttempt); wait > 0 {
+			if !sleepContext(ctx, wait) {
+				continue
+			}
+		}
+		d.attempt(ctx, hook, next)
+	}
+}
+
+// ส่ง delivery หนึ่งครั้งและจัดการผลลัพธ์ คืนค่า true ถ้าออกจากคิวแล้ว (สำเร็จหรือย้ายไป dead-letter)
+func (d *webhookDispatcher) attempt(ctx context.Context, hook *webhook, delivery *webhookDelivery) bool {
+	retryAfter, err := d.send(ctx, hook, delivery)
+	if err != nil && ctx.Err() != nil {
+		// ถูกยกเลิกระหว่างปิด proxy ไม่นับเป็นความพยายาม
+		return false
+	}
+
+	delivery.Attempts++
+	if err == nil {
+		logWebhook.Debug("webhook.delivered", "webhook", hook.Name, "event", delivery.Event, "id", delivery.ID, "attempts", delivery.Attempts)
+		webhookDeliveries.Inc(hook.Name, "success")
+		d.dequeue(hook, delivery)
+		return true
+	}
+
+	delivery.LastError = err.Error()
+	var permanent *webhookPermanentError
+	if errors.As(err, &permanent) || delivery.Attempts >= d.opts.MaxAttempts {
+		logWebhook.Error("webhook.dead_letter", "webhook", hook.Name, "event", delivery.Event, "id", delivery.ID, "attempts", delivery.Attempts, "error", err)
+		webhookDeliveries.Inc(hook.Name, "dead_letter")
+		d.deadLetter(delivery)
+		d.dequeue(hook, delivery)
+		return true
+	}
+
+	delay := d.opts.RetryDelay << min(delivery.Attempts-1, 30)
+	delay = max(min(delay, d.opts.RetryMaxDelay), retryAfter)
+	delivery.NextAttempt = time.Now().Add(delay)
+	logWebhook.Warn("webhook.retry", "webhook", hook.Name, "event", delivery.Event, "id", delivery.ID, "attempts", delivery.Attempts, "retry_in", delay.String(), "error", err)
+	webhookDeliveries.Inc(hook.Name, "retry")
+	d.persist(delivery)
+	return false
+}
+
+// ความผิดพลาดที่ส่งซ้ำแล้วไม่มีประโยชน์ เช่นปลายทางตอบ 4xx (ยกเว้น 408 และ 429)
+type webhookPermanentError struct {
+	err error
+}
+
+func (e *webhookPermanentError) Error() string {
+	return e.err.Error()
+}
+
+// POST payload ไปยัง webhook คืนค่าระยะรอจาก Retry-After (ถ้ามี)
+func (d *webhookDispatcher) send(ctx context.Context, hook *webhook, delivery *webhookDelivery) (time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Body))
+	if err != nil {
+		return 0, &webhookPermanentError{err}
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "phi-DCN-webhook")
+	req.Header.Set("X-DCN-Event", delivery.Event)
+	req.Header.Set("X-DCN-Delivery", delivery.ID)
+	req.Header.Set("X-DCN-Timestamp", timestamp)
+	if hook.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(hook.Secret))
+		mac.Write([]byte(timestamp + "."))
+		mac.Write(delivery.Body)
+		req.Header.Set("X-DCN-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	for name, value := range hook.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return 0, nil
+	case resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode >= 500:
+		var retryAfter time.Duration
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			retryAfter = min(time.Duration(seconds)*time.Second, d.opts.RetryMaxDelay)
+		}
+		return retryAfter, fmt.Errorf("ปลายทางตอบ %d", resp.StatusCode)
+	default:
+		return 0, &webhookPermanentError{fmt.Errorf("ปลายทางตอบ %d", resp.StatusCode)}
+	}
+}
+
+// ลบ delivery ออกจากคิวทั้งในหน่วยความจำและบน disk
+func (d *webhookDispatcher) dequeue(hook *webhook, delivery *webhookDelivery) {
+	hook.mu.Lock()
+	if i := slices.Index(hook.queue, delivery); i >= 0 {
+		hook.queue = slices.Delete(hook.queue, i, i+1)
+	}
+	hook.mu.Unlock()
+	hook.dropped.Store(false)
+
+	if err := os.Remove(d.queuePath(delivery)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		logWebhook.Warn("webhook.persist_failed", "id", delivery.ID, "error", err)
+	}
+}
+
+func (d *webhookDispatcher) queuePath(delivery *webhookDelivery) string {
+	return filepath.Join(d.opts.Dir, WEBHOOK_QUEUE_DIR, delivery.ID+".json")
+}
+
+// เขียน delivery ลงคิวบน disk (เขียนไฟล์ชั่วคราวแล้วเปลี่ยนชื่อ เพื่อไม่ให้เหลือไฟล์ที่เขียนไม่ครบ)
+func (d *webhookDispatcher) persist(delivery *webhookDelivery) {
+	data, _ := json.Marshal(delivery)
+	path := d.queuePath(delivery)
+	err := os.WriteFile(path+".tmp", data, 0o644)
+	if err == nil {
+		err = os.Rename(path+".tmp", path)
+	}
+	if err != nil {
+		logWebhook.Warn("webhook.persist_failed", "id", delivery.ID, "error", err)
+	}
+}
+
+// เพิ่ม delivery ที่ส่งไม่สำเร็จลง dead-letter log และลบออกจากคิวบน disk
+func (d *webhookDispatcher) deadLetter(delivery *webhookDelivery) {
+	now := time.Now()
+	delivery.FailedAt = &now
+	data, _ := json.Marshal(delivery)
+
+	d.deadMu.Lock()
+	defer d.deadMu.Unlock()
+
+	path := filepath.Join(d.opts.Dir, WEBHOOK_DEAD_LETTER)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err == nil {
+		_, err = f.Write(append(data, '\n'))
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		logWebhook.Warn("webhook.persist_failed", "file", path, "id", delivery.ID, "error", err)
+	}
+	os.Remove(d.queuePath(delivery))
+}
+
+// จำนวน delivery ที่รอส่งของแต่ละ webhook สำหรับ metrics
+func (d *webhookDispatcher) samples() []metrics.Sample {
+	samples := make([]metrics.Sample, 0, len(d.hooks))
+	for _, hook := range d.hooks {
+		hook.mu.Lock()
+		samples = append(samples, metrics.Sample{Labels: []string{hook.Name}, Value: float64(len(hook.queue))})
+		hook.mu.Unlock()
+	}
+	return samples
+}
@@ -0,0 +1,181 @@
+package proxy
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/ampol-me/phi-DCN/internal/websocket"
+	"github.com/ampol-me/phi-DCN/protocol"
+)
+
+const (
+	WS_PING_INTERVAL = 30 // ระยะห่างการส่ง ping ไปยัง WebSocket client (วินาที)
+	WS_READ_TIMEOUT  = 75 // ตัดการเชื่อมต่อถ้าไม่ได้รับ frame ใด ๆ รวมถึง pong ภายในเวลานี้ (วินาที)
+	WS_WRITE_TIMEOUT = 10 // timeout การส่งข้อความหนึ่งข้อความ (วินาที)
+)
+
+// คำสั่งที่ WebSocket client ส่งมาได้
+//
+//	{"type": "filter", "seats": ["7", "12"], "topics": ["seat"]}  เปลี่ยน filter และรับ snapshot ใหม่
+//	{"type": "ping"}                                          ตอบกลับด้วย {"type": "pong"}
+type wsCommand struct {
+	Type   string   `json:"type"`
+	Seats  []string `json:"seats"`
+	Topics []string `json:"topics"`
+}
+
+// ข้อความตอบกลับคำสั่งของ client
+type wsReply struct {
+	Type  string    `json:"type"`
+	Time  time.Time `json:"time"`
+	Error string    `json:"error,omitempty"`
+}
+
+// GET /ws/events: ส่งเหตุการณ์ของ seat state เป็น JSON ผ่าน WebSocket
+//
+// filter เริ่มต้นกำหนดได้ด้วย query ?seats=7,12&topics=seat,discussion
+func (p *ProxyServer) wsEventsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter, err := newEventFilter(splitList(query.Get("seats")), splitList(query.Get("topics")))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	conn, err := websocket.Upgrade(w, r)
+	if err != nil {
+		logStream.Debug("stream.upgrade_failed", "addr", r.RemoteAddr, "error", err)
+		return
+	}
+	conn.ReadTimeout = WS_READ_TIMEOUT * time.Second
+
+	addr := conn.RemoteAddr().String()
+	sub := p.events.subscribe("websocket", addr, filter, p.snapshotJSON, nil)
+	if sub == nil {
+		conn.CloseWithStatus(websocket.CLOSE_GOING_AWAY, "shutting down")
+		return
+	}
+	logStream.Info("stream.connected", "transport", sub.transport, "addr", addr)
+
+	go p.wsReadLoop(conn, sub)
+	p.wsWriteLoop(conn, sub)
+	logStream.Info("stream.disconnected", "transport", sub.transport, "addr", addr, "reason", sub.reason)
+}
+
+// ส่งข้อความในคิวและ ping ตามรอบ จนกว่า subscriber จะถูกลบออกจาก hub
+func (p *ProxyServer) wsWriteLoop(conn *websocket.Conn, sub *subscriber) {
+	ticker := time.NewTicker(WS_PING_INTERVAL * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case ev, ok := <-sub.send:
+			if !ok {
+				switch sub.reason {
+				case "shutdown":
+					conn.CloseWithStatus(websocket.CLOSE_GOING_AWAY, "shutting down")
+				case "queue_full":
+					conn.CloseWithStatus(websocket.CLOSE_TRY_AGAIN, "too slow")
+				default:
+					conn.Close()
+				}
+				return
+			}
+			conn.SetWriteDeadline(time.Now().Add(WS_WRITE_TIMEOUT * time.Second))
+			if err := conn.WriteMessage(websocket.OP_TEXT, ev.Data); err != nil {
+				p.events.remove(sub, "write_error")
+				streamEvictions.Inc(sub.transport, "write_error")
+			}
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(WS_WRITE_TIMEOUT * time.Second))
+			if err := conn.Ping(); err != nil {
+				p.events.remove(sub, "write_error")
+				streamEvictions.Inc(sub.transport, "write_error")
+			}
+		}
+	}
+}
+
+// รับคำสั่งจาก client จนกว่าการเชื่อมต่อจะปิดหรือไม่มี pong ภายในเวลาที่กำหนด
+func (p *ProxyServer) wsReadLoop(conn *websocket.Conn, sub *subscriber) {
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			var netErr net.Error
+			switch {
+			case errors.As(err, &netErr) && netErr.Timeout():
+				p.events.remove(sub, "timeout")
+				streamEvictions.Inc(sub.transport, "timeout")
+			case errors.Is(err, io.EOF):
+				p.events.remove(sub, "closed")
+			default:
+				p.events.remove(sub, "read_error")
+			}
+			return
+		}
+
+		var cmd wsCommand
+		if err := json.Unmarshal(data, &cmd); err != nil {
+			p.wsReply(sub, "error", "ข้อความต้องเป็น JSON")
+			continue
+		}
+		switch cmd.Type {
+		case "ping":
+			p.wsReply(sub, "pong", "")
+		case "filter":
+			filter, err := newEventFilter(cmd.Seats, cmd.Topics)
+			if err != nil {
+				p.wsReply(sub, "error", err.Error())
+				continue
+			}
+			p.events.setFilter(sub, filter, p.snapshotJSON)
+		default:
+			logStream.Debug("stream.bad_message", "addr", sub.addr, "type", cmd.Type)
+			p.wsReply(sub, "error", "ไม่รู้จักคำสั่ง "+cmd.Type)
+		}
+	}
+}
+
+func (p *ProxyServer) wsReply(sub *subscriber, kind, message string) {
+	data, _ := json.Marshal(wsReply{Type: kind, Time: time.Now(), Error: message})
+	p.events.sendTo(sub, streamEvent{Type: kind, Data: data})
+}
+
+// GET /ws/frames: ส่ง frame DCN ที่ได้รับจาก upstream ทุก byte เหมือน TCP listener
+// โดยหนึ่ง frame เป็นหนึ่งข้อความ binary
+//
+// เลือก frame ที่ต้องการได้ด้วย query ?seats=7,12&topics=seat,discussion
+func (p *ProxyServer) wsFramesHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter, err := protocol.NewFrameFilter(splitList(query.Get("seats")), splitList(query.Get("topics")))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	conn, err := websocket.Upgrade(w, r)
+	if err != nil {
+		logClients.Debug("client.upgrade_failed", "addr", r.RemoteAddr, "error", err)
+		return
+	}
+	conn.ReadTimeout = WS_READ_TIMEOUT * time.Second
+
+	client := p.AddClient(conn, TRANSPORT_WEBSOCKET, filter)
+	if client == nil {
+		return
+	}
+	defer p.RemoveClient(client.id)
+	go conn.KeepAlive(WS_PING_INTERVAL*time.Second, client.done)
+	p.sendSnapshot(client)
+
+	// ข้อความจาก client ไม่มีความหมาย อ่านทิ้งจนกว่าจะยกเลิกการเชื่อมต่อ
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ข้อมูลของ client ที่แสดงผ่าน admin API
+type ClientInfo struct {
+	ID          int       `json:"id"`
+	RemoteAddr  string    `json:"remoteAddr"`
+	Transport   string    `json:"transport"`
+	ConnectedAt time.Time `json:"connectedAt"`
+	BytesSent   uint64    `json:"bytesSent"`
+	FramesSent  uint64    `json:"framesSent"`
+	QueueLength int       `json:"queueLength"`
+}
+
+// รายการ clients ที่เชื่อมต่ออยู่ เรียงตาม id
+func (s *Server) Clients() []ClientInfo {
+	s.clientLock.Lock()
+	defer s.clientLock.Unlock()
+
+	infos := make([]ClientInfo, 0, len(s.clients))
+	for _, client := range s.clients {
+		infos = append(infos, ClientInfo{
+			ID:          client.id,
+			RemoteAddr:  client.conn.RemoteAddr().String(),
+			Transport:   client.transport,
+			ConnectedAt: client.connectedAt,
+			BytesSent:   client.bytesSent.Load(),
+			FramesSent:  client.framesSent.Load(),
+			QueueLength: len(client.send),
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].ID < infos[j].ID })
+	return infos
+}
+
+// สำเนาสถานะที่นั่งทั้งหมด เรียงตาม id (ระหว่าง replay เป็นสถานะที่ replay ส่งให้ clients)
+func (s *Server) Seats() []SeatState {
+	if s.replay != nil {
+		return s.replay.Seats()
+	}
+
+	s.stateLock.Lock()
+	defer s.stateLock.Unlock()
+
+	seats := make([]SeatState, 0, len(s.seats))
+	for _, id := range s.seatIDs() {
+		seats = append(seats, *s.seats[id])
+	}
+	return seats
+}
+
+// ผลลัพธ์การดึงข้อมูลจาก API รอบล่าสุด
+func (s *Server) LastPoll() PollResult {
+	s.stateLock.Lock()
+	defer s.stateLock.Unlock()
+
+	return s.lastPoll
+}
+
+// สร้าง http.Handler สำหรับ admin API
+//
+//	GET    /clients          รายการ clients ที่เชื่อมต่ออยู่
+//	DELETE /clients/{id}     ตัดการเชื่อมต่อ client
+//	GET    /seats            สถานะที่นั่งทั้งหมด
+//	GET    /poll             ผลลัพธ์การดึงข้อมูลจาก API รอบล่าสุด
+//	POST   /snapshot         ส่งสถานะปัจจุบันทั้งหมดไปยัง clients
+//	GET    /metrics          metrics ในรูปแบบ Prometheus
+//	GET    /healthz          liveness: listener ยังทำงานอยู่หรือไม่
+//	GET    /readyz           readiness: listener และการดึงข้อมูลจาก API
+//	GET    /replay           สถานะของ replay
+//	POST   /replay/pause     หยุด replay ชั่วคราว
+//	POST   /replay/resume    เล่น replay ต่อ
+//	POST   /replay/seek      เลื่อนตำแหน่ง เช่น {"time": "2025-04-03T16:30:06+07:00"} หรือ {"offset": "90s"}
+//	POST   /replay/speed     เปลี่ยนความเร็ว เช่น {"speed": 2}
+//	POST   /mock/mic         กำหนดสถานะไมค์ของ mock เช่น {"micOn": true}
+//	DELETE /mock/mic         กลับไปสลับสถานะไมค์ของ mock อัตโนมัติ
+func (s *Server) AdminHandler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /clients", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, s.Clients())
+	})
+
+	mux.HandleFunc("DELETE /clients/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "client id ไม่ถูกต้อง")
+			return
+		}
+		if !s.RemoveClient(id) {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("ไม่พบ client %d", id))
+			return
+		}
+		clientEvictions.Inc("admin")
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("GET /seats", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, s.Seats())
+	})
+
+	mux.HandleFunc("GET /poll", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, s.LastPoll())
+	})
+
+	mux.HandleFunc("POST /snapshot", func(w http.ResponseWriter, r *http.Request) {
+		if s.replay != nil {
+			s.replay.Snapshot(s.Broadcast)
+		} else {
+			s.BroadcastSnapshot()
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.Handle("GET /metrics", s.newMetricsRegistry())
+	mux.HandleFunc("GET /healthz", s.healthHandler(true))
+	mux.HandleFunc("GET /readyz", s.healthHandler(false))
+
+	mux.HandleFunc("GET /replay", s.replayHandler(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, s.replay.Status())
+	}))
+
+	mux.HandleFunc("POST /replay/pause", s.replayHandler(func(w http.ResponseWriter, r *http.Request) {
+		s.replay.Pause()
+		writeJSON(w, http.StatusOK, s.replay.Status())
+	}))
+
+	mux.HandleFunc("POST /replay/resume", s.replayHandler(func(w http.ResponseWriter, r *http.Request) {
+		s.replay.Resume()
+		writeJSON(w, http.StatusOK, s.replay.Status())
+	}))
+
+	mux.HandleFunc("POST /replay/seek", s.replayHandler(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Time   time.Time `json:"time"`
+			Offset string    `json:"offset"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, `ต้องระบุ {"time": "<RFC3339>"} หรือ {"offset": "<duration>"}`)
+			return
+		}
+		target := body.Time
+		if body.Offset != "" {
+			offset, err := time.ParseDuration(body.Offset)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "offset ไม่ถูกต้อง: "+err.Error())
+				return
+			}
+			target = s.replay.Status().Start.Add(offset)
+		}
+		if err := s.replay.Seek(target); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, s.replay.Status())
+	}))
+
+	mux.HandleFunc("POST /replay/speed", s.replayHandler(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Speed float64 `json:"speed"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, `ต้องระบุ {"speed": <ตัวเลข>}`)
+			return
+		}
+		if err := s.replay.SetSpeed(body.Speed); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, s.replay.Status())
+	}))
+
+	mux.HandleFunc("POST /mock/mic", func(w http.ResponseWriter, r *http.Request) {
+		if !s.api.Mock {
+			writeError(w, http.StatusConflict, "server ไม่ได้ทำงานในโหมด mock")
+			return
+		}
+		var body struct {
+			MicOn *bool `json:"micOn"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.MicOn == nil {
+			writeError(w, http.StatusBadRequest, `ต้องระบุ {"micOn": true|false}`)
+			return
+		}
+		setMockMicState(*body.MicOn)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("DELETE /mock/mic", func(w http.ResponseWriter, r *http.Request) {
+		if !s.api.Mock {
+			writeError(w, http.StatusConflict, "server ไม่ได้ทำงานในโหมด mock")
+			return
+		}
+		resumeMockToggle()
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	return mux
+}
+
+// ห่อ handler ของ replay ให้ตอบ 409 เมื่อ server ไม่ได้ทำงานในโหมด replay
+func (s *Server) replayHandler(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.replay == nil {
+			writeError(w, http.StatusConflict, "server ไม่ได้ทำงานในโหมด replay")
+			return
+		}
+		h(w, r)
+	}
+}
+
+// เขียน response เป็น JSON
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+// เขียน error response เป็น JSON
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
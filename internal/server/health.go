@@ -0,0 +1,124 @@
+package server
+
+import (
+	"net/http"
+	"time"
+)
+
+// สถานะของ health check
+const (
+	HEALTH_OK       = "ok"
+	HEALTH_DEGRADED = "degraded"
+	HEALTH_FAIL     = "fail"
+)
+
+// เกณฑ์เวลาที่ใช้ตัดสินสถานะของ upstream
+type HealthThresholds struct {
+	DegradedAfter time.Duration // ไม่มี poll สำเร็จนานเกินนี้ถือว่า degraded
+	FailAfter     time.Duration // ไม่มี poll สำเร็จนานเกินนี้ถือว่า fail
+}
+
+// ผลการตรวจสอบส่วนประกอบหนึ่ง
+type CheckResult struct {
+	Status  string         `json:"status"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+// รายงานสุขภาพของ server
+type HealthReport struct {
+	Status        string                 `json:"status"`
+	Checks        map[string]CheckResult `json:"checks"`
+	Clients       int                    `json:"clients"`
+	UptimeSeconds float64                `json:"uptimeSeconds"`
+}
+
+// สถานะที่แย่กว่าระหว่างสองค่า
+func worseStatus(a, b string) string {
+	rank := map[string]int{HEALTH_OK: 0, HEALTH_DEGRADED: 1, HEALTH_FAIL: 2}
+	if rank[b] > rank[a] {
+		return b
+	}
+	return a
+}
+
+// ตรวจสอบ listener
+func (s *Server) checkListener() CheckResult {
+	result := CheckResult{Status: HEALTH_OK, Details: map[string]any{"addr": s.listenAddr}}
+	if !s.listening.Load() {
+		result.Status = HEALTH_FAIL
+	}
+	return result
+}
+
+// ตรวจสอบการดึงข้อมูลจาก API ตามเวลาที่ poll สำเร็จครั้งล่าสุด
+func (s *Server) checkUpstream() CheckResult {
+	// โหมด replay ไม่มี upstream
+	if s.replay != nil {
+		status := s.replay.Status()
+		return CheckResult{Status: HEALTH_OK, Details: map[string]any{
+			"replay":   status.Source,
+			"position": status.Position,
+			"playing":  status.Playing,
+		}}
+	}
+
+	s.stateLock.Lock()
+	lastPoll := s.lastPoll
+	lastSuccess := s.lastPollSuccess
+	s.stateLock.Unlock()
+
+	// ก่อน poll สำเร็จครั้งแรก นับเวลาจากตอนเริ่ม server
+	since := lastSuccess
+	if since.IsZero() {
+		since = s.startedAt
+	}
+	elapsed := time.Since(since)
+
+	result := CheckResult{Status: HEALTH_OK, Details: map[string]any{
+		"mock":                    s.api.Mock,
+		"sinceLastSuccessSeconds": elapsed.Seconds(),
+	}}
+	if !lastSuccess.IsZero() {
+		result.Details["lastSuccess"] = lastSuccess
+	}
+	if lastPoll.Error != "" {
+		result.Details["lastError"] = lastPoll.Error
+	}
+
+	switch {
+	case s.thresholds.FailAfter > 0 && elapsed > s.thresholds.FailAfter:
+		result.Status = HEALTH_FAIL
+	case s.thresholds.DegradedAfter > 0 && elapsed > s.thresholds.DegradedAfter:
+		result.Status = HEALTH_DEGRADED
+	}
+	return result
+}
+
+// สร้างรายงานสุขภาพ live = true ตรวจเฉพาะ listener (liveness) ไม่เช่นนั้นตรวจ upstream ด้วย (readiness)
+func (s *Server) Health(live bool) HealthReport {
+	report := HealthReport{
+		Status:        HEALTH_OK,
+		Checks:        map[string]CheckResult{"listener": s.checkListener()},
+		Clients:       len(s.Clients()),
+		UptimeSeconds: time.Since(s.startedAt).Seconds(),
+	}
+	if !live {
+		report.Checks["upstream"] = s.checkUpstream()
+	}
+	for _, check := range report.Checks {
+		report.Status = worseStatus(report.Status, check.Status)
+	}
+	return report
+}
+
+// handler ของ /healthz และ /readyz ตอบ 503 เมื่อสถานะเป็น fail
+func (s *Server) healthHandler(live bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report := s.Health(live)
+		status := http.StatusOK
+		if report.Status == HEALTH_FAIL {
+			status = http.StatusServiceUnavailable
+		}
+		writeJSON(w, status, report)
+	}
+}
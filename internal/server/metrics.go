@@ -0,0 +1,65 @@
+package server
+
+import (
+	"strconv"
+
+	"github.com/ampol-me/phi-DCN/internal/metrics"
+)
+
+// metrics ของ server
+var (
+	framesBroadcast = metrics.NewCounter("dcn_frames_broadcast_total", "Frames broadcast to clients by topic.", "topic")
+	bytesSent       = metrics.NewCounter("dcn_bytes_sent_total", "Bytes written to client connections.")
+	writeErrors     = metrics.NewCounter("dcn_write_errors_total", "Failed writes to client connections.")
+	clientEvictions = metrics.NewCounter("dcn_client_evictions_total", "Clients disconnected by the server by reason.", "reason")
+	pollDuration    = metrics.NewHistogram("dcn_upstream_poll_duration_seconds", "Latency of speaker polls against the upstream API.",
+		0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5)
+	pollFailures  = metrics.NewCounter("dcn_upstream_poll_failures_total", "Failed speaker polls against the upstream API.")
+	journalErrors = metrics.NewCounter("dcn_journal_write_errors_total", "Frames that could not be written to the journal.")
+)
+
+// สร้าง registry พร้อม metrics ที่อ่านค่าจากสถานะของ server
+func (s *Server) newMetricsRegistry() *metrics.Registry {
+	r := &metrics.Registry{}
+	r.Register(
+		metrics.NewGaugeFunc("dcn_clients_connected", "Currently connected clients (TCP and WebSocket).", func() float64 {
+			s.clientLock.Lock()
+			defer s.clientLock.Unlock()
+			return float64(len(s.clients))
+		}),
+		framesBroadcast,
+		bytesSent,
+		writeErrors,
+		clientEvictions,
+		pollDuration,
+		pollFailures,
+		journalErrors,
+		metrics.NewGaugeFunc("dcn_active_microphones", "Seats whose microphone is currently on.", func() float64 {
+			active := 0
+			for _, seat := range s.Seats() {
+				if seat.MicOn {
+					active++
+				}
+			}
+			return float64(active)
+		}),
+		&metrics.FuncMetric{
+			Name:   "dcn_seat_talk_seconds_total",
+			Help:   "Cumulative time each seat's microphone has been on.",
+			Kind:   "counter",
+			Labels: []string{"seat_id", "seat"},
+			Collect: func() []metrics.Sample {
+				seats := s.Seats()
+				samples := make([]metrics.Sample, 0, len(seats))
+				for _, seat := range seats {
+					samples = append(samples, metrics.Sample{
+						Labels: []string{strconv.Itoa(seat.Speaker.ID), seat.Speaker.SeatName},
+						Value:  seat.TalkTime().Seconds(),
+					})
+				}
+				return samples
+			},
+		},
+	)
+	return r
+}
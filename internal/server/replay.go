@@ -0,0 +1,389 @@
+package server
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ampol-me/phi-DCN/internal/journal"
+	"github.com/ampol-me/phi-DCN/protocol"
+)
+
+// สถานะของการ replay ที่แสดงผ่าน admin API
+type ReplayStatus struct {
+	Source   string    `json:"source"`
+	Playing  bool      `json:"playing"`
+	Speed    float64   `json:"speed"`
+	Loop     bool      `json:"loop"`
+	Position time.Time `json:"position"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Index    int       `json:"index"`
+	Total    int       `json:"total"`
+	Finished bool      `json:"finished"`
+}
+
+// Replayer ส่ง frame จาก journal ที่บันทึกไว้ไปยัง clients ตามจังหวะเวลาเดิม
+type Replayer struct {
+	server  *Server
+	source  string
+	records []journal.Record
+
+	mu          sync.Mutex
+	index       int       // record ถัดไปที่จะส่ง
+	playing     bool      // false = หยุดชั่วคราว
+	speed       float64   // ตัวคูณความเร็ว
+	loop        bool      // เล่นซ้ำเมื่อจบ
+	anchorWall  time.Time // เวลาจริงที่ใช้อ้างอิง
+	anchorMedia time.Time // เวลาใน journal ที่ตรงกับ anchorWall
+	wake        chan struct{}
+
+	// สถานะล่าสุดสำหรับ client ที่เชื่อมต่อระหว่าง replay
+	lastDiscussion []byte
+	lastSeats      map[string][]byte
+
+	// สถานะที่นั่งตามที่ส่งให้ clients สำหรับ admin API และ metrics
+	seats map[int]*SeatState
+}
+
+// โหลด journal ทั้งหมดเข้าหน่วยความจำสำหรับ replay
+func NewReplayer(server *Server, source string, speed float64, loop bool) (*Replayer, error) {
+	if speed <= 0 {
+		return nil, fmt.Errorf("ความเร็ว replay ต้องมากกว่า 0")
+	}
+
+	reader, err := journal.OpenReader(source)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	var records []journal.Record
+	for {
+		record, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// ไฟล์ที่อ่านไม่ได้ (เช่นไฟล์ว่างที่เหลือจากโปรแกรมหยุดกลางคัน) ข้ามไปเหมือน journal.Open
+			logReplay.Warn("replay.file_skipped", "source", source, "error", err)
+			reader.SkipFile()
+			continue
+		}
+		records = append(records, record)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("ไม่พบ frame ใน %s", source)
+	}
+
+	// journal หลายไฟล์อาจมาจากหลาย session เรียงตามเวลาให้แน่ใจ
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Time.Before(records[j].Time) })
+
+	r := &Replayer{
+		server:    server,
+		source:    source,
+		records:   records,
+		playing:   true,
+		speed:     speed,
+		loop:      loop,
+		wake:      make(chan struct{}, 1),
+		lastSeats: make(map[string][]byte),
+		seats:     make(map[int]*SeatState),
+	}
+	r.anchorMedia = records[0].Time
+	return r, nil
+}
+
+// ส่ง frame ตามเวลาจนกว่า ctx จะถูกยกเลิก
+func (r *Replayer) Run(ctx context.Context) {
+	r.mu.Lock()
+	r.anchorWall = time.Now()
+	r.mu.Unlock()
+
+	logReplay.Info("replay.started", "source", r.source, "frames", len(r.records), "speed", r.speed)
+
+	for {
+		wait, ok := r.step()
+		if !ok {
+			// จบแล้วและไม่เล่นซ้ำ รอคำสั่ง seek หรือสัญญาณหยุด
+			wait = time.Hour
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			// สถานะของ server ว่างเสมอระหว่าง replay จึงปิดไมค์จากสถานะที่ replay ส่งไปแล้ว
+			logMain.Info("server.final_state")
+			r.mu.Lock()
+			r.clearLocked()
+			r.mu.Unlock()
+			return
+		case <-r.wake:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+// ส่ง frame ที่ถึงเวลาแล้วทั้งหมด คืนค่าเวลาที่ต้องรอถึง frame ถัดไป
+// ok = false เมื่อหยุดชั่วคราวหรือเล่นจบแล้ว
+func (r *Replayer) step() (wait time.Duration, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for {
+		if !r.playing {
+			return 0, false
+		}
+
+		if r.index >= len(r.records) {
+			if !r.loop {
+				return 0, false
+			}
+			logReplay.Info("replay.looped", "source", r.source)
+			r.clearLocked()
+			r.lastDiscussion = nil
+			r.lastSeats = make(map[string][]byte)
+			r.index = 0
+			r.anchorMedia = r.records[0].Time
+			r.anchorWall = time.Now()
+		}
+
+		record := r.records[r.index]
+		due := r.anchorWall.Add(time.Duration(float64(record.Time.Sub(r.anchorMedia)) / r.speed))
+		if wait := time.Until(due); wait > 0 {
+			return wait, true
+		}
+
+		frame := protocol.BuildFrame(record.Topic, record.Payload)
+		r.remember(record.Topic, frame)
+		r.server.Broadcast(frame)
+		if record.Topic == protocol.TOPIC_SEAT {
+			r.track(frame, time.Now())
+		}
+		r.index++
+	}
+}
+
+// เก็บ frame ล่าสุดของแต่ละที่นั่งและ DiscussionActivity ล่าสุด (ต้องถือ mu อยู่)
+func (r *Replayer) remember(topic uint32, frame []byte) {
+	switch topic {
+	case protocol.TOPIC_DISCUSSION:
+		r.lastDiscussion = frame
+	case protocol.TOPIC_SEAT:
+		if id, err := protocol.SeatIDFromPayload(frame[8:]); err == nil {
+			r.lastSeats[id] = frame
+		}
+	}
+}
+
+// เวลาใน journal ณ ขณะนี้ (ต้องถือ mu อยู่)
+func (r *Replayer) positionLocked() time.Time {
+	if !r.playing {
+		return r.anchorMedia
+	}
+	return r.anchorMedia.Add(time.Duration(float64(time.Since(r.anchorWall)) * r.speed))
+}
+
+func (r *Replayer) signal() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// หยุด replay ชั่วคราว
+func (r *Replayer) Pause() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.playing {
+		r.anchorMedia = r.positionLocked()
+		r.playing = false
+		logReplay.Info("replay.paused", "position", r.anchorMedia)
+	}
+	r.signal()
+}
+
+// เล่น replay ต่อจากตำแหน่งเดิม
+func (r *Replayer) Resume() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.playing {
+		r.anchorWall = time.Now()
+		r.playing = true
+		logReplay.Info("replay.resumed", "position", r.anchorMedia)
+	}
+	r.signal()
+}
+
+// เปลี่ยนความเร็วโดยคงตำแหน่งปัจจุบันไว้
+func (r *Replayer) SetSpeed(speed float64) error {
+	if speed <= 0 {
+		return fmt.Errorf("ความเร็ว replay ต้องมากกว่า 0")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.anchorMedia = r.positionLocked()
+	r.anchorWall = time.Now()
+	r.speed = speed
+	logReplay.Info("replay.speed", "speed", speed)
+	r.signal()
+	return nil
+}
+
+// กระโดดไปยังเวลาที่กำหนดใน journal และส่ง snapshot ของสถานะ ณ เวลานั้นไปยังทุก clients
+func (r *Replayer) Seek(t time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	start, end := r.records[0].Time, r.records[len(r.records)-1].Time
+	if t.Before(start) || t.After(end) {
+		return fmt.Errorf("เวลา %s อยู่นอกช่วง %s ถึง %s", t.Format(time.RFC3339Nano), start.Format(time.RFC3339Nano), end.Format(time.RFC3339Nano))
+	}
+
+	// ล้างสถานะที่ client เห็นอยู่ แล้วสร้างสถานะใหม่จาก frame ทั้งหมดก่อนเวลาที่กำหนด
+	r.clearLocked()
+	r.lastDiscussion = nil
+	r.lastSeats = make(map[string][]byte)
+	r.index = 0
+	for r.index < len(r.records) && r.records[r.index].Time.Before(t) {
+		record := r.records[r.index]
+		r.remember(record.Topic, protocol.BuildFrame(record.Topic, record.Payload))
+		r.index++
+	}
+	r.anchorMedia = t
+	r.anchorWall = time.Now()
+
+	logReplay.Info("replay.seek", "position", t, "index", r.index)
+	r.snapshotLocked(r.server.Broadcast)
+	now := time.Now()
+	for _, frame := range r.lastSeats {
+		r.track(frame, now)
+	}
+	r.signal()
+	return nil
+}
+
+// ปิดไมค์ของที่นั่งที่ยังเปิดอยู่และส่ง ActiveList ว่างเหมือน broadcastFinalState (ต้องถือ mu อยู่)
+//
+// snapshot ส่งเฉพาะที่นั่งที่มี frame ก่อนตำแหน่งใหม่ ไมค์ที่เปิดอยู่ ณ ตำแหน่งเดิมจึงต้องถูกปิดก่อน
+// เมื่อ seek ย้อนหลังหรือเล่นซ้ำจากต้น และเมื่อหยุด replay
+func (r *Replayer) clearLocked() {
+	ids := make([]int, 0, len(r.seats))
+	for id := range r.seats {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	now := time.Now()
+	for _, id := range ids {
+		seat := r.seats[id]
+		if seat.MicOn {
+			r.server.Broadcast(protocol.BuildFrame(protocol.TOPIC_SEAT, generateSeatXML(seat.Speaker, false)))
+			seat.setMic(false, now)
+		}
+	}
+	r.server.Broadcast(protocol.BuildFrame(protocol.TOPIC_DISCUSSION, generateDiscussionXML(nil)))
+}
+
+// ปรับสถานะที่นั่งตาม frame ของ SeatActivity ที่ส่งให้ clients แล้ว (ต้องถือ mu อยู่)
+func (r *Replayer) track(frame []byte, now time.Time) {
+	var activity protocol.SeatActivity
+	if err := xml.Unmarshal([]byte(protocol.DecodePayload(frame[8:])), &activity); err != nil {
+		return
+	}
+	id, err := strconv.Atoi(activity.Seat.ID)
+	if err != nil {
+		return
+	}
+	participantID, _ := strconv.Atoi(activity.Seat.Participant.ID)
+	micOn := activity.Seat.SeatData.MicrophoneActive
+	speaker := Speaker{
+		ID:            id,
+		Name:          activity.Seat.Participant.ParticipantData.LastName,
+		SeatName:      activity.Seat.SeatData.Name,
+		ParticipantID: participantID,
+		MicOn:         micOn,
+	}
+
+	seat, ok := r.seats[id]
+	if !ok {
+		seat = &SeatState{}
+		r.seats[id] = seat
+	}
+	seat.Speaker, seat.Present = speaker, true
+	if !ok || seat.MicOn != micOn {
+		seat.setMic(micOn, now)
+	}
+}
+
+// ส่ง frame ของสถานะล่าสุดผ่าน send (ต้องถือ mu อยู่)
+func (r *Replayer) snapshotLocked(send func([]byte)) {
+	ids := make([]string, 0, len(r.lastSeats))
+	for id := range r.lastSeats {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		send(r.lastSeats[id])
+	}
+	if r.lastDiscussion != nil {
+		send(r.lastDiscussion)
+	}
+}
+
+// ส่ง snapshot ให้ client ที่เพิ่งเชื่อมต่อ
+func (r *Replayer) Snapshot(send func([]byte)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.snapshotLocked(send)
+}
+
+// สำเนาสถานะที่นั่งตามที่ replay ส่งให้ clients เรียงตาม id
+func (r *Replayer) Seats() []SeatState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ids := make([]int, 0, len(r.seats))
+	for id := range r.seats {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	seats := make([]SeatState, 0, len(ids))
+	for _, id := range ids {
+		seats = append(seats, *r.seats[id])
+	}
+	return seats
+}
+
+// สถานะปัจจุบันของ replay
+func (r *Replayer) Status() ReplayStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return ReplayStatus{
+		Source:   r.source,
+		Playing:  r.playing,
+		Speed:    r.speed,
+		Loop:     r.loop,
+		Position: r.positionLocked(),
+		Start:    r.records[0].Time,
+		End:      r.records[len(r.records)-1].Time,
+		Index:    r.index,
+		Total:    len(r.records),
+		Finished: r.index >= len(r.records) && !r.loop,
+	}
+}
@@ -0,0 +1,145 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/ampol-me/phi-DCN/internal/journal"
+)
+
+// การตั้งค่าของ server
+type Config struct {
+	ListenAddr string           // ที่อยู่ TCP ที่รอ clients เชื่อมต่อ
+	AdminAddr  string           // ที่อยู่ของ admin HTTP API (ว่าง = ไม่เปิด)
+	WSAddr     string           // ที่อยู่ HTTP ที่ส่ง frame ผ่าน WebSocket ที่ /ws/frames (ว่าง = ไม่เปิด)
+	Health     HealthThresholds // เกณฑ์ของ /readyz
+	API        APIConfig        // แหล่งสถานะไมค์เมื่อไม่ได้ replay
+
+	Journal journal.Options // Journal.Dir ว่าง = ไม่บันทึก journal
+
+	ReplaySource string        // ไฟล์หรือไดเรกทอรี journal ที่จะ replay แทนการดึงข้อมูลจาก API (ว่าง = ไม่ replay)
+	ReplaySpeed  float64       // ตัวคูณความเร็วของ replay
+	ReplayLoop   bool          // เล่น replay ซ้ำเมื่อจบ
+	ReplaySeek   time.Duration // เริ่ม replay ที่ระยะเวลานี้นับจาก frame แรก
+}
+
+// เริ่ม server และทำงานจนกว่า ctx จะถูกยกเลิก
+// คืนค่า error เมื่อเริ่มทำงานไม่สำเร็จ (เปิด journal, โหลด replay หรือ listen ไม่ได้)
+func Run(ctx context.Context, cfg Config) error {
+	// เปิด journal ถ้ากำหนดไดเรกทอรีไว้
+	var j *journal.Journal
+	if cfg.Journal.Dir != "" {
+		var err error
+		j, err = journal.Open(cfg.Journal)
+		if err != nil {
+			logJournal.Error("journal.open_failed", "dir", cfg.Journal.Dir, "error", err)
+			return err
+		}
+		defer j.Close()
+	}
+
+	// สร้าง server
+	server := NewServer(cfg.ListenAddr, cfg.Health, cfg.API)
+	server.journal = j
+
+	// โหลด journal สำหรับ replay
+	if cfg.ReplaySource != "" {
+		replay, err := NewReplayer(server, cfg.ReplaySource, cfg.ReplaySpeed, cfg.ReplayLoop)
+		if err == nil && cfg.ReplaySeek > 0 {
+			err = replay.Seek(replay.Status().Start.Add(cfg.ReplaySeek))
+		}
+		if err != nil {
+			logReplay.Error("replay.load_failed", "source", cfg.ReplaySource, "error", err)
+			return err
+		}
+		server.replay = replay
+	}
+
+	// เริ่ม server
+	listener, err := net.Listen("tcp", cfg.ListenAddr)
+	if err != nil {
+		logMain.Error("server.start_failed", "error", err)
+		return err
+	}
+
+	server.listening.Store(true)
+
+	// ปิด listener เมื่อได้รับสัญญาณหยุด เพื่อให้ Accept คืนค่า
+	context.AfterFunc(ctx, func() {
+		server.listening.Store(false)
+		listener.Close()
+	})
+
+	logMain.Info("server.listening", "addr", listener.Addr().String())
+
+	// เริ่ม admin HTTP API
+	var adminServer *http.Server
+	if cfg.AdminAddr != "" {
+		adminServer = &http.Server{Addr: cfg.AdminAddr, Handler: server.AdminHandler()}
+		go func() {
+			logAdmin.Info("admin.listening", "addr", cfg.AdminAddr)
+			if err := adminServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logAdmin.Error("admin.start_failed", "error", err)
+			}
+		}()
+	}
+
+	// เริ่ม WebSocket listener ที่ใช้ client registry เดียวกับ TCP listener
+	var wsServer *http.Server
+	if cfg.WSAddr != "" {
+		wsServer = &http.Server{Addr: cfg.WSAddr, Handler: server.WSHandler()}
+		go func() {
+			logClients.Info("ws.listening", "addr", cfg.WSAddr, "path", "/ws/frames")
+			if err := wsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logClients.Error("ws.start_failed", "error", err)
+			}
+		}()
+	}
+
+	// เริ่มการประมวลผลและส่งข้อมูล
+	processDone := make(chan struct{})
+	go func() {
+		defer close(processDone)
+		if server.replay != nil {
+			server.replay.Run(ctx)
+		} else {
+			server.ProcessAndBroadcast(ctx)
+		}
+	}()
+
+	// รับการเชื่อมต่อจาก clients
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			logMain.Warn("server.accept_failed", "error", err)
+			continue
+		}
+		go handleClientConnection(server, conn)
+	}
+
+	logMain.Info("server.shutting_down")
+
+	// รอให้ส่งสถานะสุดท้ายเข้าคิวก่อน แล้วค่อยระบายคิวและปิดการเชื่อมต่อ
+	// การระบายคิวและการปิด admin API ใช้ deadline เดียวกัน
+	<-processDone
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), DRAIN_TIMEOUT*time.Second)
+	defer cancelDrain()
+	server.Shutdown(drainCtx)
+
+	if wsServer != nil {
+		// การเชื่อมต่อ WebSocket ถูก hijack ไปแล้วและปิดโดย Shutdown ของ server ด้านบน
+		wsServer.Close()
+	}
+	if adminServer != nil {
+		adminServer.Shutdown(drainCtx)
+	}
+
+	logMain.Info("server.stopped")
+	return nil
+}
@@ -0,0 +1,617 @@
+// Package server จำลอง Bosch DCN server: ดึงสถานะไมค์จาก API (หรือ mock หรือ journal ที่ replay)
+// แล้วส่ง frame SeatActivity และ DiscussionActivity ไปยัง clients ทาง TCP
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ampol-me/phi-DCN/internal/journal"
+	"github.com/ampol-me/phi-DCN/internal/logging"
+	"github.com/ampol-me/phi-DCN/internal/websocket"
+	"github.com/ampol-me/phi-DCN/protocol"
+)
+
+const (
+	LISTEN_ADDR = ":20000" // ที่อยู่ TCP ที่รอ clients เชื่อมต่อ
+	API_URL     = "http://10.115.206.10/api/speakers"
+
+	SEND_QUEUE_SIZE = 64 // จำนวน frame สูงสุดที่รอส่งต่อ client
+	DRAIN_TIMEOUT   = 5  // เวลารอส่งข้อมูลที่ค้างในคิวตอนปิด server (วินาที)
+
+	ADMIN_ADDR = "127.0.0.1:20080" // ที่อยู่ของ admin HTTP API
+)
+
+// logger ของแต่ละ subsystem
+var (
+	logMain     = logging.New("main")
+	logUpstream = logging.New("upstream")
+	logClients  = logging.New("clients")
+	logFraming  = logging.New("framing")
+	logAdmin    = logging.New("admin")
+	logJournal  = logging.New("journal")
+	logReplay   = logging.New("replay")
+)
+
+// โครงสร้างข้อมูลจาก API
+type Speaker struct {
+	ID            int    `json:"id"`
+	Name          string `json:"name"`
+	SeatName      string `json:"seatName"`
+	Prio          bool   `json:"prio"`
+	PrioOn        bool   `json:"prioOn"`
+	ParticipantID int    `json:"participantId"`
+	MicOn         bool   `json:"micOn"`
+}
+
+var (
+	mockLock     sync.Mutex
+	mockMicState = true
+	mockManual   = false // true = สถานะไมค์ถูกกำหนดผ่าน admin API และหยุดสลับอัตโนมัติ
+	lastToggle   = time.Now()
+)
+
+// ชนิดการเชื่อมต่อของ client
+const (
+	TRANSPORT_TCP       = "tcp"
+	TRANSPORT_WEBSOCKET = "websocket"
+)
+
+// Client เก็บข้อมูลของ client ที่เชื่อมต่อ
+type Client struct {
+	conn        net.Conn // การเชื่อมต่อ TCP หรือ *websocket.Conn ที่ส่ง frame ละหนึ่งข้อความ
+	id          int
+	transport   string
+	filter      protocol.FrameFilter // frame ที่ client ต้องการรับ (ว่าง = ทุก frame)
+	connectedAt time.Time
+	send        chan []byte   // คิวข้อมูลที่รอส่ง
+	done        chan struct{} // ปิดเมื่อ writeLoop ทำงานเสร็จ
+	bytesSent   atomic.Uint64
+	framesSent  atomic.Uint64
+}
+
+// สถานะล่าสุดของที่นั่งตามที่ server ส่งให้ clients
+type SeatState struct {
+	Speaker   Speaker   `json:"speaker"`   // ข้อมูลล่าสุดจาก API
+	MicOn     bool      `json:"micOn"`     // สถานะไมค์ที่ส่งให้ clients ล่าสุด
+	Present   bool      `json:"present"`   // อยู่ในผลลัพธ์จาก API รอบล่าสุดหรือไม่
+	ChangedAt time.Time `json:"changedAt"` // เวลาที่สถานะไมค์เปลี่ยนล่าสุด
+
+	talkTime time.Duration // เวลาเปิดไมค์สะสมก่อน ChangedAt
+}
+
+// เปลี่ยนสถานะไมค์และสะสมเวลาที่เปิดไมค์
+func (seat *SeatState) setMic(micOn bool, now time.Time) {
+	if seat.MicOn && !micOn {
+		seat.talkTime += now.Sub(seat.ChangedAt)
+	}
+	seat.MicOn = micOn
+	seat.ChangedAt = now
+}
+
+// เวลาเปิดไมค์สะสมทั้งหมด รวมช่วงที่ยังเปิดอยู่
+func (seat SeatState) TalkTime() time.Duration {
+	if seat.MicOn {
+		return seat.talkTime + time.Since(seat.ChangedAt)
+	}
+	return seat.talkTime
+}
+
+// การดึงสถานะไมค์จาก API ของ Bosch DCN
+type APIConfig struct {
+	URL  string // endpoint ของรายการ speakers
+	SID  string // ค่า header Bosch-Sid ของ session
+	Mock bool   // true = ใช้ข้อมูล mock แทน API จริง
+}
+
+// ผลลัพธ์การดึงข้อมูลจาก API รอบล่าสุด
+type PollResult struct {
+	Time       time.Time `json:"time"`
+	DurationMs float64   `json:"durationMs"`
+	Mock       bool      `json:"mock"`
+	Speakers   []Speaker `json:"speakers"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Server จัดการการเชื่อมต่อของ clients
+type Server struct {
+	clients    map[int]*Client
+	nextID     int
+	closed     bool
+	clientLock sync.Mutex
+
+	seats           map[int]*SeatState
+	lastSpeakers    []Speaker
+	lastPoll        PollResult
+	lastPollSuccess time.Time
+	stateLock       sync.Mutex
+
+	startedAt  time.Time
+	listenAddr string
+	listening  atomic.Bool
+	thresholds HealthThresholds
+	api        APIConfig
+
+	journal *journal.Journal // nil เมื่อไม่ได้เปิดการบันทึก journal
+	replay  *Replayer        // nil เมื่อทำงานแบบดึงข้อมูลจาก API
+}
+
+// สร้าง Server ใหม่
+func NewServer(listenAddr string, thresholds HealthThresholds, api APIConfig) *Server {
+	return &Server{
+		clients:    make(map[int]*Client),
+		nextID:     1,
+		seats:      make(map[int]*SeatState),
+		startedAt:  time.Now(),
+		listenAddr: listenAddr,
+		thresholds: thresholds,
+		api:        api,
+	}
+}
+
+// เพิ่ม client ใหม่ (คืนค่า nil ถ้า server กำลังปิด)
+func (s *Server) AddClient(conn net.Conn, transport string, filter protocol.FrameFilter) *Client {
+	s.clientLock.Lock()
+	defer s.clientLock.Unlock()
+
+	if s.closed {
+		conn.Close()
+		return nil
+	}
+
+	client := &Client{
+		conn:        conn,
+		id:          s.nextID,
+		transport:   transport,
+		filter:      filter,
+		connectedAt: time.Now(),
+		send:        make(chan []byte, SEND_QUEUE_SIZE),
+		done:        make(chan struct{}),
+	}
+	s.clients[s.nextID] = client
+	s.nextID++
+
+	go s.writeLoop(client)
+
+	logClients.Info("client.connected", "id", client.id, "addr", conn.RemoteAddr().String(), "transport", transport)
+	return client
+}
+
+// ลบ client (คืนค่า false ถ้าไม่พบ client)
+func (s *Server) RemoveClient(id int) bool {
+	s.clientLock.Lock()
+	defer s.clientLock.Unlock()
+
+	client, exists := s.clients[id]
+	if !exists {
+		return false
+	}
+
+	logClients.Info("client.disconnected", "id", id, "addr", client.conn.RemoteAddr().String())
+	close(client.send)
+	client.conn.Close()
+	delete(s.clients, id)
+	return true
+}
+
+// ส่งข้อมูลในคิวไปยัง client ทีละ frame จนกว่าคิวจะถูกปิด
+func (s *Server) writeLoop(client *Client) {
+	defer close(client.done)
+
+	for data := range client.send {
+		n, err := client.conn.Write(data)
+		client.bytesSent.Add(uint64(n))
+		bytesSent.Add(float64(n))
+		if err != nil {
+			logClients.Warn("client.write_failed", "id", client.id, "error", err)
+			writeErrors.Inc()
+			clientEvictions.Inc("write_error")
+			go s.RemoveClient(client.id)
+			// อ่านคิวให้หมดเพื่อไม่ให้ Broadcast ค้าง
+			for range client.send {
+			}
+			return
+		}
+		client.framesSent.Add(1)
+	}
+}
+
+// ส่งข้อมูลไปยังทุก clients
+func (s *Server) Broadcast(data []byte) {
+	s.clientLock.Lock()
+	defer s.clientLock.Unlock()
+
+	topic := protocol.FrameTopic(data)
+	framesBroadcast.Inc(strconv.Itoa(int(topic)))
+	logFraming.Debug("frame.broadcast", "topic", topic, "length", len(data)-8, "clients", len(s.clients))
+
+	if s.journal != nil {
+		if _, err := s.journal.Append(journal.DIR_SENT, topic, data[8:], time.Now()); err != nil {
+			logJournal.Warn("journal.write_failed", "topic", topic, "error", err)
+			journalErrors.Inc()
+		}
+	}
+
+	disconnectedClients := []int{}
+
+	var seatID string // อ่านครั้งเดียวเมื่อมี client ที่กรองตามที่นั่ง
+	for id, client := range s.clients {
+		if client.filter.Seats != nil && topic == protocol.TOPIC_SEAT && seatID == "" {
+			seatID, _ = protocol.SeatIDFromPayload(data[protocol.HEADER_SIZE:])
+		}
+		if !client.filter.Match(data, seatID) {
+			continue
+		}
+		select {
+		case client.send <- data:
+		default:
+			logClients.Warn("client.queue_full", "id", id)
+			clientEvictions.Inc("queue_full")
+			disconnectedClients = append(disconnectedClients, id)
+		}
+	}
+
+	// ลบ clients ที่ยกเลิกการเชื่อมต่อ
+	for _, id := range disconnectedClients {
+		go s.RemoveClient(id)
+	}
+}
+
+// ปิด server: รอส่งข้อมูลที่ค้างในคิวจนกว่า ctx จะหมดเวลา แล้วปิดการเชื่อมต่อทั้งหมด
+func (s *Server) Shutdown(ctx context.Context) {
+	s.clientLock.Lock()
+	s.closed = true
+	clients := make([]*Client, 0, len(s.clients))
+	for id, client := range s.clients {
+		close(client.send)
+		clients = append(clients, client)
+		delete(s.clients, id)
+	}
+	s.clientLock.Unlock()
+
+	for _, client := range clients {
+		select {
+		case <-client.done:
+		case <-ctx.Done():
+			logClients.Warn("client.drain_timeout", "id", client.id, "queued", len(client.send))
+		}
+		if ws, ok := client.conn.(*websocket.Conn); ok {
+			ws.CloseWithStatus(websocket.CLOSE_GOING_AWAY, "shutting down")
+		} else {
+			client.conn.Close()
+		}
+	}
+}
+
+// ส่ง snapshot ของสถานะปัจจุบันให้ client ที่เพิ่งเชื่อมต่อ
+//
+// โหมด replay ส่ง frame ล่าสุดจาก journal ส่วนโหมดปกติส่ง SeatActivity ทุกที่นั่งและ DiscussionActivity ล่าสุด
+func (s *Server) sendSnapshot(client *Client) {
+	send := func(frame []byte) {
+		if !client.filter.Match(frame, "") {
+			return
+		}
+		s.clientLock.Lock()
+		defer s.clientLock.Unlock()
+
+		// client อาจถูกลบไปแล้วระหว่างนี้
+		if s.clients[client.id] != client {
+			return
+		}
+		select {
+		case client.send <- frame:
+		default:
+		}
+	}
+
+	if s.replay != nil {
+		s.replay.Snapshot(send)
+		return
+	}
+
+	s.stateLock.Lock()
+	defer s.stateLock.Unlock()
+
+	// ยังไม่เคยได้ข้อมูลจาก API
+	if len(s.seats) == 0 && s.lastSpeakers == nil {
+		return
+	}
+	for _, id := range s.seatIDs() {
+		seat := s.seats[id]
+		send(protocol.BuildFrame(protocol.TOPIC_SEAT, generateSeatXML(seat.Speaker, seat.MicOn)))
+	}
+	send(protocol.BuildFrame(protocol.TOPIC_DISCUSSION, generateDiscussionXML(s.lastSpeakers)))
+}
+
+// ฟังก์ชันจำลองข้อมูล API
+func getMockSpeakers() ([]Speaker, error) {
+	// สลับสถานะไมค์ทุก 5 วินาที
+	mockLock.Lock()
+	defer mockLock.Unlock()
+
+	if !mockManual && time.Since(lastToggle) >= 5*time.Second {
+		mockMicState = !mockMicState
+		lastToggle = time.Now()
+		logUpstream.Info("mock.toggled", "mic_on", mockMicState)
+	}
+
+	return []Speaker{
+		{
+			ID:            3539,
+			Name:          "A05",
+			SeatName:      "A05",
+			Prio:          false,
+			PrioOn:        false,
+			ParticipantID: 0,
+			MicOn:         mockMicState,
+		},
+	}, nil
+}
+
+// กำหนดสถานะไมค์ของ mock เอง (หยุดการสลับอัตโนมัติจนกว่าจะเรียก resumeMockToggle)
+func setMockMicState(micOn bool) {
+	mockLock.Lock()
+	defer mockLock.Unlock()
+
+	mockMicState = micOn
+	mockManual = true
+	logUpstream.Info("mock.set", "mic_on", micOn)
+}
+
+// กลับไปสลับสถานะไมค์ของ mock อัตโนมัติ
+func resumeMockToggle() {
+	mockLock.Lock()
+	defer mockLock.Unlock()
+
+	mockManual = false
+	lastToggle = time.Now()
+}
+
+// ฟังก์ชันดึงข้อมูล (เลือกระหว่าง API จริงหรือ mock)
+func (s *Server) getSpeakers(ctx context.Context) ([]Speaker, error) {
+	if s.api.Mock {
+		return getMockSpeakers()
+	}
+
+	// สร้าง request ใหม่
+	req, err := http.NewRequestWithContext(ctx, "GET", s.api.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", logging.Msg("upstream.request"), err)
+	}
+
+	// เพิ่ม Header สำหรับการตรวจสอบสิทธิ์
+	req.Header.Set("Bosch-Sid", s.api.SID)
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", logging.Msg("upstream.connect"), err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", logging.Msg("upstream.read"), err)
+	}
+
+	var speakers []Speaker
+	if err := json.Unmarshal(body, &speakers); err != nil {
+		return nil, fmt.Errorf("%s: %w", logging.Msg("upstream.decode"), err)
+	}
+
+	return speakers, nil
+}
+
+// แปลง Speaker จาก API เป็นข้อมูลที่นั่งสำหรับสร้าง XML
+func protocolSeat(speaker Speaker, micOn bool) protocol.Seat {
+	return protocol.Seat{ID: speaker.ID, Name: speaker.SeatName, ParticipantID: speaker.ParticipantID, MicOn: micOn}
+}
+
+// สร้าง payload DiscussionActivity จากรายการ speakers (เฉพาะที่เปิดไมค์อยู่ใน ActiveList)
+func generateDiscussionXML(speakers []Speaker) []byte {
+	seats := make([]protocol.Seat, 0, len(speakers))
+	for _, speaker := range speakers {
+		seats = append(seats, protocolSeat(speaker, speaker.MicOn))
+	}
+	return protocol.EncodeUTF16LE(protocol.DiscussionActivityXML(seats, time.Now()))
+}
+
+// สร้าง payload SeatActivity ของที่นั่งหนึ่งที่ด้วยสถานะไมค์ที่กำหนด
+func generateSeatXML(speaker Speaker, micState bool) []byte {
+	return protocol.EncodeUTF16LE(protocol.SeatActivityXML(protocolSeat(speaker, micState), time.Now()))
+}
+
+// ฟังก์ชันดึงข้อมูลจาก API และส่งไปยัง clients จนกว่า ctx จะถูกยกเลิก
+func (s *Server) ProcessAndBroadcast(ctx context.Context) {
+	for {
+		start := time.Now()
+		speakers, err := s.getSpeakers(ctx)
+		if err != nil && ctx.Err() != nil {
+			s.broadcastFinalState()
+			return
+		}
+		s.recordPoll(start, speakers, err)
+
+		if err != nil {
+			logUpstream.Warn("upstream.poll_failed", "error", err)
+			logFraming.Debug("discussion.poll_empty")
+			// ส่ง XML ว่างเมื่อไม่มีข้อมูลจาก API
+			emptyXML := protocol.EncodeUTF16LE(fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?><DiscussionActivity xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:xsd="http://www.w3.org/2001/XMLSchema" Version="1" TimeStamp="%s" Topic="Discussion" Type="ActiveListUpdated"><Discussion Id="80"><ActiveList><Participants></Participants></ActiveList></Discussion></DiscussionActivity>`,
+				time.Now().Format(protocol.TIMESTAMP_LAYOUT)))
+
+			s.Broadcast(protocol.BuildFrame(protocol.TOPIC_DISCUSSION, emptyXML))
+		} else {
+			s.applySpeakers(speakers)
+		}
+
+		if !sleepContext(ctx, time.Second) {
+			s.broadcastFinalState()
+			return
+		}
+	}
+}
+
+// บันทึกผลลัพธ์การดึงข้อมูลรอบล่าสุด
+func (s *Server) recordPoll(start time.Time, speakers []Speaker, err error) {
+	s.stateLock.Lock()
+	defer s.stateLock.Unlock()
+
+	pollDuration.Observe(time.Since(start).Seconds())
+	if err != nil {
+		pollFailures.Inc()
+	}
+
+	s.lastPoll = PollResult{
+		Time:       start,
+		DurationMs: float64(time.Since(start).Microseconds()) / 1000,
+		Mock:       s.api.Mock,
+		Speakers:   speakers,
+	}
+	if err != nil {
+		s.lastPoll.Error = err.Error()
+	} else {
+		s.lastPollSuccess = start
+	}
+}
+
+// เปรียบเทียบข้อมูลใหม่กับสถานะเดิม แล้วส่งเฉพาะส่วนที่เปลี่ยนไปยัง clients
+func (s *Server) applySpeakers(speakers []Speaker) {
+	s.stateLock.Lock()
+	defer s.stateLock.Unlock()
+
+	now := time.Now()
+
+	// ตรวจสอบการเปลี่ยนแปลงของแต่ละที่นั่ง
+	currentSpeakerIDs := make(map[int]bool)
+	for _, speaker := range speakers {
+		currentSpeakerIDs[speaker.ID] = true
+
+		seat, exists := s.seats[speaker.ID]
+		if !exists {
+			seat = &SeatState{}
+			s.seats[speaker.ID] = seat
+		}
+		seat.Speaker = speaker
+		seat.Present = true
+
+		// ตรวจสอบการเปลี่ยนแปลงสถานะไมค์
+		if !exists || seat.MicOn != speaker.MicOn {
+			// ส่ง SeatActivity เมื่อสถานะเปลี่ยน
+			logUpstream.Info("seat.mic_changed", "seat_id", speaker.ID, "seat", speaker.SeatName, "mic_on", speaker.MicOn)
+			s.Broadcast(protocol.BuildFrame(protocol.TOPIC_SEAT, generateSeatXML(speaker, speaker.MicOn)))
+			seat.setMic(speaker.MicOn, now)
+		}
+	}
+
+	// ตรวจสอบที่นั่งที่หายไป ส่งข้อมูลเดิมแต่ปิดไมค์
+	for id, seat := range s.seats {
+		if currentSpeakerIDs[id] {
+			continue
+		}
+		seat.Present = false
+		if seat.MicOn {
+			logUpstream.Info("seat.mic_changed", "seat_id", id, "seat", seat.Speaker.SeatName, "mic_on", false)
+			s.Broadcast(protocol.BuildFrame(protocol.TOPIC_SEAT, generateSeatXML(seat.Speaker, false)))
+			seat.setMic(false, now)
+		}
+	}
+
+	// ส่ง DiscussionActivity เมื่อรายการที่นั่งเปลี่ยน
+	if !reflect.DeepEqual(speakers, s.lastSpeakers) {
+		logUpstream.Info("discussion.changed", "active", activeSeatNames(speakers))
+		s.Broadcast(protocol.BuildFrame(protocol.TOPIC_DISCUSSION, generateDiscussionXML(speakers)))
+		s.lastSpeakers = speakers
+	}
+}
+
+// ชื่อที่นั่งที่เปิดไมค์อยู่ สำหรับเขียน log
+func activeSeatNames(speakers []Speaker) []string {
+	names := []string{}
+	for _, speaker := range speakers {
+		if speaker.MicOn {
+			names = append(names, speaker.SeatName)
+		}
+	}
+	return names
+}
+
+// ส่งสถานะปัจจุบันทั้งหมด (SeatActivity ทุกที่นั่ง และ DiscussionActivity) ไปยัง clients
+func (s *Server) BroadcastSnapshot() {
+	s.stateLock.Lock()
+	defer s.stateLock.Unlock()
+
+	for _, id := range s.seatIDs() {
+		seat := s.seats[id]
+		s.Broadcast(protocol.BuildFrame(protocol.TOPIC_SEAT, generateSeatXML(seat.Speaker, seat.MicOn)))
+	}
+	s.Broadcast(protocol.BuildFrame(protocol.TOPIC_DISCUSSION, generateDiscussionXML(s.lastSpeakers)))
+}
+
+// รายการ id ของที่นั่งเรียงจากน้อยไปมาก (ต้องถือ stateLock อยู่)
+func (s *Server) seatIDs() []int {
+	ids := make([]int, 0, len(s.seats))
+	for id := range s.seats {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// ส่งสถานะสุดท้ายก่อนปิด server: ปิดไมค์ทุกที่นั่งที่ยังเปิดอยู่และส่ง ActiveList ว่าง
+func (s *Server) broadcastFinalState() {
+	s.stateLock.Lock()
+	defer s.stateLock.Unlock()
+
+	logMain.Info("server.final_state")
+
+	for _, id := range s.seatIDs() {
+		seat := s.seats[id]
+		if seat.MicOn {
+			s.Broadcast(protocol.BuildFrame(protocol.TOPIC_SEAT, generateSeatXML(seat.Speaker, false)))
+			seat.setMic(false, time.Now())
+		}
+	}
+	s.Broadcast(protocol.BuildFrame(protocol.TOPIC_DISCUSSION, generateDiscussionXML(nil)))
+}
+
+// รอตามเวลาที่กำหนด คืนค่า false ถ้า ctx ถูกยกเลิกก่อน
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// จัดการการเชื่อมต่อจาก client
+func handleClientConnection(server *Server, conn net.Conn) {
+	client := server.AddClient(conn, TRANSPORT_TCP, protocol.FrameFilter{})
+	if client == nil {
+		return
+	}
+	defer server.RemoveClient(client.id)
+	server.sendSnapshot(client)
+
+	// รอจนกว่า client จะยกเลิกการเชื่อมต่อ
+	buffer := make([]byte, 1024)
+	for {
+		_, err := conn.Read(buffer)
+		if err != nil {
+			return
+		}
+	}
+}
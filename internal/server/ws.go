@@ -0,0 +1,68 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ampol-me/phi-DCN/internal/websocket"
+	"github.com/ampol-me/phi-DCN/protocol"
+)
+
+const (
+	WS_PING_INTERVAL = 30 // ระยะห่างการส่ง ping ไปยัง WebSocket client (วินาที)
+	WS_READ_TIMEOUT  = 75 // ตัดการเชื่อมต่อถ้าไม่ได้รับ frame ใด ๆ รวมถึง pong ภายในเวลานี้ (วินาที)
+)
+
+// handler ของ WebSocket listener: GET /ws/frames
+func (s *Server) WSHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /ws/frames", s.wsFramesHandler)
+	return mux
+}
+
+// GET /ws/frames: ส่ง frame DCN (header 8 bytes + payload) เหมือน TCP listener ทุก byte
+// โดยหนึ่ง frame เป็นหนึ่งข้อความ binary
+//
+// เลือก frame ที่ต้องการได้ด้วย query ?seats=7,12&topics=seat,discussion
+func (s *Server) wsFramesHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter, err := protocol.NewFrameFilter(splitList(query.Get("seats")), splitList(query.Get("topics")))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	conn, err := websocket.Upgrade(w, r)
+	if err != nil {
+		logClients.Debug("client.upgrade_failed", "addr", r.RemoteAddr, "error", err)
+		return
+	}
+	conn.ReadTimeout = WS_READ_TIMEOUT * time.Second
+
+	client := s.AddClient(conn, TRANSPORT_WEBSOCKET, filter)
+	if client == nil {
+		return
+	}
+	defer s.RemoveClient(client.id)
+	go conn.KeepAlive(WS_PING_INTERVAL*time.Second, client.done)
+	s.sendSnapshot(client)
+
+	// ข้อความจาก client ไม่มีความหมาย อ่านทิ้งจนกว่าจะยกเลิกการเชื่อมต่อ
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+// แยกรายการที่คั่นด้วย comma และตัดช่องว่าง
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
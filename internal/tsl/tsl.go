@@ -0,0 +1,269 @@
+// Package tsl สร้างและส่ง packet ของ TSL UMD (Under Monitor Display) protocol
+// สำหรับป้ายชื่อและ tally บน multiviewer และ switcher
+//
+// รองรับ v3.1 (18 bytes ต่อ display ข้อความ ASCII 16 ตัวอักษร) และ v5.0 (ข้อความยาวได้และเป็น UTF-16LE ได้)
+// ผ่าน UDP (หนึ่ง packet ต่อ datagram) หรือ TCP (v5.0 ห่อด้วย DLE/STX ตามข้อกำหนด)
+package tsl
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+	"unicode/utf16"
+)
+
+// เวอร์ชันของ protocol และชนิดการส่ง
+const (
+	VERSION_31 = "v3.1"
+	VERSION_50 = "v5"
+
+	TRANSPORT_UDP = "udp"
+	TRANSPORT_TCP = "tcp"
+)
+
+// สีของ tally (v5.0) สำหรับ v3.1 สีแดงคือ tally 1 สีเขียวคือ tally 2 และสีเหลืองคือทั้งสองดวง
+const (
+	TALLY_OFF   = 0
+	TALLY_RED   = 1
+	TALLY_GREEN = 2
+	TALLY_AMBER = 3
+)
+
+const (
+	V31_MAX_INDEX   = 126   // address ของ v3.1 คือ 0-126
+	V31_TEXT_LENGTH = 16    // ข้อความของ v3.1 ยาว 16 ตัวอักษรเสมอ
+	V50_MAX_INDEX   = 65534 // 0xFFFF คือ broadcast
+	V50_MAX_PACKET  = 2048  // ขนาด packet สูงสุดของ v5.0
+
+	V50_FLAG_UNICODE = 0x01 // ข้อความเป็น UTF-16LE
+	V50_DLE          = 0xFE
+	V50_STX          = 0x02
+
+	DIAL_TIMEOUT  = 5 * time.Second
+	WRITE_TIMEOUT = 2 * time.Second
+)
+
+// สถานะของ display หนึ่งตัว
+type Display struct {
+	Index      int    // v3.1: 0-126, v5.0: 0-65534
+	Text       string //
+	Tally      int    // TALLY_OFF, TALLY_RED, TALLY_GREEN หรือ TALLY_AMBER
+	Brightness int    // 0-3
+}
+
+// สร้าง packet v3.1: address+0x80, control (tally 1-4 ที่ bit 0-3 และ brightness ที่ bit 4-5)
+// และข้อความ ASCII 16 ตัวอักษร (ตัวอักษรอื่นแทนด้วย ?)
+func EncodeV31(d Display) []byte {
+	packet := make([]byte, 2+V31_TEXT_LENGTH)
+	packet[0] = 0x80 + byte(d.Index)
+	packet[1] = byte(d.Tally&0x03) | byte(d.Brightness&0x03)<<4
+	for i := range V31_TEXT_LENGTH {
+		packet[2+i] = ' '
+	}
+	i := 0
+	for _, r := range d.Text {
+		if i == V31_TEXT_LENGTH {
+			break
+		}
+		if r < 0x20 || r > 0x7E {
+			r = '?'
+		}
+		packet[2+i] = byte(r)
+		i++
+	}
+	return packet
+}
+
+// สร้าง packet v5.0 ที่มี display message เดียว
+//
+//	PBC u16, VER u8, FLAGS u8, SCREEN u16, INDEX u16, CONTROL u16, LENGTH u16, TEXT (little-endian ทั้งหมด)
+//
+// CONTROL: RH tally ที่ bit 0-1, text tally ที่ bit 2-3, LH tally ที่ bit 4-5 และ brightness ที่ bit 6-7
+// ข้อความที่มีตัวอักษรนอก ASCII ส่งเป็น UTF-16LE
+func EncodeV5(screen int, d Display) []byte {
+	var flags byte
+	var text []byte
+	if isASCII(d.Text) {
+		text = []byte(d.Text)
+	} else {
+		flags |= V50_FLAG_UNICODE
+		for _, unit := range utf16.Encode([]rune(d.Text)) {
+			text = binary.LittleEndian.AppendUint16(text, unit)
+		}
+	}
+	if limit := V50_MAX_PACKET - 12; len(text) > limit {
+		text = text[:limit&^1]
+	}
+
+	tally := uint16(d.Tally & 0x03)
+	control := tally | tally<<2 | tally<<4 | uint16(d.Brightness&0x03)<<6
+
+	packet := make([]byte, 0, 12+len(text))
+	packet = binary.LittleEndian.AppendUint16(packet, uint16(10+len(text))) // PBC ไม่นับตัวเอง
+	packet = append(packet, 0, flags)
+	packet = binary.LittleEndian.AppendUint16(packet, uint16(screen))
+	packet = binary.LittleEndian.AppendUint16(packet, uint16(d.Index))
+	packet = binary.LittleEndian.AppendUint16(packet, control)
+	packet = binary.LittleEndian.AppendUint16(packet, uint16(len(text)))
+	return append(packet, text...)
+}
+
+// ห่อ packet v5.0 สำหรับ TCP: ขึ้นต้นด้วย DLE STX และส่ง DLE ใน packet ซ้ำสองครั้ง
+func WrapV5(packet []byte) []byte {
+	wrapped := make([]byte, 0, len(packet)+4)
+	wrapped = append(wrapped, V50_DLE, V50_STX)
+	for _, b := range packet {
+		if b == V50_DLE {
+			wrapped = append(wrapped, V50_DLE)
+		}
+		wrapped = append(wrapped, b)
+	}
+	return wrapped
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+// ปลายทางหนึ่งแห่ง
+type Options struct {
+	Version   string // VERSION_31 หรือ VERSION_50
+	Transport string // TRANSPORT_UDP หรือ TRANSPORT_TCP
+	Addr      string // host:port
+	Screen    int    // v5.0: screen index
+}
+
+// ตรวจสอบ Options และช่วงของ index
+func (o Options) Validate() error {
+	if o.Version != VERSION_31 && o.Version != VERSION_50 {
+		return fmt.Errorf("tsl: ไม่รู้จัก version %q (ใช้ %s หรือ %s)", o.Version, VERSION_31, VERSION_50)
+	}
+	if o.Transport != TRANSPORT_UDP && o.Transport != TRANSPORT_TCP {
+		return fmt.Errorf("tsl: ไม่รู้จัก transport %q (ใช้ udp หรือ tcp)", o.Transport)
+	}
+	if _, _, err := net.SplitHostPort(o.Addr); err != nil {
+		return fmt.Errorf("tsl: addr %q ต้องเป็น host:port", o.Addr)
+	}
+	if o.Screen < 0 || o.Screen > V50_MAX_INDEX {
+		return fmt.Errorf("tsl: screen %d อยู่นอกช่วง 0-%d", o.Screen, V50_MAX_INDEX)
+	}
+	return nil
+}
+
+// index สูงสุดของ version
+func (o Options) MaxIndex() int {
+	if o.Version == VERSION_31 {
+		return V31_MAX_INDEX
+	}
+	return V50_MAX_INDEX
+}
+
+// Sender ส่ง display ไปยังปลายทางหนึ่งแห่ง เชื่อมต่อเมื่อส่งครั้งแรกและเชื่อมต่อใหม่เมื่อส่งไม่สำเร็จ
+type Sender struct {
+	opts Options
+
+	mu      sync.Mutex
+	conn    net.Conn     // TCP
+	udp     *net.UDPConn // UDP
+	udpAddr *net.UDPAddr
+}
+
+func NewSender(opts Options) *Sender {
+	return &Sender{opts: opts}
+}
+
+func (s *Sender) Addr() string {
+	return s.opts.Addr
+}
+
+// ส่ง display ทั้งหมด (หนึ่ง packet ต่อ display)
+func (s *Sender) Send(displays []Display) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.open(); err != nil {
+		return err
+	}
+
+	for _, d := range displays {
+		var packet []byte
+		switch {
+		case s.opts.Version == VERSION_31:
+			packet = EncodeV31(d)
+		case s.opts.Transport == TRANSPORT_TCP:
+			packet = WrapV5(EncodeV5(s.opts.Screen, d))
+		default:
+			packet = EncodeV5(s.opts.Screen, d)
+		}
+		if err := s.write(packet); err != nil {
+			// เชื่อมต่อใหม่ในครั้งถัดไป
+			s.closeLocked()
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Sender) open() error {
+	if s.conn != nil || s.udp != nil {
+		return nil
+	}
+	if s.opts.Transport == TRANSPORT_TCP {
+		conn, err := net.DialTimeout(s.opts.Transport, s.opts.Addr, DIAL_TIMEOUT)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+		return nil
+	}
+
+	// ใช้ socket ที่ไม่ได้ connect เพราะ ICMP port unreachable จาก receiver ที่ยังไม่เปิด
+	// จะทำให้การส่งครั้งถัดไปของ socket ที่ connect แล้วล้มเหลวสลับกันไป
+	udpAddr, err := net.ResolveUDPAddr("udp", s.opts.Addr)
+	if err != nil {
+		return err
+	}
+	conn, err := net.ListenUDP("udp", nil)
+	if err != nil {
+		return err
+	}
+	s.udp, s.udpAddr = conn, udpAddr
+	return nil
+}
+
+func (s *Sender) write(packet []byte) error {
+	if s.udp != nil {
+		s.udp.SetWriteDeadline(time.Now().Add(WRITE_TIMEOUT))
+		_, err := s.udp.WriteToUDP(packet, s.udpAddr)
+		return err
+	}
+	s.conn.SetWriteDeadline(time.Now().Add(WRITE_TIMEOUT))
+	_, err := s.conn.Write(packet)
+	return err
+}
+
+func (s *Sender) closeLocked() error {
+	var err error
+	if s.conn != nil {
+		err = s.conn.Close()
+		s.conn = nil
+	}
+	if s.udp != nil {
+		err = s.udp.Close()
+		s.udp, s.udpAddr = nil, nil
+	}
+	return err
+}
+
+func (s *Sender) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closeLocked()
+}
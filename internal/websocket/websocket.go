@@ -0,0 +1,321 @@
+// Package websocket เป็น WebSocket (RFC 6455) ฝั่ง server เท่าที่ proxy และ server ต้องใช้
+//
+// รองรับ handshake, ข้อความ text/binary (รวม fragment), ping/pong และ close
+// ไม่รองรับ extension (เช่น permessage-deflate) และ subprotocol
+package websocket
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// opcode ของ frame
+const (
+	OP_CONTINUATION = 0x0
+	OP_TEXT         = 0x1
+	OP_BINARY       = 0x2
+	OP_CLOSE        = 0x8
+	OP_PING         = 0x9
+	OP_PONG         = 0xA
+)
+
+// รหัสสถานะของ close frame
+const (
+	CLOSE_NORMAL     = 1000
+	CLOSE_GOING_AWAY = 1001 // server กำลังปิด
+	CLOSE_POLICY     = 1008 // client ส่งข้อความที่ไม่ถูกต้อง
+	CLOSE_TRY_AGAIN  = 1013 // client รับข้อมูลไม่ทัน
+)
+
+// ขนาดข้อความสูงสุดที่รับจาก client (ข้อความจาก browser มีแค่คำสั่งสั้น ๆ)
+const MAX_MESSAGE_SIZE = 64 * 1024
+
+// GUID สำหรับคำนวณ Sec-WebSocket-Accept
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+var (
+	ErrNotWebSocket    = errors.New("websocket: request ไม่ใช่ WebSocket handshake")
+	ErrProtocol        = errors.New("websocket: frame ไม่ถูกต้องตาม protocol")
+	ErrMessageTooLarge = errors.New("websocket: ข้อความยาวเกินกำหนด")
+)
+
+// Conn คือการเชื่อมต่อ WebSocket ฝั่ง server
+//
+// ReadMessage เรียกได้จาก goroutine เดียว ส่วน WriteMessage เรียกพร้อมกันจากหลาย goroutine ได้
+//
+// Conn ใช้แทน net.Conn ได้: Write ส่งข้อมูลแต่ละครั้งเป็นข้อความ binary หนึ่งข้อความ
+// และ Read อ่านข้อมูลของข้อความที่ client ส่งมาต่อกันเป็น stream
+type Conn struct {
+	conn    net.Conn
+	br      *bufio.Reader
+	wmu     sync.Mutex
+	pending []byte // ข้อมูลของข้อความล่าสุดที่ Read ยังอ่านไม่หมด
+
+	ReadTimeout time.Duration // เวลารอ frame ถัดไปสูงสุด (รวม pong) 0 = ไม่จำกัด
+}
+
+// ตรวจสอบ handshake และเปลี่ยน HTTP request เป็นการเชื่อมต่อ WebSocket
+//
+// ถ้า request ไม่ใช่ WebSocket handshake จะตอบ 400 และคืนค่า ErrNotWebSocket
+func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if r.Method != http.MethodGet ||
+		!headerContains(r.Header, "Connection", "upgrade") ||
+		!headerContains(r.Header, "Upgrade", "websocket") ||
+		r.Header.Get("Sec-WebSocket-Version") != "13" ||
+		key == "" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "ต้องเชื่อมต่อด้วย WebSocket", http.StatusBadRequest)
+		return nil, ErrNotWebSocket
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "ไม่รองรับ WebSocket", http.StatusInternalServerError)
+		return nil, errors.New("websocket: ResponseWriter ไม่รองรับ Hijack")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n\r\n"
+	if _, err := conn.Write([]byte(response)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &Conn{conn: conn, br: rw.Reader}, nil
+}
+
+// ค่า Sec-WebSocket-Accept ของ key ที่ client ส่งมา
+func acceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// ตรวจว่า header มี token ที่ต้องการหรือไม่ (ไม่สนตัวพิมพ์เล็ก/ใหญ่)
+func headerContains(h http.Header, name, token string) bool {
+	for _, value := range h.Values(name) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ที่อยู่ของ client
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// ที่อยู่ฝั่ง server
+func (c *Conn) LocalAddr() net.Addr {
+	return c.conn.LocalAddr()
+}
+
+// กำหนด deadline ของการอ่านและการเขียน
+func (c *Conn) SetDeadline(t time.Time) error {
+	return c.conn.SetDeadline(t)
+}
+
+// กำหนด deadline ของการอ่าน (ReadTimeout จะกำหนดใหม่ทุกครั้งที่รอ frame ถ้าไม่เป็น 0)
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// กำหนด deadline ของการเขียน
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	return c.conn.SetWriteDeadline(t)
+}
+
+// ส่งข้อมูลเป็นข้อความ binary หนึ่งข้อความ
+func (c *Conn) Write(p []byte) (int, error) {
+	if err := c.WriteMessage(OP_BINARY, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// อ่านข้อมูลของข้อความ text หรือ binary ที่ client ส่งมา
+func (c *Conn) Read(p []byte) (int, error) {
+	for len(c.pending) == 0 {
+		_, data, err := c.ReadMessage()
+		if err != nil {
+			return 0, err
+		}
+		c.pending = data
+	}
+	n := copy(p, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
+
+// ส่งข้อความหนึ่งข้อความเป็น frame เดียว (server ไม่ต้อง mask ข้อมูล)
+func (c *Conn) WriteMessage(opcode byte, data []byte) error {
+	header := make([]byte, 2, 10)
+	header[0] = 0x80 | opcode // FIN
+	switch {
+	case len(data) < 126:
+		header[1] = byte(len(data))
+	case len(data) <= 0xFFFF:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(len(data)))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(len(data)))
+	}
+
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	_, err := (&net.Buffers{header, data}).WriteTo(c.conn)
+	return err
+}
+
+// ส่ง ping ไปยัง client
+func (c *Conn) Ping() error {
+	return c.WriteMessage(OP_PING, nil)
+}
+
+// ส่ง ping ทุก interval จนกว่า stop จะถูกปิดหรือส่งไม่สำเร็จ
+func (c *Conn) KeepAlive(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := c.Ping(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// รับข้อความ text หรือ binary ถัดไป
+//
+// ตอบ ping ด้วย pong ให้อัตโนมัติ และคืนค่า io.EOF เมื่อ client ส่ง close frame
+func (c *Conn) ReadMessage() (opcode byte, data []byte, err error) {
+	var message []byte
+	var messageOp byte
+	for {
+		if c.ReadTimeout > 0 {
+			c.conn.SetReadDeadline(time.Now().Add(c.ReadTimeout))
+		}
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch op {
+		case OP_PING:
+			if err := c.WriteMessage(OP_PONG, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case OP_PONG:
+			continue
+		case OP_CLOSE:
+			c.WriteMessage(OP_CLOSE, payload[:min(len(payload), 2)])
+			return 0, nil, io.EOF
+		case OP_TEXT, OP_BINARY:
+			if messageOp != 0 {
+				return 0, nil, ErrProtocol
+			}
+			messageOp = op
+		case OP_CONTINUATION:
+			if messageOp == 0 {
+				return 0, nil, ErrProtocol
+			}
+		default:
+			return 0, nil, ErrProtocol
+		}
+
+		if len(message)+len(payload) > MAX_MESSAGE_SIZE {
+			return 0, nil, ErrMessageTooLarge
+		}
+		message = append(message, payload...)
+		if fin {
+			return messageOp, message, nil
+		}
+	}
+}
+
+// อ่าน frame หนึ่ง frame และถอด mask
+func (c *Conn) readFrame() (fin bool, opcode byte, payload []byte, err error) {
+	var head [2]byte
+	if _, err := io.ReadFull(c.br, head[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin = head[0]&0x80 != 0
+	opcode = head[0] & 0x0F
+	if head[0]&0x70 != 0 || head[1]&0x80 == 0 {
+		// ไม่รองรับ extension และ frame จาก client ต้อง mask เสมอ
+		return false, 0, nil, ErrProtocol
+	}
+
+	length := uint64(head[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if opcode >= OP_CLOSE && (length > 125 || !fin) {
+		return false, 0, nil, ErrProtocol
+	}
+	if length > MAX_MESSAGE_SIZE {
+		return false, 0, nil, ErrMessageTooLarge
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return fin, opcode, payload, nil
+}
+
+// ส่ง close frame พร้อมรหัสสถานะแล้วปิดการเชื่อมต่อ
+func (c *Conn) CloseWithStatus(status uint16, reason string) error {
+	payload := binary.BigEndian.AppendUint16(nil, status)
+	payload = append(payload, reason[:min(len(reason), 123)]...)
+	c.conn.SetWriteDeadline(time.Now().Add(time.Second))
+	c.WriteMessage(OP_CLOSE, payload)
+	return c.conn.Close()
+}
+
+// ปิดการเชื่อมต่อตามปกติ (status 1000)
+func (c *Conn) Close() error {
+	return c.CloseWithStatus(1000, "")
+}
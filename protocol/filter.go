@@ -0,0 +1,73 @@
+package protocol
+
+import (
+	"fmt"
+	"strings"
+)
+
+// แปลงชื่อ topic (seat, discussion, ชื่อเต็ม หรือหมายเลข) เป็นหมายเลข topic
+func ParseTopic(name string) (uint32, error) {
+	switch strings.ToLower(name) {
+	case "seat", "seatactivity", "5":
+		return TOPIC_SEAT, nil
+	case "discussion", "discussionactivity", "3":
+		return TOPIC_DISCUSSION, nil
+	}
+	return 0, fmt.Errorf("ไม่รู้จัก topic %q (ใช้ seat หรือ discussion)", name)
+}
+
+// FrameFilter เลือก frame ที่จะส่งให้ client ตาม topic และ Seat Id
+//
+// ค่าว่างของแต่ละเงื่อนไขหมายถึงไม่กรอง ส่วน DiscussionActivity ไม่ผูกกับที่นั่งจึงกรองด้วย topic เท่านั้น
+type FrameFilter struct {
+	Topics map[uint32]bool
+	Seats  map[string]bool
+}
+
+// สร้าง FrameFilter จากรายการ Seat Id และชื่อ topic
+func NewFrameFilter(seats, topics []string) (FrameFilter, error) {
+	var f FrameFilter
+	for _, id := range seats {
+		if f.Seats == nil {
+			f.Seats = make(map[string]bool)
+		}
+		f.Seats[id] = true
+	}
+	for _, name := range topics {
+		topic, err := ParseTopic(name)
+		if err != nil {
+			return FrameFilter{}, err
+		}
+		if f.Topics == nil {
+			f.Topics = make(map[uint32]bool)
+		}
+		f.Topics[topic] = true
+	}
+	return f, nil
+}
+
+// ไม่มีเงื่อนไขใด ๆ (ส่งทุก frame)
+func (f FrameFilter) IsEmpty() bool {
+	return f.Topics == nil && f.Seats == nil
+}
+
+// ตรวจว่า frame (header + payload) ผ่าน filter หรือไม่
+//
+// seatID คือ Seat Id ของ frame SeatActivity ถ้าผู้เรียกอ่านไว้แล้ว (ว่าง = อ่านจาก payload)
+func (f FrameFilter) Match(frame []byte, seatID string) bool {
+	topic := FrameTopic(frame)
+	if f.Topics != nil && !f.Topics[topic] {
+		return false
+	}
+	if f.Seats == nil || topic != TOPIC_SEAT {
+		return true
+	}
+	if seatID == "" {
+		id, err := SeatIDFromPayload(frame[HEADER_SIZE:])
+		if err != nil {
+			return false
+		}
+		seatID = id
+	}
+	return f.Seats[seatID]
+}
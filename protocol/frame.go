@@ -0,0 +1,95 @@
+// Package protocol เข้ารหัสและถอดรหัส frame ของ Bosch DCN
+//
+// frame หนึ่ง frame ประกอบด้วย header 8 bytes (topic uint32 และความยาว payload uint32
+// แบบ little endian) ตามด้วย payload XML ที่เข้ารหัสเป็น UTF-16LE พร้อม BOM หรือ UTF-8
+package protocol
+
+import "encoding/binary"
+
+// topic ของ DCN ที่รู้จัก
+const (
+	TOPIC_DISCUSSION = 3 // DiscussionActivity
+	TOPIC_SEAT       = 5 // SeatActivity
+)
+
+// ขนาด header ของ frame
+const HEADER_SIZE = 8
+
+// ชื่อของ topic สำหรับแสดงผล
+func TopicName(topic uint32) string {
+	switch topic {
+	case TOPIC_DISCUSSION:
+		return "DiscussionActivity"
+	case TOPIC_SEAT:
+		return "SeatActivity"
+	}
+	return "Unknown"
+}
+
+// สร้าง frame ที่มี header 8 bytes (topic + ความยาว) นำหน้า
+func BuildFrame(topic uint32, payload []byte) []byte {
+	frame := make([]byte, HEADER_SIZE, HEADER_SIZE+len(payload))
+	binary.LittleEndian.PutUint32(frame[0:4], topic)
+	binary.LittleEndian.PutUint32(frame[4:8], uint32(len(payload)))
+	return append(frame, payload...)
+}
+
+// topic ของ frame (frame ต้องยาวอย่างน้อย HEADER_SIZE)
+func FrameTopic(frame []byte) uint32 {
+	return binary.LittleEndian.Uint32(frame[0:4])
+}
+
+// FrameDecoder แยก frame DCN (header 8 bytes + payload) ออกจาก TCP stream
+//
+// ถ้า bytes ที่ตำแหน่งปัจจุบันไม่ใช่ header ของ topic ที่รู้จัก จะข้ามไปทีละ byte
+// จนกว่าจะพบ header ถัดไป ใช้ร่วมกันระหว่างการรับข้อมูลจาก upstream และการ import จากไฟล์ capture
+type FrameDecoder struct {
+	pending   []byte // ข้อมูลที่ยังไม่ครบ frame
+	resyncing bool   // true ระหว่างข้าม bytes เพื่อหา header ที่ถูกต้อง
+
+	ResyncEvents int // จำนวนครั้งที่หลุด sync
+	ResyncBytes  int // จำนวน bytes ที่ข้ามไปทั้งหมด
+}
+
+// เพิ่มข้อมูลใหม่และคืนค่า frame ที่ครบแล้วทั้งหมด (header + payload แต่ละ frame เป็นสำเนาของตัวเอง)
+func (d *FrameDecoder) Feed(data []byte) [][]byte {
+	// รวมข้อมูลที่เหลือจากรอบที่แล้วกับข้อมูลใหม่
+	buf := append(d.pending, data...)
+
+	var frames [][]byte
+	for len(buf) >= HEADER_SIZE { // ต้องมีอย่างน้อย 8 bytes สำหรับ header
+		if !IsFrameTopic(buf[0]) {
+			// ไม่ใช่ header ที่ถูกต้อง เลื่อนไป 1 byte
+			if !d.resyncing {
+				d.resyncing = true
+				d.ResyncEvents++
+			}
+			d.ResyncBytes++
+			buf = buf[1:]
+			continue
+		}
+
+		// ข้อมูลยังไม่ครบ frame เก็บไว้รอรอบหน้า
+		length := binary.LittleEndian.Uint32(buf[4:8])
+		if uint64(len(buf)) < HEADER_SIZE+uint64(length) {
+			break
+		}
+
+		d.resyncing = false
+		frames = append(frames, append([]byte(nil), buf[:HEADER_SIZE+length]...))
+		buf = buf[HEADER_SIZE+length:]
+	}
+
+	d.pending = buf
+	return frames
+}
+
+// จำนวน bytes ที่ค้างอยู่และยังไม่ครบ frame
+func (d *FrameDecoder) Pending() int {
+	return len(d.pending)
+}
+
+// ตรวจสอบ byte แรกของ header ว่าเป็น topic ที่รู้จักหรือไม่
+func IsFrameTopic(b byte) bool {
+	return b == TOPIC_SEAT || b == TOPIC_DISCUSSION
+}
@@ -0,0 +1,50 @@
+package protocol
+
+import "unicode/utf16"
+
+// การเข้ารหัสของ payload
+const (
+	ENCODING_UTF16LE = "utf-16le"
+	ENCODING_UTF8    = "utf-8"
+)
+
+// แปลง string เป็น UTF-16LE พร้อม BOM (รูปแบบเดียวกับ Bosch DCN)
+func EncodeUTF16LE(s string) []byte {
+	u16 := utf16.Encode([]rune(s))
+	b := make([]byte, 2+len(u16)*2)
+
+	// ใส่ BOM (0xFF 0xFE สำหรับ UTF-16LE)
+	b[0] = 0xFF
+	b[1] = 0xFE
+	for i, v := range u16 {
+		b[2+i*2] = byte(v)
+		b[2+i*2+1] = byte(v >> 8)
+	}
+	return b
+}
+
+// การเข้ารหัสของ payload ตาม BOM
+func PayloadEncoding(payload []byte) string {
+	if len(payload) >= 2 && payload[0] == 0xFF && payload[1] == 0xFE {
+		return ENCODING_UTF16LE
+	}
+	return ENCODING_UTF8
+}
+
+// แปลง payload ของ frame เป็น string โดยตรวจ BOM ของ UTF-16LE
+func DecodePayload(payload []byte) string {
+	if len(payload) > 2 && payload[0] == 0xFF && payload[1] == 0xFE {
+		// ข้าม BOM (2 bytes) และแปลงเป็น UTF-8
+		return DecodeUTF16LE(payload[2:])
+	}
+	return string(payload)
+}
+
+// แปลง bytes แบบ UTF-16LE (ไม่มี BOM) เป็น string
+func DecodeUTF16LE(b []byte) string {
+	words := make([]uint16, len(b)/2)
+	for i := range words {
+		words[i] = uint16(b[i*2]) | uint16(b[i*2+1])<<8
+	}
+	return string(utf16.Decode(words))
+}
@@ -0,0 +1,177 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// รูปแบบ TimeStamp ใน XML ของ DCN
+const TIMESTAMP_LAYOUT = "2006-01-02T15:04:05.0000000-07:00"
+
+// โครงสร้างข้อมูล XML
+type SeatActivity struct {
+	XMLName   xml.Name `xml:"SeatActivity"`
+	TimeStamp string   `xml:"TimeStamp,attr"`
+	Type      string   `xml:"Type,attr"`
+	Seat      struct {
+		ID       string `xml:"Id,attr"`
+		SeatData struct {
+			Name             string `xml:"Name,attr"`
+			MicrophoneActive bool   `xml:"MicrophoneActive,attr"`
+			SeatType         string `xml:"SeatType,attr"`
+		} `xml:"SeatData"`
+		Participant struct {
+			ID              string          `xml:"Id,attr"`
+			ParticipantData ParticipantData `xml:"ParticipantData"`
+		} `xml:"Participant"`
+	} `xml:"Seat"`
+}
+
+// ข้อมูลผู้เข้าร่วมประชุมที่ผูกกับที่นั่ง
+type ParticipantData struct {
+	Present                 bool   `xml:"Present,attr"`
+	MicrophoneAuthorisation bool   `xml:"MicrophoneAuthorisation,attr"`
+	FirstName               string `xml:"FirstName,attr"`
+	MiddleName              string `xml:"MiddleName,attr"`
+	LastName                string `xml:"LastName,attr"`
+	Title                   string `xml:"Title,attr"`
+	Country                 string `xml:"Country,attr"`
+	RemainingSpeechTime     int    `xml:"RemainingSpeechTime,attr"`
+}
+
+type DiscussionActivity struct {
+	XMLName    xml.Name `xml:"DiscussionActivity"`
+	TimeStamp  string   `xml:"TimeStamp,attr"`
+	Type       string   `xml:"Type,attr"`
+	Discussion struct {
+		ID         string `xml:"Id,attr"`
+		ActiveList struct {
+			Participants struct {
+				ParticipantContainers []struct {
+					ID   string `xml:"Id,attr"`
+					Seat struct {
+						ID       string `xml:"Id,attr"`
+						SeatData struct {
+							Name             string `xml:"Name,attr"`
+							MicrophoneActive bool   `xml:"MicrophoneActive,attr"`
+						} `xml:"SeatData"`
+					} `xml:"Seat"`
+				} `xml:"ParticipantContainer"`
+			} `xml:"Participants"`
+		} `xml:"ActiveList"`
+	} `xml:"Discussion"`
+}
+
+// แปลงค่า TimeStamp ใน XML ของ DCN เป็นเวลา
+//
+// DCN ส่ง TimeStamp ทั้งแบบมีเศษวินาทีและ timezone และแบบไม่มี timezone
+// แบบหลังถือว่าเป็นเวลาท้องถิ่นของเครื่องที่รันอยู่
+func ParseTimestamp(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02T15:04:05.999999999", s, time.Local)
+}
+
+// ข้อมูลที่นั่งสำหรับสร้าง XML
+type Seat struct {
+	ID            int
+	Name          string
+	ParticipantID int
+	MicOn         bool
+}
+
+// สร้าง XML ของ SeatActivity
+func SeatActivityXML(seat Seat, t time.Time) string {
+	return fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?><SeatActivity xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:xsd="http://www.w3.org/2001/XMLSchema" Version="1" TimeStamp="%s" Topic="Seat" Type="SeatUpdated"><Seat Id="%d"><SeatData Name="%s" MicrophoneActive="%v" SeatType="Delegate" IsSpecialStation="false" /><Participant Id="%d"><ParticipantData Present="false" VotingWeight="1" VotingAuthorisation="true" MicrophoneAuthorisation="true" FirstName="" MiddleName="" LastName="%s" Title="" Country="" RemainingSpeechTime="-1" SpeechTimerOnHold="false" /></Participant><IsReposnding>false</IsReposnding></Seat></SeatActivity>`,
+		t.Format(TIMESTAMP_LAYOUT),
+		seat.ID,
+		seat.Name,
+		seat.MicOn,
+		seat.ParticipantID,
+		seat.Name,
+	)
+}
+
+// สร้าง XML ของ DiscussionActivity โดยมีเฉพาะที่นั่งที่เปิดไมค์อยู่ใน ActiveList
+func DiscussionActivityXML(seats []Seat, t time.Time) string {
+	var participants strings.Builder
+	for _, seat := range seats {
+		if seat.MicOn {
+			fmt.Fprintf(&participants, `<ParticipantContainer Id="%d"><Seat Id="%d"><SeatData Name="%s" MicrophoneActive="true" SeatType="Delegate" IsSpecialStation="false" /><IsReposnding>false</IsReposnding></Seat></ParticipantContainer>`,
+				seat.ParticipantID,
+				seat.ID,
+				seat.Name,
+			)
+		}
+	}
+
+	activeList := ""
+	if participants.Len() > 0 {
+		activeList = "<Participants>" + participants.String() + "</Participants>"
+	}
+	return fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?><DiscussionActivity xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:xsd="http://www.w3.org/2001/XMLSchema" Version="1" TimeStamp="%s" Topic="Discussion" Type="ActiveListUpdated"><Discussion Id="71"><ActiveList>%s</ActiveList></Discussion></DiscussionActivity>`,
+		t.Format(TIMESTAMP_LAYOUT),
+		activeList,
+	)
+}
+
+// จัดรูปแบบ XML ให้อ่านง่าย แยกเป็นหลายเอกสารถ้ามี <?xml มากกว่าหนึ่งครั้ง
+func PrettyXML(xmlStr string) []string {
+	// แยก XML strings ด้วย <?xml
+	xmlParts := bytes.Split([]byte(xmlStr), []byte("<?xml"))
+
+	var results []string
+
+	for _, part := range xmlParts {
+		// ข้าม empty parts
+		if len(bytes.TrimSpace(part)) == 0 {
+			continue
+		}
+
+		// เติม <?xml กลับไป
+		if !bytes.HasPrefix(part, []byte("<?xml")) {
+			part = append([]byte("<?xml"), part...)
+		}
+
+		decoder := xml.NewDecoder(bytes.NewReader(part))
+
+		var pretty bytes.Buffer
+		encoder := xml.NewEncoder(&pretty)
+		encoder.Indent("", "  ")
+
+		// อ่านและเขียน XML token แต่ละตัว
+		for {
+			token, err := decoder.Token()
+			if err != nil || token == nil {
+				break
+			}
+			if err := encoder.EncodeToken(token); err != nil {
+				break
+			}
+		}
+		encoder.Flush()
+
+		if pretty.Len() > 0 {
+			results = append(results, pretty.String())
+		}
+	}
+
+	return results
+}
+
+// อ่าน Seat Id จาก payload ของ SeatActivity (UTF-16LE พร้อม BOM หรือ UTF-8)
+func SeatIDFromPayload(payload []byte) (string, error) {
+	var seat SeatActivity
+	if err := xml.Unmarshal([]byte(DecodePayload(payload)), &seat); err != nil {
+		return "", err
+	}
+	if seat.Seat.ID == "" {
+		return "", errors.New("ไม่พบ Seat Id")
+	}
+	return seat.Seat.ID, nil
+}